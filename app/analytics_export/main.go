@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/subosito/gotenv"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/export"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+)
+
+func main() {
+	gotenv.Load()
+
+	dir := flag.String("dir", "export", "output directory, also holding watermarks of previous exports")
+	format := flag.String("format", export.FormatJSONL, "jsonl or csv")
+	full := flag.Bool("full", false, "export every row instead of rows updated since previous export")
+	tables := flag.String("tables", "", "comma separated tables to export, all when empty")
+	margin := flag.Duration("margin", export.DefaultMargin, "rows updated within margin before the export are left to the next one")
+	flag.Parse()
+
+	if *format != export.FormatJSONL && *format != export.FormatCSV {
+		log.Fatal("format must be jsonl or csv")
+	}
+	ts, err := export.ParseTables(*tables)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	e := &export.Exporter{DB: mysql.Init(), Dir: *dir, Format: *format, Full: *full, Tables: ts, Margin: *margin}
+	files, err := e.Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range files {
+		fmt.Printf("%s: %d rows\n", f.Path, f.Rows)
+	}
+}
@@ -1,13 +1,37 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/wiskarindra/jenkins_jr/pkg/api"
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/access"
+	"github.com/wiskarindra/jenkins_jr/pkg/approval"
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/auth"
+	"github.com/wiskarindra/jenkins_jr/pkg/bot"
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+	"github.com/wiskarindra/jenkins_jr/pkg/conditional"
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+	"github.com/wiskarindra/jenkins_jr/pkg/importer"
+	"github.com/wiskarindra/jenkins_jr/pkg/influencer"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
 	"github.com/wiskarindra/jenkins_jr/pkg/log"
 	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+	"github.com/wiskarindra/jenkins_jr/pkg/ratelimit"
+	"github.com/wiskarindra/jenkins_jr/pkg/recommend"
+	"github.com/wiskarindra/jenkins_jr/pkg/search"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+	"github.com/wiskarindra/jenkins_jr/pkg/watch"
+	"github.com/wiskarindra/jenkins_jr/pkg/webhook"
 	"github.com/wiskarindra/jenkins_jr/pkg/jenkins_jr"
 
+	"github.com/julienschmidt/httprouter"
 	"github.com/rs/cors"
 	"github.com/subosito/gotenv"
 )
@@ -16,7 +40,7 @@ func main() {
 	gotenv.Load()
 
 	db := mysql.Init()
-	env := jenkins_jr.Env{DB: db}
+	env := jenkins_jr.Env{DB: db, Jenkins: jenkins.New(os.Getenv("JENKINS_URL"), os.Getenv("JENKINS_USER"), os.Getenv("JENKINS_API_TOKEN"))}
 
 	router := api.NewRouter()
 	router.HandlerFunc("GET", "/metrics", instrument.Handler)
@@ -25,6 +49,86 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		botAPI := telegram.New(token, os.Getenv("TELEGRAM_API_URL"))
+		watcher := watch.New(&watch.MySQLStore{DB: env.DB}, env.Jenkins, botAPI)
+		watcher.Poll = config.Duration("WATCH_POLL", 10*time.Second)
+		watcher.Timeout = config.Duration("WATCH_TIMEOUT", 2*time.Hour)
+		poller := subscription.NewPoller(&subscription.MySQLStore{DB: env.DB}, env.Jenkins, botAPI)
+		poller.Poll = config.Duration("ALERT_POLL", time.Minute)
+		if loc, err := time.LoadLocation(config.String("QUIET_HOURS_TIMEZONE", "Asia/Jakarta")); err == nil {
+			poller.Location = loc
+		}
+		admins, err := access.ParseAdmins(os.Getenv("BOT_ADMINS"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		acl := &bot.Access{Policy: access.NewPolicy(&access.MySQLStore{DB: env.DB}, admins...)}
+		commands := bot.NewCommands(env.Jenkins, watcher, acl, &approval.MySQLStore{DB: env.DB})
+		subscriptions := &bot.Subscriptions{Store: poller.Store, Location: poller.Location, Access: acl}
+		inputs := &bot.Inputs{Store: poller.Store, Jenkins: env.Jenkins, Access: acl, Approvals: commands.Approvals}
+		botRouter := bot.NewRouter()
+		acl.Register(botRouter)
+		commands.Register(botRouter)
+		subscriptions.Register(botRouter)
+		inputs.Register(botRouter)
+		b := bot.New(botAPI, botRouter)
+		if secret := os.Getenv("JENKINS_WEBHOOK_SECRET"); secret != "" {
+			// jobs notifying builds are not polled anymore, until they stop for WEBHOOK_TTL
+			poller.WebhookTTL = config.Duration("WEBHOOK_TTL", 5*poller.Poll)
+			wh := &webhook.Handler{Secret: secret, Poller: poller, Watcher: watcher}
+			router.POST("/webhooks/jenkins", wh.Jenkins)
+		}
+		go watcher.Run(context.Background())
+		go poller.Run(context.Background())
+		go b.Run(context.Background())
+	}
+
+	introspector := auth.NewHTTPIntrospector(config.String("AUTH_INTROSPECTION_URL", config.AuthIntrospectionURL), os.Getenv("SPYRO_CLIENT_ID"), os.Getenv("SPYRO_CLIENT_SECRET"))
+	authn := &auth.Authenticator{Introspector: auth.NewCachedIntrospector(introspector, config.Duration("AUTH_CACHE_TTL", time.Minute), 10000)}
+
+	limiter := &ratelimit.Limiter{Store: ratelimit.NewMemoryStore(), TrustProxy: os.Getenv("RATE_LIMIT_TRUST_PROXY") == "true"}
+	listLimit := ratelimit.Limit{Requests: 120, Period: time.Minute}
+	likeLimit := ratelimit.LimitFromEnv("RATE_LIMIT_LIKES", ratelimit.Limit{Requests: 30, Period: time.Minute})
+	// authenticated routes are first limited per IP address, so that made up tokens are not all introspected
+	ipLimit := ratelimit.LimitFromEnv("RATE_LIMIT_IP", ratelimit.Limit{Requests: 300, Period: time.Minute})
+	throttle := func(h httprouter.Handle) httprouter.Handle {
+		return limiter.HandleIP("auth", ipLimit, h)
+	}
+
+	listings := cache.New(config.Int("POST_CACHE_SIZE", 1000))
+
+	ih := &influencer.Handler{DB: env.DB, Cache: listings}
+	router.GET("/influencers", ih.List)
+	router.POST("/influencers", throttle(authn.Require(auth.ScopeInfluencersWrite, currentuser.Require(currentuser.PermissionInfluencersWrite, ih.Create))))
+	router.GET("/influencers/:id", ih.Show)
+	router.PATCH("/influencers/:id", throttle(authn.Require(auth.ScopeInfluencersWrite, currentuser.Require(currentuser.PermissionInfluencersWrite, ih.Update))))
+	router.DELETE("/influencers/:id", throttle(authn.Require(auth.ScopeInfluencersWrite, currentuser.Require(currentuser.PermissionInfluencersDelete, ih.Delete))))
+	router.GET("/influencers/:id/posts", throttle(authn.Authenticate(limiter.Handle("influencer_posts", ratelimit.LimitFromEnv("RATE_LIMIT_INFLUENCER_POSTS", listLimit), conditional.CacheControl(config.String("CACHE_CONTROL_INFLUENCER_POSTS", "no-cache"), ih.Posts)))))
+
+	ph := &post.Handler{DB: env.DB, Cache: listings, CacheTTL: config.Duration("POST_CACHE_TTL", time.Minute)}
+	router.GET("/posts", throttle(authn.Authenticate(limiter.Handle("posts", ratelimit.LimitFromEnv("RATE_LIMIT_POSTS", listLimit), conditional.CacheControl(config.String("CACHE_CONTROL_POSTS", "no-cache"), ph.List)))))
+	router.GET("/posts/:id", conditional.CacheControl(config.String("CACHE_CONTROL_POST", "no-cache"), ph.Show))
+	router.GET("/posts/:id/version", throttle(authn.Require(auth.ScopePostsRead, currentuser.Require(currentuser.PermissionPostsRead, ph.Version))))
+	router.POST("/posts/:id/revert", throttle(authn.Require(auth.ScopePostsWrite, currentuser.Require(currentuser.PermissionPostsWrite, ph.Revert))))
+	router.POST("/posts/:id/like", throttle(authn.Authenticate(limiter.Handle("likes", likeLimit, ph.Like))))
+	router.DELETE("/posts/:id/like", throttle(authn.Authenticate(limiter.Handle("likes", likeLimit, ph.Unlike))))
+	router.DELETE("/posts/:id", throttle(authn.Require(auth.ScopePostsWrite, currentuser.Require(currentuser.PermissionPostsDelete, ph.Delete))))
+	router.POST("/posts/:id/restore", throttle(authn.Require(auth.ScopePostsWrite, currentuser.Require(currentuser.PermissionPostsWrite, ph.Restore))))
+	router.GET("/trash/posts", throttle(authn.Require(auth.ScopePostsRead, currentuser.Require(currentuser.PermissionPostsRead, ph.Trash))))
+
+	imh := &importer.Handler{DB: env.DB, Cache: listings}
+	router.POST("/imports/posts", throttle(authn.Require(auth.ScopePostsWrite, currentuser.Require(currentuser.PermissionPostsWrite, imh.Import))))
+
+	sh := &search.Handler{DB: env.DB, Searcher: search.NewMySQLSearcher(env.DB)}
+	router.GET("/search/posts", throttle(authn.Authenticate(limiter.Handle("search", ratelimit.LimitFromEnv("RATE_LIMIT_SEARCH", listLimit), sh.Posts))))
+
+	rh := &recommend.Handler{DB: env.DB, Recommender: recommend.New(&recommend.MySQLStore{DB: env.DB}, listings)}
+	router.GET("/posts/:id/related", rh.Related)
+
+	ah := &audit.Handler{DB: env.DB}
+	router.GET("/histories", throttle(authn.Require(auth.ScopeHistoriesRead, currentuser.Require(currentuser.PermissionHistoriesRead, ah.List))))
+
 	co := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{"GET", "POST", "PATCH", "DELETE", "PUT", "HEAD", "OPTIONS"},
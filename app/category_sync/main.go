@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/subosito/gotenv"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/category"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+)
+
+func main() {
+	gotenv.Load()
+
+	url := flag.String("url", config.CategorySourceURL(), "Bukalapak category source URL")
+	file := flag.String("file", "", "read categories from local JSON file instead of url")
+	dryRun := flag.Bool("dry-run", false, "only report differences without applying them")
+	flag.Parse()
+
+	var source category.CategorySource = category.NewHTTPSource(*url)
+	if *file != "" {
+		source = &category.FileSource{Path: *file}
+	}
+
+	syncer := category.Syncer{DB: mysql.Init(), Source: source, DryRun: *dryRun}
+	report, err := syncer.Sync(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(report)
+}
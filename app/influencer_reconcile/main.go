@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/subosito/gotenv"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/influencer"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+)
+
+func main() {
+	gotenv.Load()
+
+	r := influencer.NewReconciler(nil)
+	flag.BoolVar(&r.DryRun, "dry-run", false, "only report matches without touching the database")
+	flag.Float64Var(&r.MatchThreshold, "match", r.MatchThreshold, "minimum similarity to link a name to an existing influencer")
+	flag.Float64Var(&r.ReviewThreshold, "review", r.ReviewThreshold, "minimum similarity to report a name for manual review instead of creating an influencer")
+	flag.Float64Var(&r.Margin, "margin", r.Margin, "minimum similarity gap between the two best candidates")
+	report := flag.String("report", "", "write CSV report to this file instead of stdout")
+	flag.Parse()
+
+	r.DB = mysql.Init()
+	matches, err := r.Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *report != "" {
+		f, err := os.Create(*report)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := influencer.WriteCSV(out, matches); err != nil {
+		log.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	for _, m := range matches {
+		counts[m.Action]++
+	}
+	fmt.Fprintf(os.Stderr, "%d matched, %d created, %d ambiguous\n", counts[influencer.ActionMatched], counts[influencer.ActionCreated], counts[influencer.ActionAmbiguous])
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/subosito/gotenv"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/importer"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+)
+
+func main() {
+	gotenv.Load()
+
+	file := flag.String("file", "", "CSV or JSON file describing posts")
+	format := flag.String("format", "", "csv or json, guessed from file extension when empty")
+	dryRun := flag.Bool("dry-run", false, "only validate and preview posts")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("file is required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	posts, errs := importer.Parse(f, *format)
+	if len(errs) == 0 {
+		im := &importer.Importer{DB: mysql.Init()}
+		report, err := im.Import(context.Background(), posts, *dryRun)
+		if err != nil {
+			log.Fatal(err)
+		}
+		errs = report.Errors
+		if len(errs) == 0 {
+			prefix := ""
+			if report.DryRun {
+				prefix = "[dry run] "
+			}
+			fmt.Printf("%s%d posts, %d images, %d tags imported\n", prefix, len(report.Posts), report.Images, report.Tags)
+			for _, p := range report.Posts {
+				fmt.Printf("  %s (id %d): %s\n", p.Ref, p.ID, p.Title)
+			}
+			return
+		}
+	}
+
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, e)
+	}
+	os.Exit(1)
+}
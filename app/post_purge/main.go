@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/subosito/gotenv"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+)
+
+func main() {
+	gotenv.Load()
+
+	days := flag.Int("days", 30, "retention of deleted posts, in days")
+	batch := flag.Int("batch", 100, "number of posts removed per transaction")
+	flag.Parse()
+
+	if *days < 1 {
+		log.Fatal("days must be positive")
+	}
+
+	purged, err := post.Purge(context.Background(), mysql.Init(), time.Duration(*days)*24*time.Hour, *batch)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d posts purged\n", purged)
+}
@@ -1,6 +1,10 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 const (
 	DatabaseDatetimeFormat     = "2006-01-02 15:04:05"
@@ -8,6 +12,8 @@ const (
 	DateRangeSearchFormat      = "2006-01-02T15:04:05Z07:00"
 	InspirationIndexDefaultURL = "https://www.bukalapak.com/inspirasi"
 	InfluencerIndexDefaultURL  = "https://www.bukalapak.com/i"
+	CategorySourceDefaultURL   = "https://api.bukalapak.com/categories"
+	AuthIntrospectionURL       = "https://accounts.bukalapak.com/oauth/introspect"
 	InspirationHomepageTitle   = "Inspirasi"
 	IndexImageURLStyle         = "s-1080-1350"
 	HomepageImageURLStyle      = "s-240-300"
@@ -28,3 +34,47 @@ func InfluencerIndexURL() string {
 	}
 	return url
 }
+
+func CategorySourceURL() string {
+	url := os.Getenv("CATEGORY_SOURCE_URL")
+	if url == "" {
+		url = CategorySourceDefaultURL
+	}
+	return url
+}
+
+// String returns environment variable, or fallback when missing
+func String(key, fallback string) string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	return v
+}
+
+// Int returns environment variable as int, or fallback when missing or invalid
+func Int(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+// Float returns environment variable as float64, or fallback when missing or invalid
+func Float(key string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+// Duration returns environment variable parsed by time.ParseDuration, or fallback when missing or invalid
+func Duration(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
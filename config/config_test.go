@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/subosito/gotenv"
@@ -19,3 +20,18 @@ func TestInspirationIndexURL(t *testing.T) {
 	assert.Equal(t, InspirationIndexDefaultURL, InspirationIndexURL())
 	os.Setenv("INSPIRATION_INDEX_URL", url)
 }
+
+func TestEnvParsers(t *testing.T) {
+	os.Setenv("CONFIG_TEST_VALUE", "15")
+	defer os.Unsetenv("CONFIG_TEST_VALUE")
+
+	assert.Equal(t, "15", String("CONFIG_TEST_VALUE", "1"))
+	assert.Equal(t, "1", String("CONFIG_TEST_MISSING", "1"))
+	assert.Equal(t, 15, Int("CONFIG_TEST_VALUE", 1))
+	assert.Equal(t, 15.0, Float("CONFIG_TEST_VALUE", 1))
+	assert.Equal(t, time.Second, Duration("CONFIG_TEST_VALUE", time.Second))
+
+	os.Setenv("CONFIG_TEST_VALUE", "15m")
+	assert.Equal(t, 1, Int("CONFIG_TEST_VALUE", 1))
+	assert.Equal(t, 15*time.Minute, Duration("CONFIG_TEST_VALUE", time.Second))
+}
@@ -0,0 +1,19 @@
+class AddProfileToInfluencers < ActiveRecord::Migration[5.1]
+  def up
+    add_column :influencers, :slug, :string
+    add_column :influencers, :avatar_url, :string
+    add_column :influencers, :bio, :text
+    add_column :influencers, :social_links, :text
+
+    execute "UPDATE influencers SET slug = CONCAT('i-', id)"
+    add_index :influencers, [:slug], name: "index_influencers_on_slug", unique: true
+  end
+
+  def down
+    remove_index :influencers, name: "index_influencers_on_slug"
+    remove_column :influencers, :slug
+    remove_column :influencers, :avatar_url
+    remove_column :influencers, :bio
+    remove_column :influencers, :social_links
+  end
+end
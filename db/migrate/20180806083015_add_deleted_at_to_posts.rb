@@ -0,0 +1,19 @@
+class AddDeletedAtToPosts < ActiveRecord::Migration[5.1]
+  def up
+    add_column :posts, :deleted_at, :datetime
+    add_column :post_images, :deleted_at, :datetime
+    add_column :post_tags, :deleted_at, :datetime
+    add_column :post_filters, :deleted_at, :datetime
+
+    execute "UPDATE posts SET deleted_at = updated_at WHERE deleted = true"
+    add_index :posts, [:deleted, :deleted_at], name: "index_posts_on_deleted_and_deleted_at"
+  end
+
+  def down
+    remove_index :posts, name: "index_posts_on_deleted_and_deleted_at"
+    remove_column :posts, :deleted_at
+    remove_column :post_images, :deleted_at
+    remove_column :post_tags, :deleted_at
+    remove_column :post_filters, :deleted_at
+  end
+end
@@ -0,0 +1,11 @@
+class AddFulltextIndexesToPosts < ActiveRecord::Migration[5.1]
+  def up
+    add_index :posts, [:title, :description, :influencer_name], name: "index_posts_on_title_and_description_and_influencer_name", type: :fulltext
+    add_index :post_tags, [:name], name: "index_post_tags_on_name", type: :fulltext
+  end
+
+  def down
+    remove_index :posts, name: "index_posts_on_title_and_description_and_influencer_name"
+    remove_index :post_tags, name: "index_post_tags_on_name"
+  end
+end
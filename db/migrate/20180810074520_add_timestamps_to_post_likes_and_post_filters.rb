@@ -0,0 +1,23 @@
+class AddTimestampsToPostLikesAndPostFilters < ActiveRecord::Migration[5.1]
+  def up
+    [:post_likes, :post_filters].each do |table|
+      execute "ALTER TABLE #{table} ADD COLUMN created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP"
+      execute "ALTER TABLE #{table} ADD COLUMN updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"
+      add_index table, :updated_at
+    end
+    [:posts, :post_images, :post_tags].each do |table|
+      add_index table, :updated_at
+    end
+  end
+
+  def down
+    [:posts, :post_images, :post_tags].each do |table|
+      remove_index table, :updated_at
+    end
+    [:post_likes, :post_filters].each do |table|
+      remove_index table, :updated_at
+      remove_column table, :created_at
+      remove_column table, :updated_at
+    end
+  end
+end
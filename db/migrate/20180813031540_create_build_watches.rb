@@ -0,0 +1,24 @@
+class CreateBuildWatches < ActiveRecord::Migration[5.1]
+  def up
+    create_table :build_watches do |t|
+      t.bigint   :chat_id, null: false
+      t.bigint   :message_id, null: false
+      t.string   :job, null: false
+      t.text     :title, null: false
+      t.bigint   :queue_id, null: false
+      t.bigint   :build_number, null: false, default: 0
+      t.text     :text, null: false
+      t.string   :result
+      t.datetime :expires_at, null: false
+      t.datetime :finished_at
+
+      t.timestamps null: false
+    end
+
+    add_index :build_watches, [:finished_at], name: 'index_build_watches_on_finished_at'
+  end
+
+  def down
+    drop_table :build_watches
+  end
+end
@@ -0,0 +1,55 @@
+class CreateSubscriptions < ActiveRecord::Migration[5.1]
+  def up
+    create_table :subscriptions do |t|
+      t.bigint :chat_id, null: false
+      t.string :pattern, null: false
+      t.string :events, null: false
+      t.bigint :created_by, null: false, default: 0
+
+      t.timestamps null: false
+    end
+
+    add_index :subscriptions, [:chat_id, :pattern], name: 'index_subscriptions_on_chat_and_pattern', unique: true
+
+    create_table :quiet_hours do |t|
+      t.bigint  :chat_id, null: false
+      t.integer :starts_at, null: false
+      t.integer :ends_at, null: false
+
+      t.timestamps null: false
+    end
+
+    add_index :quiet_hours, [:chat_id], name: 'index_quiet_hours_on_chat_id', unique: true
+
+    create_table :job_states do |t|
+      t.string :job, null: false
+      t.bigint :build_number, null: false, default: 0
+      t.string :result, null: false, default: ''
+
+      t.timestamps null: false
+    end
+
+    add_index :job_states, [:job], name: 'index_job_states_on_job', unique: true
+
+    create_table :notifications do |t|
+      t.bigint   :chat_id, null: false
+      t.string   :job, null: false
+      t.bigint   :build_number, null: false
+      t.string   :event, null: false
+      t.text     :text, null: false
+      t.datetime :sent_at
+
+      t.timestamps null: false
+    end
+
+    add_index :notifications, [:chat_id, :job, :build_number], name: 'index_notifications_on_chat_and_job_and_build', unique: true
+    add_index :notifications, [:sent_at], name: 'index_notifications_on_sent_at'
+  end
+
+  def down
+    drop_table :notifications
+    drop_table :job_states
+    drop_table :quiet_hours
+    drop_table :subscriptions
+  end
+end
@@ -0,0 +1,9 @@
+class AddWebhookAtToJobStates < ActiveRecord::Migration[5.1]
+  def up
+    add_column :job_states, :webhook_at, :datetime
+  end
+
+  def down
+    remove_column :job_states, :webhook_at
+  end
+end
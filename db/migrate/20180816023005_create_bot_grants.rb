@@ -0,0 +1,33 @@
+class CreateBotGrants < ActiveRecord::Migration[5.1]
+  def up
+    create_table :bot_grants do |t|
+      t.string :subject_type, null: false
+      t.bigint :subject_id, null: false
+      t.string :role, null: false
+      t.bigint :created_by, null: false, default: 0
+
+      t.timestamps null: false
+    end
+
+    add_index :bot_grants, [:subject_type, :subject_id], name: 'index_bot_grants_on_subject', unique: true
+
+    create_table :bot_job_rules do |t|
+      t.string :pattern, null: false
+      t.string :role, null: false
+      t.bigint :created_by, null: false, default: 0
+
+      t.timestamps null: false
+    end
+
+    add_index :bot_job_rules, [:pattern], name: 'index_bot_job_rules_on_pattern', unique: true
+
+    # bot commands are recorded against Telegram chat IDs, which do not fit in an integer
+    change_column :action_log_histories, :record_id, :bigint
+  end
+
+  def down
+    change_column :action_log_histories, :record_id, :integer
+    drop_table :bot_job_rules
+    drop_table :bot_grants
+  end
+end
@@ -0,0 +1,49 @@
+class CreateBuildRequests < ActiveRecord::Migration[5.1]
+  def up
+    create_table :approval_rules do |t|
+      t.string  :pattern, null: false
+      t.integer :approvers, null: false
+      t.integer :window, null: false
+      t.bigint  :created_by, null: false, default: 0
+
+      t.timestamps null: false
+    end
+
+    add_index :approval_rules, [:pattern], name: 'index_approval_rules_on_pattern', unique: true
+
+    create_table :build_requests do |t|
+      t.bigint   :chat_id, null: false
+      t.bigint   :message_id, null: false
+      t.string   :job, null: false
+      t.text     :params, null: false
+      t.text     :title, null: false
+      t.bigint   :requested_by, null: false
+      t.string   :requester_name, null: false
+      t.integer  :approvers, null: false
+      t.string   :status, null: false
+      t.bigint   :queue_id, null: false, default: 0
+      t.datetime :expires_at, null: false
+      t.datetime :decided_at
+
+      t.timestamps null: false
+    end
+
+    add_index :build_requests, [:job], name: 'index_build_requests_on_job'
+
+    create_table :build_request_votes do |t|
+      t.bigint   :request_id, null: false
+      t.bigint   :user_id, null: false
+      t.string   :user_name, null: false
+      t.string   :decision, null: false
+      t.datetime :created_at, null: false
+    end
+
+    add_index :build_request_votes, [:request_id, :user_id], name: 'index_build_request_votes_on_request_and_user', unique: true
+  end
+
+  def down
+    drop_table :build_request_votes
+    drop_table :build_requests
+    drop_table :approval_rules
+  end
+end
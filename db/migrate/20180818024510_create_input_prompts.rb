@@ -0,0 +1,24 @@
+class CreateInputPrompts < ActiveRecord::Migration[5.1]
+  def up
+    create_table :input_prompts do |t|
+      t.bigint   :chat_id, null: false
+      t.bigint   :message_id, null: false, default: 0
+      t.string   :job, null: false
+      t.bigint   :build_number, null: false
+      t.string   :input_id, null: false
+      t.text     :text, null: false
+      t.string   :status, null: false
+      t.string   :decided_by, null: false, default: ''
+      t.datetime :decided_at
+
+      t.timestamps null: false
+    end
+
+    add_index :input_prompts, [:chat_id, :job, :build_number, :input_id], name: 'index_input_prompts_on_chat_and_job_and_build_and_input', unique: true
+    add_index :input_prompts, [:message_id, :status], name: 'index_input_prompts_on_message_id_and_status'
+  end
+
+  def down
+    drop_table :input_prompts
+  end
+end
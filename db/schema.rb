@@ -10,10 +10,10 @@
 #
 # It's strongly recommended that you check this file into your version control system.
 
-ActiveRecord::Schema.define(version: 20180725130653) do
+ActiveRecord::Schema.define(version: 20180818024510) do
 
   create_table "action_log_histories", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
-    t.integer "record_id"
+    t.bigint "record_id"
     t.string "record_type"
     t.text "changes"
     t.bigint "actor_id"
@@ -22,12 +22,85 @@ ActiveRecord::Schema.define(version: 20180725130653) do
     t.index ["record_id", "record_type"], name: "index_action_log_histories_on_record"
   end
 
+  create_table "approval_rules", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.string "pattern", null: false
+    t.integer "approvers", null: false
+    t.integer "window", null: false
+    t.bigint "created_by", default: 0, null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["pattern"], name: "index_approval_rules_on_pattern", unique: true
+  end
+
+  create_table "bot_grants", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.string "subject_type", null: false
+    t.bigint "subject_id", null: false
+    t.string "role", null: false
+    t.bigint "created_by", default: 0, null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["subject_type", "subject_id"], name: "index_bot_grants_on_subject", unique: true
+  end
+
+  create_table "bot_job_rules", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.string "pattern", null: false
+    t.string "role", null: false
+    t.bigint "created_by", default: 0, null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["pattern"], name: "index_bot_job_rules_on_pattern", unique: true
+  end
+
+  create_table "build_request_votes", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.bigint "request_id", null: false
+    t.bigint "user_id", null: false
+    t.string "user_name", null: false
+    t.string "decision", null: false
+    t.datetime "created_at", null: false
+    t.index ["request_id", "user_id"], name: "index_build_request_votes_on_request_and_user", unique: true
+  end
+
+  create_table "build_requests", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.bigint "chat_id", null: false
+    t.bigint "message_id", null: false
+    t.string "job", null: false
+    t.text "params", null: false
+    t.text "title", null: false
+    t.bigint "requested_by", null: false
+    t.string "requester_name", null: false
+    t.integer "approvers", null: false
+    t.string "status", null: false
+    t.bigint "queue_id", default: 0, null: false
+    t.datetime "expires_at", null: false
+    t.datetime "decided_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["job"], name: "index_build_requests_on_job"
+  end
+
+  create_table "build_watches", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.bigint "chat_id", null: false
+    t.bigint "message_id", null: false
+    t.string "job", null: false
+    t.text "title", null: false
+    t.bigint "queue_id", null: false
+    t.bigint "build_number", default: 0, null: false
+    t.text "text", null: false
+    t.string "result"
+    t.datetime "expires_at", null: false
+    t.datetime "finished_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["finished_at"], name: "index_build_watches_on_finished_at"
+  end
+
   create_table "categories", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
     t.integer "bukalapak_category_id", default: 0
     t.string "bukalapak_category_name"
     t.integer "count", default: 0
     t.datetime "created_at", null: false
     t.datetime "updated_at", null: false
+    t.boolean "deleted", default: false
     t.index ["bukalapak_category_id"], name: "index_categories_on_bukalapak_category_id", unique: true
   end
 
@@ -35,13 +108,61 @@ ActiveRecord::Schema.define(version: 20180725130653) do
     t.string "name"
     t.datetime "created_at", null: false
     t.datetime "updated_at", null: false
+    t.string "slug"
+    t.string "avatar_url"
+    t.text "bio"
+    t.text "social_links"
     t.index ["name"], name: "index_influencers_on_name"
+    t.index ["slug"], name: "index_influencers_on_slug", unique: true
+  end
+
+  create_table "input_prompts", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.bigint "chat_id", null: false
+    t.bigint "message_id", default: 0, null: false
+    t.string "job", null: false
+    t.bigint "build_number", null: false
+    t.string "input_id", null: false
+    t.text "text", null: false
+    t.string "status", null: false
+    t.string "decided_by", default: "", null: false
+    t.datetime "decided_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["chat_id", "job", "build_number", "input_id"], name: "index_input_prompts_on_chat_and_job_and_build_and_input", unique: true
+    t.index ["message_id", "status"], name: "index_input_prompts_on_message_id_and_status"
+  end
+
+  create_table "job_states", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.string "job", null: false
+    t.bigint "build_number", default: 0, null: false
+    t.string "result", default: "", null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.datetime "webhook_at"
+    t.index ["job"], name: "index_job_states_on_job", unique: true
+  end
+
+  create_table "notifications", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.bigint "chat_id", null: false
+    t.string "job", null: false
+    t.bigint "build_number", null: false
+    t.string "event", null: false
+    t.text "text", null: false
+    t.datetime "sent_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["chat_id", "job", "build_number"], name: "index_notifications_on_chat_and_job_and_build", unique: true
+    t.index ["sent_at"], name: "index_notifications_on_sent_at"
   end
 
   create_table "post_filters", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
     t.integer "post_id"
     t.integer "bukalapak_category_id", default: 0
+    t.datetime "deleted_at"
+    t.datetime "created_at", default: -> { "CURRENT_TIMESTAMP" }, null: false
+    t.datetime "updated_at", default: -> { "CURRENT_TIMESTAMP" }, null: false
     t.index ["post_id", "bukalapak_category_id"], name: "index_post_filters_on_post_id_and_bukalapak_category_id", unique: true
+    t.index ["updated_at"], name: "index_post_filters_on_updated_at"
   end
 
   create_table "post_images", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
@@ -52,15 +173,20 @@ ActiveRecord::Schema.define(version: 20180725130653) do
     t.datetime "created_at", null: false
     t.datetime "updated_at", null: false
     t.integer "position", default: 0
+    t.datetime "deleted_at"
     t.index ["post_id"], name: "index_post_images_on_post_id"
+    t.index ["updated_at"], name: "index_post_images_on_updated_at"
   end
 
   create_table "post_likes", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
     t.integer "post_id"
     t.integer "bukalapak_user_id"
     t.boolean "liked", default: true
+    t.datetime "created_at", default: -> { "CURRENT_TIMESTAMP" }, null: false
+    t.datetime "updated_at", default: -> { "CURRENT_TIMESTAMP" }, null: false
     t.index ["post_id", "bukalapak_user_id", "liked"], name: "index_post_likes_on_post_and_user_and_liked"
     t.index ["post_id", "bukalapak_user_id"], name: "index_post_likes_on_post_and_user", unique: true
+    t.index ["updated_at"], name: "index_post_likes_on_updated_at"
   end
 
   create_table "post_tags", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
@@ -75,7 +201,10 @@ ActiveRecord::Schema.define(version: 20180725130653) do
     t.string "reference_type"
     t.integer "bukalapak_category_id", default: 0
     t.integer "post_image_id"
+    t.datetime "deleted_at"
+    t.index ["name"], name: "index_post_tags_on_name", type: :fulltext
     t.index ["post_id"], name: "index_post_tags_on_post_id"
+    t.index ["updated_at"], name: "index_post_tags_on_updated_at"
   end
 
   create_table "posts", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
@@ -91,8 +220,30 @@ ActiveRecord::Schema.define(version: 20180725130653) do
     t.datetime "created_at", null: false
     t.datetime "updated_at", null: false
     t.integer "influencer_id", default: 0
+    t.datetime "deleted_at"
+    t.index ["deleted", "deleted_at"], name: "index_posts_on_deleted_and_deleted_at"
     t.index ["deleted", "published"], name: "index_posts_on_deleted_and_published"
     t.index ["influencer_id", "deleted", "published"], name: "index_posts_on_influencer_id_and_deleted_and_published"
+    t.index ["title", "description", "influencer_name"], name: "index_posts_on_title_and_description_and_influencer_name", type: :fulltext
+    t.index ["updated_at"], name: "index_posts_on_updated_at"
+  end
+
+  create_table "quiet_hours", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.bigint "chat_id", null: false
+    t.integer "starts_at", null: false
+    t.integer "ends_at", null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["chat_id"], name: "index_quiet_hours_on_chat_id", unique: true
   end
 
+  create_table "subscriptions", force: :cascade, options: "ENGINE=InnoDB DEFAULT CHARSET=utf8" do |t|
+    t.bigint "chat_id", null: false
+    t.string "pattern", null: false
+    t.string "events", null: false
+    t.bigint "created_by", default: 0, null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["chat_id", "pattern"], name: "index_subscriptions_on_chat_and_pattern", unique: true
+  end
 end
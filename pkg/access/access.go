@@ -0,0 +1,197 @@
+package access
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Roles of Telegram users and chats, every role being allowed what the previous one is
+const (
+	// RoleViewer can list jobs, see builds and manage alerts
+	RoleViewer = "viewer"
+	// RoleDeveloper can also build and abort jobs without rules
+	RoleDeveloper = "developer"
+	// RoleLead can also build and abort jobs restricted to leads
+	RoleLead = "lead"
+	// RoleAdmin can also grant roles and restrict jobs
+	RoleAdmin = "admin"
+)
+
+// Roles lists roles from the least to the most allowed
+var Roles = []string{RoleViewer, RoleDeveloper, RoleLead, RoleAdmin}
+
+// Subjects a role is granted to
+const (
+	SubjectUser = "user"
+	SubjectChat = "chat"
+)
+
+// Results of audited commands
+const (
+	ResultOK     = "ok"
+	ResultDenied = "denied"
+	ResultFailed = "failed"
+)
+
+// Grant gives a role to a Telegram user, wherever they write, or to every member of a chat
+type Grant struct {
+	ID          int64  `db:"id"`
+	SubjectType string `db:"subject_type"`
+	SubjectID   int64  `db:"subject_id"`
+	Role        string `db:"role"`
+	// CreatedBy is the Telegram user who granted the role
+	CreatedBy int64     `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// JobRule restricts building and aborting jobs whose full name matches Pattern to a role
+type JobRule struct {
+	ID        int64     `db:"id"`
+	Pattern   string    `db:"pattern"`
+	Role      string    `db:"role"`
+	CreatedBy int64     `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Matches tells whether a job is covered by the rule
+func (r *JobRule) Matches(job string) bool {
+	ok, _ := path.Match(r.Pattern, job)
+	return ok
+}
+
+// Entry is a privileged command audited in action_log_histories, as a change of the chat it was sent in
+type Entry struct {
+	UserID int64 `db:"-"`
+	ChatID int64 `db:"-"`
+	// Command is the command name, or the name of the pressed button
+	Command string `db:"command"`
+	// Text is the command as sent, without values of name=value arguments which may be secrets
+	Text   string `db:"text"`
+	Result string `db:"result"`
+}
+
+// Level returns the rank of a role in Roles, -1 for unknown roles and no role
+func Level(role string) int {
+	for k, r := range Roles {
+		if r == role {
+			return k
+		}
+	}
+	return -1
+}
+
+// Includes tells whether role is allowed what required is
+func Includes(role, required string) bool {
+	return Level(role) >= 0 && Level(role) >= Level(required)
+}
+
+// ParseRole validates a role name
+func ParseRole(role string) (string, error) {
+	role = strings.ToLower(role)
+	if Level(role) < 0 {
+		return "", fmt.Errorf("Unknown role %s, expected %s", role, strings.Join(Roles, ", "))
+	}
+	return role, nil
+}
+
+// ParseAdmins parses comma separated Telegram user IDs, such as BOT_ADMINS
+func ParseAdmins(s string) ([]int64, error) {
+	ids := []int64{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Telegram user ID %s", field)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ValidPattern checks that a job pattern is well formed
+func ValidPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("Invalid job pattern %s", pattern)
+	}
+	return nil
+}
+
+// Store keeps roles, job rules and the audit of commands
+type Store interface {
+	// Grants returns roles granted to a user and to a chat
+	Grants(ctx context.Context, userID, chatID int64) ([]Grant, error)
+	// AllGrants returns every granted role
+	AllGrants(ctx context.Context) ([]Grant, error)
+	// Grant gives a role to a subject, replacing its previous one
+	Grant(ctx context.Context, g *Grant) error
+	// Revoke removes the role of a subject, returning false when it had none
+	Revoke(ctx context.Context, subjectType string, subjectID int64) (bool, error)
+
+	// JobRules returns every job rule
+	JobRules(ctx context.Context) ([]JobRule, error)
+	// SetJobRule creates a rule for a pattern, or replaces its role
+	SetJobRule(ctx context.Context, r *JobRule) error
+	// RemoveJobRule removes the rule of a pattern, returning false when there was none
+	RemoveJobRule(ctx context.Context, pattern string) (bool, error)
+
+	// Audit records a privileged command
+	Audit(ctx context.Context, e *Entry) error
+}
+
+// Policy tells what users are allowed
+type Policy struct {
+	Store Store
+	// Admins are users who are admins whatever the store says, so that the first roles can be granted
+	Admins map[int64]bool
+}
+
+// NewPolicy returns Policy with admins
+func NewPolicy(store Store, admins ...int64) *Policy {
+	p := &Policy{Store: store, Admins: map[int64]bool{}}
+	for _, id := range admins {
+		p.Admins[id] = true
+	}
+	return p
+}
+
+// Role returns the most allowed role of a user in a chat, empty when they have none
+func (p *Policy) Role(ctx context.Context, userID, chatID int64) (string, error) {
+	if p.Admins[userID] {
+		return RoleAdmin, nil
+	}
+	grants, err := p.Store.Grants(ctx, userID, chatID)
+	if err != nil {
+		return "", err
+	}
+	role := ""
+	for _, g := range grants {
+		if Level(g.Role) > Level(role) {
+			role = g.Role
+		}
+	}
+	return role, nil
+}
+
+// JobRole returns the role required to build and abort a job, the most allowed one among its rules and developer
+func (p *Policy) JobRole(ctx context.Context, job string) (string, error) {
+	rules, err := p.Store.JobRules(ctx)
+	if err != nil {
+		return "", err
+	}
+	role := RoleDeveloper
+	for _, r := range rules {
+		if r.Matches(job) && Level(r.Role) > Level(role) {
+			role = r.Role
+		}
+	}
+	return role, nil
+}
@@ -0,0 +1,67 @@
+package access_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/access"
+)
+
+func TestIncludes(t *testing.T) {
+	assert.True(t, access.Includes(access.RoleAdmin, access.RoleLead))
+	assert.True(t, access.Includes(access.RoleDeveloper, access.RoleDeveloper))
+	assert.False(t, access.Includes(access.RoleViewer, access.RoleDeveloper))
+	assert.False(t, access.Includes("", access.RoleViewer))
+
+	role, err := access.ParseRole("Lead")
+	assert.Nil(t, err)
+	assert.Equal(t, access.RoleLead, role)
+	_, err = access.ParseRole("owner")
+	assert.NotNil(t, err)
+}
+
+func TestParseAdmins(t *testing.T) {
+	ids, err := access.ParseAdmins(" 7, 42,")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{7, 42}, ids)
+	ids, err = access.ParseAdmins("")
+	assert.Nil(t, err)
+	assert.Empty(t, ids)
+	_, err = access.ParseAdmins("7,@lead")
+	assert.NotNil(t, err)
+}
+
+func TestPolicy(t *testing.T) {
+	ctx := context.Background()
+	store := access.NewMemoryStore()
+	p := access.NewPolicy(store, 1)
+
+	role, _ := p.Role(ctx, 1, 100)
+	assert.Equal(t, access.RoleAdmin, role)
+	role, _ = p.Role(ctx, 2, 100)
+	assert.Equal(t, "", role)
+
+	// the most allowed of the user and chat roles
+	store.Grant(ctx, &access.Grant{SubjectType: access.SubjectChat, SubjectID: 100, Role: access.RoleDeveloper})
+	store.Grant(ctx, &access.Grant{SubjectType: access.SubjectUser, SubjectID: 2, Role: access.RoleViewer})
+	role, _ = p.Role(ctx, 2, 100)
+	assert.Equal(t, access.RoleDeveloper, role)
+	role, _ = p.Role(ctx, 2, 200)
+	assert.Equal(t, access.RoleViewer, role)
+	store.Grant(ctx, &access.Grant{SubjectType: access.SubjectUser, SubjectID: 2, Role: access.RoleLead})
+	role, _ = p.Role(ctx, 2, 200)
+	assert.Equal(t, access.RoleLead, role)
+
+	role, _ = p.JobRole(ctx, "deploy-production")
+	assert.Equal(t, access.RoleDeveloper, role)
+	store.SetJobRule(ctx, &access.JobRule{Pattern: "deploy-*", Role: access.RoleLead})
+	store.SetJobRule(ctx, &access.JobRule{Pattern: "*-production", Role: access.RoleAdmin})
+	role, _ = p.JobRole(ctx, "deploy-production")
+	assert.Equal(t, access.RoleAdmin, role)
+	role, _ = p.JobRole(ctx, "deploy-staging")
+	assert.Equal(t, access.RoleLead, role)
+	role, _ = p.JobRole(ctx, "mobile/android")
+	assert.Equal(t, access.RoleDeveloper, role)
+}
@@ -0,0 +1,134 @@
+package access
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps roles in process memory, they are lost on restart
+type MemoryStore struct {
+	mu      sync.Mutex
+	grants  []Grant
+	rules   []JobRule
+	entries []Entry
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Grants implements Store
+func (s *MemoryStore) Grants(_ context.Context, userID, chatID int64) ([]Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grants := []Grant{}
+	for _, g := range s.grants {
+		if (g.SubjectType == SubjectUser && g.SubjectID == userID) || (g.SubjectType == SubjectChat && g.SubjectID == chatID) {
+			grants = append(grants, g)
+		}
+	}
+	return grants, nil
+}
+
+// AllGrants implements Store
+func (s *MemoryStore) AllGrants(_ context.Context) ([]Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grants := append([]Grant{}, s.grants...)
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].SubjectType != grants[j].SubjectType {
+			return grants[i].SubjectType > grants[j].SubjectType
+		}
+		return grants[i].SubjectID < grants[j].SubjectID
+	})
+	return grants, nil
+}
+
+// Grant implements Store
+func (s *MemoryStore) Grant(_ context.Context, g *Grant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, old := range s.grants {
+		if old.SubjectType == g.SubjectType && old.SubjectID == g.SubjectID {
+			s.grants[k].Role, s.grants[k].CreatedBy, s.grants[k].UpdatedAt = g.Role, g.CreatedBy, now
+			return nil
+		}
+	}
+	saved := *g
+	saved.ID = int64(len(s.grants) + 1)
+	saved.CreatedAt, saved.UpdatedAt = now, now
+	s.grants = append(s.grants, saved)
+	return nil
+}
+
+// Revoke implements Store
+func (s *MemoryStore) Revoke(_ context.Context, subjectType string, subjectID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, g := range s.grants {
+		if g.SubjectType == subjectType && g.SubjectID == subjectID {
+			s.grants = append(s.grants[:k], s.grants[k+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// JobRules implements Store
+func (s *MemoryStore) JobRules(_ context.Context) ([]JobRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := append([]JobRule{}, s.rules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Pattern < rules[j].Pattern })
+	return rules, nil
+}
+
+// SetJobRule implements Store
+func (s *MemoryStore) SetJobRule(_ context.Context, r *JobRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, old := range s.rules {
+		if old.Pattern == r.Pattern {
+			s.rules[k].Role, s.rules[k].CreatedBy, s.rules[k].UpdatedAt = r.Role, r.CreatedBy, now
+			return nil
+		}
+	}
+	saved := *r
+	saved.ID = int64(len(s.rules) + 1)
+	saved.CreatedAt, saved.UpdatedAt = now, now
+	s.rules = append(s.rules, saved)
+	return nil
+}
+
+// RemoveJobRule implements Store
+func (s *MemoryStore) RemoveJobRule(_ context.Context, pattern string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, r := range s.rules {
+		if r.Pattern == pattern {
+			s.rules = append(s.rules[:k], s.rules[k+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Audit implements Store
+func (s *MemoryStore) Audit(_ context.Context, e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, *e)
+	return nil
+}
+
+// Entries returns audited commands, oldest first
+func (s *MemoryStore) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry{}, s.entries...)
+}
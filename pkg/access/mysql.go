@@ -0,0 +1,85 @@
+package access
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+)
+
+// MySQLStore keeps roles in the bot_grants and bot_job_rules tables, and audits commands in action_log_histories
+type MySQLStore struct {
+	DB *sqlx.DB
+}
+
+func now() string {
+	return time.Now().Format(config.DatabaseDatetimeFormat)
+}
+
+// Grants implements Store
+func (s *MySQLStore) Grants(ctx context.Context, userID, chatID int64) ([]Grant, error) {
+	grants := []Grant{}
+	err := s.DB.SelectContext(ctx, &grants, "SELECT * FROM bot_grants WHERE (subject_type = ? AND subject_id = ?) OR (subject_type = ? AND subject_id = ?)",
+		SubjectUser, userID, SubjectChat, chatID)
+	return grants, err
+}
+
+// AllGrants implements Store
+func (s *MySQLStore) AllGrants(ctx context.Context) ([]Grant, error) {
+	grants := []Grant{}
+	err := s.DB.SelectContext(ctx, &grants, "SELECT * FROM bot_grants ORDER BY subject_type DESC, subject_id")
+	return grants, err
+}
+
+// Grant implements Store
+func (s *MySQLStore) Grant(ctx context.Context, g *Grant) error {
+	n := now()
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO bot_grants (subject_type, subject_id, role, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE role = VALUES(role), created_by = VALUES(created_by), updated_at = VALUES(updated_at)",
+		g.SubjectType, g.SubjectID, g.Role, g.CreatedBy, n, n)
+	return err
+}
+
+// Revoke implements Store
+func (s *MySQLStore) Revoke(ctx context.Context, subjectType string, subjectID int64) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM bot_grants WHERE subject_type = ? AND subject_id = ?", subjectType, subjectID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// JobRules implements Store
+func (s *MySQLStore) JobRules(ctx context.Context) ([]JobRule, error) {
+	rules := []JobRule{}
+	err := s.DB.SelectContext(ctx, &rules, "SELECT * FROM bot_job_rules ORDER BY pattern")
+	return rules, err
+}
+
+// SetJobRule implements Store
+func (s *MySQLStore) SetJobRule(ctx context.Context, r *JobRule) error {
+	n := now()
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO bot_job_rules (pattern, role, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE role = VALUES(role), created_by = VALUES(created_by), updated_at = VALUES(updated_at)",
+		r.Pattern, r.Role, r.CreatedBy, n, n)
+	return err
+}
+
+// RemoveJobRule implements Store
+func (s *MySQLStore) RemoveJobRule(ctx context.Context, pattern string) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM bot_job_rules WHERE pattern = ?", pattern)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// Audit implements Store, the Telegram user being the actor
+func (s *MySQLStore) Audit(ctx context.Context, e *Entry) error {
+	ctx = currentuser.NewContext(ctx, &currentuser.CurrentUser{ID: e.UserID})
+	return audit.Record(ctx, s.DB, audit.RecordTelegramChat, e.ChatID, nil, e)
+}
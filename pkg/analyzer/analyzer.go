@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins_jr"
+)
+
+const (
+	// maxLines caps the console lines quoted in a summary
+	maxLines = 5
+	// maxLineLength caps the length of a quoted line
+	maxLineLength = 200
+	// maxTests caps the failing tests listed in a summary
+	maxTests = 10
+	// MaxTailSize caps the end of a console output analyzed by Build, failures being printed last
+	MaxTailSize = 512 << 10
+)
+
+// Rule recognizes a cause of build failure by the console lines it prints
+type Rule struct {
+	// Cause describes the failure in a summary
+	Cause   string
+	Pattern *regexp.Regexp
+}
+
+// Rules are checked in order, the first one matching a console output giving its probable cause.
+// Causes which make tests or compilation fail in turn come before those.
+var Rules = []Rule{
+	{Cause: "Out of memory", Pattern: regexp.MustCompile(`(?i)(out of memory|OutOfMemoryError|cannot allocate memory|signal: killed|exit code 137)`)},
+	{Cause: "Timeout", Pattern: regexp.MustCompile(`(?i)(test timed out after|build timed out|due to timeout|deadline exceeded)`)},
+	{Cause: "MySQL connection error", Pattern: regexp.MustCompile(`(?i)(dial tcp \S+:3306|can't connect to (local )?mysql server|error 10(40|45): |error 200[23] |\[mysql\]|driver: bad connection)`)},
+	{Cause: "Dependency fetch error", Pattern: regexp.MustCompile(`(?i)(unknown revision|cannot find package|could not resolve (host|dependencies)|unable to access '|dep: |go: .*: (unknown revision|reading .*: 404|dial tcp)|npm ERR! (code E|404|network))`)},
+	{Cause: "Compile error", Pattern: regexp.MustCompile(`^(\S+\.go:\d+(:\d+)?: |\S+\.(java|kt|c|cc|cpp|ts):\d+(:\d+)?: error: |FAIL\s+\S+ \[(build|setup) failed\])`)},
+	{Cause: testFailure, Pattern: regexp.MustCompile(`^(FAIL\s+\S+\s+\d|panic: )`)},
+}
+
+const testFailure = "Go test failure"
+
+// testPattern matches a failing Go test, subtests included
+var testPattern = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+
+// Summary is what a console output tells of a build failure
+type Summary struct {
+	Cause string
+	// Lines are the first lines matching the rule of the cause
+	Lines []string
+	// Tests are the failing Go tests, in order
+	Tests []string
+}
+
+// Analyze summarizes a console output, returning nil when it tells nothing known
+func Analyze(text string) *Summary {
+	lines := strings.Split(text, "\n")
+	s := &Summary{}
+	for _, r := range Rules {
+		for _, line := range lines {
+			if !r.Pattern.MatchString(line) {
+				continue
+			}
+			s.Cause = r.Cause
+			s.Lines = append(s.Lines, shorten(strings.TrimSpace(line)))
+			if len(s.Lines) == maxLines {
+				break
+			}
+		}
+		if s.Cause != "" {
+			break
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, line := range lines {
+		if m := testPattern.FindStringSubmatch(line); m != nil && !seen[m[1]] {
+			seen[m[1]] = true
+			s.Tests = append(s.Tests, m[1])
+		}
+	}
+
+	if s.Cause == "" {
+		if len(s.Tests) == 0 {
+			return nil
+		}
+		s.Cause = testFailure
+	}
+	return s
+}
+
+// Build fetches and summarizes the last MaxTailSize bytes of the console output of a build.
+// Only failed and unstable builds are summarized, nil being returned for others.
+func Build(ctx context.Context, client jenkins_jr.JenkinsClient, job string, b *jenkins.Build) (*Summary, error) {
+	if b.Result != jenkins.ResultFailure && b.Result != jenkins.ResultUnstable {
+		return nil, nil
+	}
+	text, err := tail(ctx, client, job, b.Number)
+	if err != nil {
+		return nil, err
+	}
+	return Analyze(text), nil
+}
+
+// tail reads a console output chunk by chunk, keeping its last lines up to MaxTailSize bytes
+func tail(ctx context.Context, client jenkins_jr.JenkinsClient, job string, number int64) (string, error) {
+	var text []byte
+	var start int64
+	truncated := false
+	for {
+		chunk, err := client.ProgressiveText(ctx, job, number, start)
+		if err != nil {
+			return "", err
+		}
+		text = append(text, chunk.Text...)
+		if len(text) > MaxTailSize {
+			text = append(text[:0], text[len(text)-MaxTailSize:]...)
+			truncated = true
+		}
+		if !chunk.More || chunk.Text == "" {
+			break
+		}
+		start = chunk.Next
+	}
+	if truncated {
+		// the first line is likely cut
+		if k := bytes.IndexByte(text, '\n'); k >= 0 {
+			text = text[k+1:]
+		}
+	}
+	return string(text), nil
+}
+
+// String formats a summary to be appended to a notification
+func (s *Summary) String() string {
+	var b bytes.Buffer
+	b.WriteString("Probable cause: " + s.Cause)
+	for _, line := range s.Lines {
+		b.WriteString("\n> " + line)
+	}
+	if len(s.Tests) > 0 {
+		tests := s.Tests
+		if len(tests) > maxTests {
+			tests = tests[:maxTests]
+		}
+		b.WriteString("\nFailing tests: " + strings.Join(tests, ", "))
+		if len(s.Tests) > maxTests {
+			fmt.Fprintf(&b, " and %d more", len(s.Tests)-maxTests)
+		}
+	}
+	return b.String()
+}
+
+func shorten(line string) string {
+	if len(line) <= maxLineLength {
+		return line
+	}
+	cut := maxLineLength
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "…"
+}
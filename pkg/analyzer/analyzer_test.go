@@ -0,0 +1,106 @@
+package analyzer_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/analyzer"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins/jenkinstest"
+)
+
+func TestAnalyze(t *testing.T) {
+	cases := []struct {
+		console string
+		cause   string
+		lines   []string
+		tests   []string
+	}{
+		{"Started by remote API\nok  \tgithub.com/a/b\t0.01s\nFinished: SUCCESS\n", "", nil, nil},
+		{
+			"=== RUN   TestPoller\n--- FAIL: TestPoller (0.01s)\n    poller_test.go:42: not equal\n=== RUN   TestText\n--- FAIL: TestText (0.00s)\n    --- FAIL: TestText/fixed (0.00s)\nFAIL\nFAIL\tgithub.com/a/subscription\t0.02s\n",
+			"Go test failure",
+			[]string{"FAIL\tgithub.com/a/subscription\t0.02s"},
+			[]string{"TestPoller", "TestText", "TestText/fixed"},
+		},
+		{
+			"# github.com/a/bot\npkg/bot/log.go:12:2: undefined: telegram\nFAIL\tgithub.com/a/bot [build failed]\n",
+			"Compile error",
+			[]string{"pkg/bot/log.go:12:2: undefined: telegram", "FAIL\tgithub.com/a/bot [build failed]"},
+			nil,
+		},
+		{"fatal error: runtime: out of memory\n\ngoroutine 1 [running]:\n", "Out of memory", []string{"fatal error: runtime: out of memory"}, nil},
+		{"panic: test timed out after 10m0s\n\ngoroutine 7 [running]:\n", "Timeout", []string{"panic: test timed out after 10m0s"}, nil},
+		{
+			"--- FAIL: TestMySQLStore (0.00s)\n    store_test.go:20: dial tcp 127.0.0.1:3306: connect: connection refused\nFAIL\tgithub.com/a/watch\t0.01s\n",
+			"MySQL connection error",
+			[]string{"store_test.go:20: dial tcp 127.0.0.1:3306: connect: connection refused"},
+			[]string{"TestMySQLStore"},
+		},
+		{"+ dep ensure -vendor-only\ngrouped write of manifest, lock and vendor: failed to export github.com/jmoiron/sqlx: unknown revision v2\n", "Dependency fetch error", []string{"grouped write of manifest, lock and vendor: failed to export github.com/jmoiron/sqlx: unknown revision v2"}, nil},
+		{"go: github.com/a/b@v1.0.0: reading https://proxy.golang.org/github.com/a/b/@v/v1.0.0.mod: 404 Not Found\n", "Dependency fetch error", []string{"go: github.com/a/b@v1.0.0: reading https://proxy.golang.org/github.com/a/b/@v/v1.0.0.mod: 404 Not Found"}, nil},
+		{
+			"go: downloading github.com/stretchr/testify v1.2.2\n--- FAIL: TestFoo (0.00s)\nFAIL\tgithub.com/a/b\t0.01s\n",
+			"Go test failure",
+			[]string{"FAIL\tgithub.com/a/b\t0.01s"},
+			[]string{"TestFoo"},
+		},
+	}
+	for _, c := range cases {
+		s := analyzer.Analyze(c.console)
+		if c.cause == "" {
+			assert.Nil(t, s, c.console)
+			continue
+		}
+		if assert.NotNil(t, s, c.console) {
+			assert.Equal(t, c.cause, s.Cause)
+			assert.Equal(t, c.lines, s.Lines)
+			assert.Equal(t, c.tests, s.Tests)
+		}
+	}
+}
+
+func TestSummaryString(t *testing.T) {
+	s := &analyzer.Summary{Cause: "Go test failure", Lines: []string{"FAIL\tgithub.com/a/b\t0.01s"}, Tests: []string{"TestA"}}
+	assert.Equal(t, "Probable cause: Go test failure\n> FAIL\tgithub.com/a/b\t0.01s\nFailing tests: TestA", s.String())
+
+	console := strings.Repeat("panic: "+strings.Repeat("é", 150)+"\n", 10)
+	for k := 0; k < 12; k++ {
+		console += fmt.Sprintf("--- FAIL: Test%d (0.00s)\n", k)
+	}
+	s = analyzer.Analyze(console)
+	assert.Len(t, s.Lines, 5)
+	assert.True(t, strings.HasSuffix(s.Lines[0], "é…"), s.Lines[0])
+	assert.True(t, strings.HasSuffix(s.String(), "Test8, Test9 and 2 more"))
+}
+
+func TestBuild(t *testing.T) {
+	server := jenkinstest.NewServer()
+	defer server.Close()
+	server.AddJob("deploy")
+	c := jenkins.New(server.URL, "bot", "token")
+	ctx := context.Background()
+
+	c.Trigger(ctx, "deploy", nil)
+	server.Finish("deploy", 1, jenkins.ResultSuccess, "fatal error: runtime: out of memory\n")
+	b, _ := c.Build(ctx, "deploy", 1)
+	s, err := analyzer.Build(ctx, c, "deploy", b)
+	assert.Nil(t, err)
+	assert.Nil(t, s, "successful builds are not analyzed")
+
+	// only the end of long console outputs is analyzed
+	c.Trigger(ctx, "deploy", nil)
+	console := "fatal error: runtime: out of memory\n" + strings.Repeat("=== RUN   TestFoo\n", analyzer.MaxTailSize/10) + "--- FAIL: TestFoo (0.00s)\n"
+	server.Finish("deploy", 2, jenkins.ResultFailure, console)
+	b, _ = c.Build(ctx, "deploy", 2)
+	s, err = analyzer.Build(ctx, c, "deploy", b)
+	assert.Nil(t, err)
+	if assert.NotNil(t, s) {
+		assert.Equal(t, "Go test failure", s.Cause)
+		assert.Equal(t, []string{"TestFoo"}, s.Tests)
+	}
+}
@@ -0,0 +1,129 @@
+package approval
+
+import (
+	"context"
+	"path"
+	"time"
+)
+
+// Statuses of build requests
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+	// StatusFailed is an approved request whose build could not be queued
+	StatusFailed = "failed"
+)
+
+// Decisions of approvers
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// Rule makes builds of jobs whose full name matches Pattern wait for Approvers users other than the requester
+type Rule struct {
+	ID        int64  `db:"id"`
+	Pattern   string `db:"pattern"`
+	Approvers int    `db:"approvers"`
+	// Window is how long a request waits for approvals, in seconds
+	Window    int64     `db:"window"`
+	CreatedBy int64     `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Matches tells whether a job is covered by the rule
+func (r *Rule) Matches(job string) bool {
+	ok, _ := path.Match(r.Pattern, job)
+	return ok
+}
+
+// Request is a build waiting for approval, kept once decided as history
+type Request struct {
+	ID int64 `db:"id"`
+	// ChatID and MessageID are the approval prompt
+	ChatID    int64  `db:"chat_id"`
+	MessageID int64  `db:"message_id"`
+	Job       string `db:"job"`
+	// Params are the build parameters as JSON, password ones excepted
+	Params string `db:"params"`
+	// Title describes the build in messages
+	Title       string `db:"title"`
+	RequestedBy int64  `db:"requested_by"`
+	// RequesterName is shown in the prompt and the history
+	RequesterName string `db:"requester_name"`
+	Approvers     int    `db:"approvers"`
+	Status        string `db:"status"`
+	// QueueID is the queue item of the build once approved
+	QueueID   int64      `db:"queue_id"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	DecidedAt *time.Time `db:"decided_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+}
+
+// Vote is the decision of a user on a request
+type Vote struct {
+	ID        int64     `db:"id"`
+	RequestID int64     `db:"request_id"`
+	UserID    int64     `db:"user_id"`
+	UserName  string    `db:"user_name"`
+	Decision  string    `db:"decision"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Store keeps approval rules, build requests and votes
+type Store interface {
+	// Rules returns every rule
+	Rules(ctx context.Context) ([]Rule, error)
+	// SetRule creates a rule for a pattern, or replaces its approvers and window
+	SetRule(ctx context.Context, r *Rule) error
+	// RemoveRule removes the rule of a pattern, returning false when there was none
+	RemoveRule(ctx context.Context, pattern string) (bool, error)
+
+	// Create saves a pending request, setting its ID
+	Create(ctx context.Context, r *Request) error
+	// Get returns a request, nil when it does not exist
+	Get(ctx context.Context, id int64) (*Request, error)
+	// Decide saves the status and queue item of a pending request, returning false when it was already decided
+	Decide(ctx context.Context, r *Request) (bool, error)
+	// Finish saves the queue item of an approved request once its build is queued, or StatusFailed when it could not be
+	Finish(ctx context.Context, r *Request) error
+	// Requests returns the latest requests, of a job unless job is empty, newest first
+	Requests(ctx context.Context, job string, limit int) ([]Request, error)
+
+	// Vote saves a decision on a request, returning false when the user already voted on it
+	Vote(ctx context.Context, v *Vote) (bool, error)
+	// Votes returns decisions on a request, oldest first
+	Votes(ctx context.Context, requestID int64) ([]Vote, error)
+}
+
+// Find returns the rule of a job requiring the most approvers, nil when the job needs none
+func Find(ctx context.Context, store Store, job string) (*Rule, error) {
+	rules, err := store.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found *Rule
+	for k, r := range rules {
+		if r.Matches(job) && r.Approvers > 0 && (found == nil || r.Approvers > found.Approvers) {
+			found = &rules[k]
+		}
+	}
+	return found, nil
+}
+
+// Count returns the approvals and rejections of a request
+func Count(votes []Vote) (approvals, rejections []Vote) {
+	for _, v := range votes {
+		if v.Decision == DecisionApprove {
+			approvals = append(approvals, v)
+		} else {
+			rejections = append(rejections, v)
+		}
+	}
+	return approvals, rejections
+}
@@ -0,0 +1,68 @@
+package approval_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/approval"
+)
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	store := approval.NewMemoryStore()
+	r, err := approval.Find(ctx, store, "deploy-production")
+	assert.Nil(t, err)
+	assert.Nil(t, r)
+
+	store.SetRule(ctx, &approval.Rule{Pattern: "deploy-*", Approvers: 1, Window: 3600})
+	store.SetRule(ctx, &approval.Rule{Pattern: "*-production", Approvers: 2, Window: 600})
+	r, _ = approval.Find(ctx, store, "deploy-production")
+	if assert.NotNil(t, r) {
+		assert.Equal(t, "*-production", r.Pattern)
+	}
+	r, _ = approval.Find(ctx, store, "deploy-staging")
+	if assert.NotNil(t, r) {
+		assert.Equal(t, 1, r.Approvers)
+	}
+	r, _ = approval.Find(ctx, store, "lint")
+	assert.Nil(t, r)
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := approval.NewMemoryStore()
+	r := &approval.Request{Job: "deploy", Approvers: 2}
+	assert.Nil(t, store.Create(ctx, r))
+	assert.Equal(t, int64(1), r.ID)
+	assert.Equal(t, approval.StatusPending, r.Status)
+
+	voted, _ := store.Vote(ctx, &approval.Vote{RequestID: 1, UserID: 9, Decision: approval.DecisionApprove})
+	assert.True(t, voted)
+	voted, _ = store.Vote(ctx, &approval.Vote{RequestID: 1, UserID: 9, Decision: approval.DecisionReject})
+	assert.False(t, voted)
+	store.Vote(ctx, &approval.Vote{RequestID: 1, UserID: 10, Decision: approval.DecisionReject})
+	votes, _ := store.Votes(ctx, 1)
+	approvals, rejections := approval.Count(votes)
+	assert.Len(t, approvals, 1)
+	assert.Len(t, rejections, 1)
+
+	// decided once
+	r.Status = approval.StatusRejected
+	decided, _ := store.Decide(ctx, r)
+	assert.True(t, decided)
+	r.Status = approval.StatusApproved
+	decided, _ = store.Decide(ctx, r)
+	assert.False(t, decided)
+	saved, _ := store.Get(ctx, 1)
+	assert.Equal(t, approval.StatusRejected, saved.Status)
+	assert.NotNil(t, saved.DecidedAt)
+
+	// only approved requests are finished
+	r.Status, r.QueueID = approval.StatusApproved, 7
+	assert.Nil(t, store.Finish(ctx, r))
+	saved, _ = store.Get(ctx, 1)
+	assert.Equal(t, approval.StatusRejected, saved.Status)
+	assert.Equal(t, int64(0), saved.QueueID)
+}
@@ -0,0 +1,151 @@
+package approval
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps approvals in process memory, they are lost on restart
+type MemoryStore struct {
+	mu       sync.Mutex
+	rules    []Rule
+	requests []Request
+	votes    []Vote
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Rules implements Store
+func (s *MemoryStore) Rules(_ context.Context) ([]Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := append([]Rule{}, s.rules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Pattern < rules[j].Pattern })
+	return rules, nil
+}
+
+// SetRule implements Store
+func (s *MemoryStore) SetRule(_ context.Context, r *Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, old := range s.rules {
+		if old.Pattern == r.Pattern {
+			s.rules[k].Approvers, s.rules[k].Window, s.rules[k].CreatedBy, s.rules[k].UpdatedAt = r.Approvers, r.Window, r.CreatedBy, now
+			return nil
+		}
+	}
+	saved := *r
+	saved.ID = int64(len(s.rules) + 1)
+	saved.CreatedAt, saved.UpdatedAt = now, now
+	s.rules = append(s.rules, saved)
+	return nil
+}
+
+// RemoveRule implements Store
+func (s *MemoryStore) RemoveRule(_ context.Context, pattern string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, r := range s.rules {
+		if r.Pattern == pattern {
+			s.rules = append(s.rules[:k], s.rules[k+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Create implements Store
+func (s *MemoryStore) Create(_ context.Context, r *Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	r.ID = int64(len(s.requests) + 1)
+	r.Status, r.QueueID, r.DecidedAt = StatusPending, 0, nil
+	r.CreatedAt, r.UpdatedAt = now, now
+	s.requests = append(s.requests, *r)
+	return nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(_ context.Context, id int64) (*Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || id > int64(len(s.requests)) {
+		return nil, nil
+	}
+	r := s.requests[id-1]
+	return &r, nil
+}
+
+// Decide implements Store
+func (s *MemoryStore) Decide(_ context.Context, r *Request) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.ID < 1 || r.ID > int64(len(s.requests)) || s.requests[r.ID-1].Status != StatusPending {
+		return false, nil
+	}
+	now := time.Now()
+	saved := &s.requests[r.ID-1]
+	saved.Status, saved.QueueID, saved.DecidedAt, saved.UpdatedAt = r.Status, r.QueueID, &now, now
+	return true, nil
+}
+
+// Finish implements Store
+func (s *MemoryStore) Finish(_ context.Context, r *Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.ID < 1 || r.ID > int64(len(s.requests)) || s.requests[r.ID-1].Status != StatusApproved {
+		return nil
+	}
+	saved := &s.requests[r.ID-1]
+	saved.Status, saved.QueueID, saved.UpdatedAt = r.Status, r.QueueID, time.Now()
+	return nil
+}
+
+// Requests implements Store
+func (s *MemoryStore) Requests(_ context.Context, job string, limit int) ([]Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := []Request{}
+	for k := len(s.requests) - 1; k >= 0 && len(requests) < limit; k-- {
+		if job == "" || s.requests[k].Job == job {
+			requests = append(requests, s.requests[k])
+		}
+	}
+	return requests, nil
+}
+
+// Vote implements Store
+func (s *MemoryStore) Vote(_ context.Context, v *Vote) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, old := range s.votes {
+		if old.RequestID == v.RequestID && old.UserID == v.UserID {
+			return false, nil
+		}
+	}
+	saved := *v
+	saved.ID = int64(len(s.votes) + 1)
+	saved.CreatedAt = time.Now()
+	s.votes = append(s.votes, saved)
+	return true, nil
+}
+
+// Votes implements Store
+func (s *MemoryStore) Votes(_ context.Context, requestID int64) ([]Vote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	votes := []Vote{}
+	for _, v := range s.votes {
+		if v.RequestID == requestID {
+			votes = append(votes, v)
+		}
+	}
+	return votes, nil
+}
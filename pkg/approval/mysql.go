@@ -0,0 +1,119 @@
+package approval
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+)
+
+// MySQLStore keeps approvals in the approval_rules, build_requests and build_request_votes tables
+type MySQLStore struct {
+	DB *sqlx.DB
+}
+
+func now() string {
+	return time.Now().Format(config.DatabaseDatetimeFormat)
+}
+
+// Rules implements Store
+func (s *MySQLStore) Rules(ctx context.Context) ([]Rule, error) {
+	rules := []Rule{}
+	err := s.DB.SelectContext(ctx, &rules, "SELECT * FROM approval_rules ORDER BY pattern")
+	return rules, err
+}
+
+// SetRule implements Store
+func (s *MySQLStore) SetRule(ctx context.Context, r *Rule) error {
+	n := now()
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO approval_rules (pattern, approvers, `window`, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE approvers = VALUES(approvers), `window` = VALUES(`window`), created_by = VALUES(created_by), updated_at = VALUES(updated_at)",
+		r.Pattern, r.Approvers, r.Window, r.CreatedBy, n, n)
+	return err
+}
+
+// RemoveRule implements Store
+func (s *MySQLStore) RemoveRule(ctx context.Context, pattern string) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM approval_rules WHERE pattern = ?", pattern)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// Create implements Store
+func (s *MySQLStore) Create(ctx context.Context, r *Request) error {
+	n := now()
+	res, err := s.DB.ExecContext(ctx, "INSERT INTO build_requests (chat_id, message_id, job, params, title, requested_by, requester_name, approvers, status, queue_id, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?)",
+		r.ChatID, r.MessageID, r.Job, r.Params, r.Title, r.RequestedBy, r.RequesterName, r.Approvers, StatusPending, r.ExpiresAt.Format(config.DatabaseDatetimeFormat), n, n)
+	if err != nil {
+		return err
+	}
+	r.Status = StatusPending
+	r.ID, err = res.LastInsertId()
+	return err
+}
+
+// Get implements Store
+func (s *MySQLStore) Get(ctx context.Context, id int64) (*Request, error) {
+	var r Request
+	err := s.DB.GetContext(ctx, &r, "SELECT * FROM build_requests WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Decide implements Store
+func (s *MySQLStore) Decide(ctx context.Context, r *Request) (bool, error) {
+	n := now()
+	res, err := s.DB.ExecContext(ctx, "UPDATE build_requests SET status = ?, queue_id = ?, decided_at = ?, updated_at = ? WHERE id = ? AND status = ?",
+		r.Status, r.QueueID, n, n, r.ID, StatusPending)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// Finish implements Store
+func (s *MySQLStore) Finish(ctx context.Context, r *Request) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE build_requests SET status = ?, queue_id = ?, updated_at = ? WHERE id = ? AND status = ?",
+		r.Status, r.QueueID, now(), r.ID, StatusApproved)
+	return err
+}
+
+// Requests implements Store
+func (s *MySQLStore) Requests(ctx context.Context, job string, limit int) ([]Request, error) {
+	requests := []Request{}
+	if job == "" {
+		err := s.DB.SelectContext(ctx, &requests, "SELECT * FROM build_requests ORDER BY id DESC LIMIT ?", limit)
+		return requests, err
+	}
+	err := s.DB.SelectContext(ctx, &requests, "SELECT * FROM build_requests WHERE job = ? ORDER BY id DESC LIMIT ?", job, limit)
+	return requests, err
+}
+
+// Vote implements Store
+func (s *MySQLStore) Vote(ctx context.Context, v *Vote) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, "INSERT IGNORE INTO build_request_votes (request_id, user_id, user_name, decision, created_at) VALUES (?, ?, ?, ?, ?)",
+		v.RequestID, v.UserID, v.UserName, v.Decision, now())
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// Votes implements Store
+func (s *MySQLStore) Votes(ctx context.Context, requestID int64) ([]Vote, error) {
+	votes := []Vote{}
+	err := s.DB.SelectContext(ctx, &votes, "SELECT * FROM build_request_votes WHERE request_id = ? ORDER BY id", requestID)
+	return votes, err
+}
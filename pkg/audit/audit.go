@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+)
+
+// Record types stored in action_log_histories.record_type
+const (
+	RecordPost       = "Post"
+	RecordPostImage  = "PostImage"
+	RecordPostTag    = "PostTag"
+	RecordInfluencer = "Influencer"
+	// RecordTelegramChat records commands sent to the bot in a chat, the actor being the Telegram user
+	RecordTelegramChat = "TelegramChat"
+)
+
+// ignored lists columns not worth recording
+var ignored = map[string]bool{"updated_at": true}
+
+// Changes maps column name to its [before, after] values, like ActiveRecord changes
+type Changes map[string][2]interface{}
+
+// Diff compares db tagged fields of two values of the same struct type.
+// before is nil for creations and after is nil for deletions.
+func Diff(before, after interface{}) (Changes, error) {
+	b, a := indirect(before), indirect(after)
+	if !b.IsValid() && !a.IsValid() {
+		return Changes{}, nil
+	}
+
+	var t reflect.Type
+	if a.IsValid() {
+		t = a.Type()
+	} else {
+		t = b.Type()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("audit: diff requires structs")
+	}
+	if b.IsValid() && a.IsValid() && b.Type() != a.Type() {
+		return nil, errors.New("audit: diff requires values of the same type")
+	}
+
+	changes := Changes{}
+	for k := 0; k < t.NumField(); k++ {
+		column := Column(t.Field(k))
+		if column == "" || ignored[column] {
+			continue
+		}
+
+		var from, to interface{}
+		if b.IsValid() {
+			from = b.Field(k).Interface()
+		}
+		if a.IsValid() {
+			to = a.Field(k).Interface()
+		}
+		if !b.IsValid() || !a.IsValid() || !reflect.DeepEqual(from, to) {
+			changes[column] = [2]interface{}{from, to}
+		}
+	}
+	return changes, nil
+}
+
+// Column returns column name of a struct field, empty when the field is not stored
+func Column(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	column := f.Tag.Get("db")
+	if column == "-" {
+		return ""
+	}
+	return column
+}
+
+func indirect(v interface{}) reflect.Value {
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// Record writes the changes between before and after into action_log_histories.
+// Pass the transaction of the mutation as db so that both are committed together.
+// The actor is the current user of ctx.
+func Record(ctx context.Context, db sqlx.ExecerContext, recordType string, recordID int64, before, after interface{}) error {
+	changes, err := Diff(before, after)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Format(config.DatabaseDatetimeFormat)
+	_, err = db.ExecContext(ctx, "INSERT INTO action_log_histories (record_id, record_type, changes, actor_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		recordID, recordType, string(b), currentuser.FromContext(ctx).ID, now, now)
+	return err
+}
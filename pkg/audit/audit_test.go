@@ -0,0 +1,90 @@
+package audit_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+)
+
+type record struct {
+	ID        int64     `db:"id"`
+	Title     string    `db:"title"`
+	Published bool      `db:"published"`
+	UpdatedAt time.Time `db:"updated_at"`
+	Images    []string  `db:"-"`
+	cached    bool
+}
+
+type execer struct {
+	query string
+	args  []interface{}
+}
+
+func (e *execer) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.query, e.args = query, args
+	return nil, nil
+}
+
+func TestDiff(t *testing.T) {
+	before := record{ID: 1, Title: "Gaya Kasual", UpdatedAt: time.Now()}
+	after := record{ID: 1, Title: "Gaya Kasual Pria", Published: true, UpdatedAt: time.Now().Add(time.Hour), Images: []string{"a.jpg"}, cached: true}
+
+	changes, err := audit.Diff(&before, &after)
+	assert.Nil(t, err)
+	assert.Equal(t, audit.Changes{
+		"title":     {"Gaya Kasual", "Gaya Kasual Pria"},
+		"published": {false, true},
+	}, changes)
+}
+
+func TestDiffCreationAndDeletion(t *testing.T) {
+	r := &record{ID: 1, Title: "Gaya Kasual"}
+
+	changes, err := audit.Diff(nil, r)
+	assert.Nil(t, err)
+	assert.Equal(t, [2]interface{}{nil, "Gaya Kasual"}, changes["title"])
+	assert.Len(t, changes, 3)
+
+	changes, err = audit.Diff(r, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, [2]interface{}{int64(1), nil}, changes["id"])
+}
+
+func TestDiffTypeMismatch(t *testing.T) {
+	_, err := audit.Diff(&record{}, &struct{ ID int64 }{})
+	assert.NotNil(t, err)
+
+	_, err = audit.Diff("a", "b")
+	assert.NotNil(t, err)
+}
+
+func TestRecord(t *testing.T) {
+	ctx := currentuser.NewContext(context.Background(), &currentuser.CurrentUser{ID: 42})
+	e := &execer{}
+
+	err := audit.Record(ctx, e, audit.RecordPost, 7, &record{Title: "a"}, &record{Title: "b"})
+	assert.Nil(t, err)
+	assert.Contains(t, e.query, "INSERT INTO action_log_histories")
+	assert.Equal(t, int64(7), e.args[0])
+	assert.Equal(t, audit.RecordPost, e.args[1])
+	assert.Equal(t, int64(42), e.args[3])
+
+	var changes map[string][]string
+	assert.Nil(t, json.Unmarshal([]byte(e.args[2].(string)), &changes))
+	assert.Equal(t, map[string][]string{"title": {"a", "b"}}, changes)
+}
+
+func TestRecordWithoutChanges(t *testing.T) {
+	e := &execer{}
+	err := audit.Record(context.Background(), e, audit.RecordPost, 7, &record{Title: "a"}, &record{Title: "a"})
+
+	assert.Nil(t, err)
+	assert.Empty(t, e.query)
+}
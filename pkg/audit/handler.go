@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/request"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+// Handler serves audit history endpoints
+type Handler struct {
+	DB *sqlx.DB
+}
+
+// ParseFilter reads record_type, record_id, actor_id, from and to query parameters.
+// from and to use config.DateRangeSearchFormat.
+func ParseFilter(r *http.Request) (Filter, error) {
+	q := r.URL.Query()
+	f := Filter{
+		RecordType: q.Get("record_type"),
+		RecordID:   request.ID(q.Get("record_id")),
+		ActorID:    request.ID(q.Get("actor_id")),
+	}
+	if f.RecordType == RecordTelegramChat {
+		// group chat IDs are negative
+		f.RecordID, _ = strconv.ParseInt(q.Get("record_id"), 10, 64)
+	}
+	if f.RecordType == "" || f.RecordID == 0 {
+		return f, errors.New("record_type and record_id are required")
+	}
+
+	for param, dest := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		if q.Get(param) == "" {
+			continue
+		}
+		t, err := time.Parse(config.DateRangeSearchFormat, q.Get(param))
+		if err != nil {
+			return f, errors.New(param + " must be formatted as " + config.DateRangeSearchFormat)
+		}
+		*dest = &t
+	}
+	return f, nil
+}
+
+// List returns change history of a record, newest first
+func (h *Handler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	f, err := ParseFilter(r)
+	if err != nil {
+		response.Fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, offset := request.Page(r)
+
+	histories, err := List(ctx, h.DB, f, limit, offset)
+	if err != nil {
+		log.ErrLog(ctx, err, "audit", "Failed to list histories")
+		response.Fail(w, http.StatusInternalServerError, "Failed to list histories")
+		return
+	}
+	total, err := Count(ctx, h.DB, f)
+	if err != nil {
+		log.ErrLog(ctx, err, "audit", "Failed to count histories")
+		response.Fail(w, http.StatusInternalServerError, "Failed to count histories")
+		return
+	}
+	response.OK(w, histories, response.Meta{Limit: limit, Offset: offset, Total: total})
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+)
+
+// ErrNotCreated is returned when rewinding a record to a time before its creation
+var ErrNotCreated = errors.New("record did not exist yet")
+
+// RawChanges is Changes as stored, with undecoded values
+type RawChanges map[string][2]json.RawMessage
+
+// Scan implements sql.Scanner
+func (c *RawChanges) Scan(src interface{}) error {
+	*c = RawChanges{}
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("cannot scan %T into RawChanges", src)
+}
+
+// History is a row of action_log_histories
+type History struct {
+	ID         int64      `db:"id" json:"id"`
+	RecordID   int64      `db:"record_id" json:"record_id"`
+	RecordType string     `db:"record_type" json:"record_type"`
+	Changes    RawChanges `db:"changes" json:"changes"`
+	ActorID    int64      `db:"actor_id" json:"actor_id"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Filter narrows down listed histories
+type Filter struct {
+	RecordType string
+	RecordID   int64
+	ActorID    int64
+	From       *time.Time
+	To         *time.Time
+}
+
+func (f Filter) where() (string, []interface{}) {
+	conds := []string{"record_type = ?", "record_id = ?"}
+	args := []interface{}{f.RecordType, f.RecordID}
+	if f.ActorID > 0 {
+		conds = append(conds, "actor_id = ?")
+		args = append(args, f.ActorID)
+	}
+	if f.From != nil {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, datetime(*f.From))
+	}
+	if f.To != nil {
+		conds = append(conds, "created_at <= ?")
+		args = append(args, datetime(*f.To))
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// datetime formats a bound the way created_at is written, in local time
+func datetime(t time.Time) string {
+	return t.In(time.Local).Format(config.DatabaseDatetimeFormat)
+}
+
+const historyColumns = "id, record_id, record_type, changes, COALESCE(actor_id, 0) AS actor_id, created_at"
+
+// List returns histories matching filter, newest first
+func List(ctx context.Context, db sqlx.QueryerContext, f Filter, limit, offset int) ([]History, error) {
+	where, args := f.where()
+	histories := []History{}
+	err := sqlx.SelectContext(ctx, db, &histories, "SELECT "+historyColumns+" FROM action_log_histories"+where+" ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	return histories, err
+}
+
+// Count returns number of histories matching filter
+func Count(ctx context.Context, db sqlx.QueryerContext, f Filter) (int, error) {
+	where, args := f.where()
+	var count int
+	err := sqlx.GetContext(ctx, db, &count, "SELECT COUNT(*) FROM action_log_histories"+where, args...)
+	return count, err
+}
+
+// Since returns histories of a record recorded after t, newest first
+func Since(ctx context.Context, db sqlx.QueryerContext, recordType string, recordID int64, t time.Time) ([]History, error) {
+	histories := []History{}
+	err := sqlx.SelectContext(ctx, db, &histories, "SELECT "+historyColumns+" FROM action_log_histories WHERE record_type = ? AND record_id = ? AND created_at > ? ORDER BY created_at DESC, id DESC", recordType, recordID, datetime(t))
+	return histories, err
+}
+
+// Rewind undoes histories on v, a pointer to the current state of the record.
+// histories must be ordered newest first, as returned by Since.
+func Rewind(v interface{}, histories []History) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("audit: rewind requires pointer to struct")
+	}
+	rv = rv.Elem()
+	t := rv.Type()
+
+	for _, h := range histories {
+		if id, ok := h.Changes["id"]; ok && isNull(id[0]) {
+			return ErrNotCreated
+		}
+		for k := 0; k < t.NumField(); k++ {
+			change, ok := h.Changes[Column(t.Field(k))]
+			if !ok {
+				continue
+			}
+			field := rv.Field(k)
+			if isNull(change[0]) {
+				field.Set(reflect.Zero(field.Type()))
+				continue
+			}
+			if err := json.Unmarshal(change[0], field.Addr().Interface()); err != nil {
+				return fmt.Errorf("audit: rewind %s of history %d: %v", Column(t.Field(k)), h.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
@@ -0,0 +1,118 @@
+package audit_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+)
+
+type post struct {
+	ID              int64      `db:"id"`
+	Title           string     `db:"title"`
+	Published       bool       `db:"published"`
+	LastPublishedAt *time.Time `db:"last_published_at"`
+}
+
+func history(t *testing.T, id int64, before, after interface{}) audit.History {
+	changes, err := audit.Diff(before, after)
+	assert.Nil(t, err)
+
+	b, _ := json.Marshal(changes)
+	h := audit.History{ID: id}
+	assert.Nil(t, h.Changes.Scan(b))
+	return h
+}
+
+func TestRewind(t *testing.T) {
+	published := time.Date(2018, 8, 1, 10, 0, 0, 0, time.UTC)
+	v1 := &post{ID: 1, Title: "Gaya Kasual"}
+	v2 := &post{ID: 1, Title: "Gaya Kasual Pria"}
+	v3 := &post{ID: 1, Title: "Gaya Kasual Pria", Published: true, LastPublishedAt: &published}
+
+	histories := []audit.History{history(t, 3, v2, v3), history(t, 2, v1, v2)}
+
+	p := *v3
+	assert.Nil(t, audit.Rewind(&p, histories[:1]))
+	assert.Equal(t, *v2, p)
+
+	p = *v3
+	assert.Nil(t, audit.Rewind(&p, histories))
+	assert.Equal(t, *v1, p)
+}
+
+func TestRewindBeforeCreation(t *testing.T) {
+	v1 := &post{ID: 1, Title: "Gaya Kasual"}
+	p := *v1
+
+	err := audit.Rewind(&p, []audit.History{history(t, 1, nil, v1)})
+	assert.Equal(t, audit.ErrNotCreated, err)
+}
+
+// queryer records arguments of the last query, failing it
+type queryer struct {
+	args []interface{}
+}
+
+func (q *queryer) QueryContext(_ context.Context, _ string, args ...interface{}) (*sql.Rows, error) {
+	q.args = args
+	return nil, errors.New("queryer: no database")
+}
+
+func (q *queryer) QueryxContext(_ context.Context, _ string, args ...interface{}) (*sqlx.Rows, error) {
+	q.args = args
+	return nil, errors.New("queryer: no database")
+}
+
+func (q *queryer) QueryRowxContext(_ context.Context, _ string, args ...interface{}) *sqlx.Row {
+	q.args = args
+	return &sqlx.Row{}
+}
+
+func TestBoundsInLocalTime(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("WIB", 7*3600)
+	defer func() { time.Local = local }()
+
+	from := time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2018, 8, 2, 0, 0, 0, 0, time.UTC)
+	q := &queryer{}
+
+	_, err := audit.List(context.Background(), q, audit.Filter{RecordType: "Post", RecordID: 3, From: &from, To: &to}, 10, 0)
+	assert.NotNil(t, err)
+	assert.Equal(t, []interface{}{"Post", int64(3), "2018-08-01 07:00:00", "2018-08-02 07:00:00", 10, 0}, q.args)
+
+	_, err = audit.Since(context.Background(), q, "Post", 3, from)
+	assert.NotNil(t, err)
+	assert.Equal(t, []interface{}{"Post", int64(3), "2018-08-01 07:00:00"}, q.args)
+}
+
+func TestParseFilter(t *testing.T) {
+	r := httptest.NewRequest("GET", "/histories?record_type=Post&record_id=3&actor_id=9&from=2018-08-01T00:00:00%2B07:00", nil)
+	f, err := audit.ParseFilter(r)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Post", f.RecordType)
+	assert.Equal(t, int64(3), f.RecordID)
+	assert.Equal(t, int64(9), f.ActorID)
+	assert.Equal(t, time.Date(2018, 7, 31, 17, 0, 0, 0, time.UTC), f.From.UTC())
+	assert.Nil(t, f.To)
+
+	_, err = audit.ParseFilter(httptest.NewRequest("GET", "/histories?record_type=Post", nil))
+	assert.NotNil(t, err)
+
+	_, err = audit.ParseFilter(httptest.NewRequest("GET", "/histories?record_type=Post&record_id=3&to=yesterday", nil))
+	assert.NotNil(t, err)
+
+	f, err = audit.ParseFilter(httptest.NewRequest("GET", "/histories?record_type=TelegramChat&record_id=-1001", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1001), f.RecordID)
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+)
+
+// Scopes required by admin routes
+const (
+	ScopePostsRead        = currentuser.PermissionPostsRead
+	ScopePostsWrite       = currentuser.PermissionPostsWrite
+	ScopeInfluencersWrite = currentuser.PermissionInfluencersWrite
+	ScopeHistoriesRead    = currentuser.PermissionHistoriesRead
+)
+
+// ErrInactive is returned for unknown, expired or revoked tokens
+var ErrInactive = errors.New("token is not active")
+
+// Token is an introspected bearer token, as described by RFC 7662
+type Token struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope"`
+	ClientID  string `json:"client_id"`
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+	// Roles is set on tokens of back office users
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Scopes returns the space separated scopes of the token
+func (t *Token) Scopes() []string {
+	return strings.Fields(t.Scope)
+}
+
+// HasScope tells whether the token was granted scope
+func (t *Token) HasScope(scope string) bool {
+	for _, s := range t.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// User returns the user the token acts for, with ID 0 for clients acting on their own
+func (t *Token) User() *currentuser.CurrentUser {
+	id, _ := strconv.ParseInt(t.Subject, 10, 64)
+	return &currentuser.CurrentUser{ID: id, Roles: t.Roles, Scopes: t.Scopes()}
+}
+
+// Expired tells whether the token expiry, if any, is past
+func (t *Token) Expired() bool {
+	return t.ExpiresAt > 0 && time.Now().Unix() >= t.ExpiresAt
+}
+
+// Introspector resolves bearer tokens
+type Introspector interface {
+	// Introspect returns the token, or ErrInactive when it can't be used
+	Introspect(ctx context.Context, token string) (*Token, error)
+}
+
+// HTTPIntrospector calls an OAuth2 introspection endpoint, authenticated with Spyro client credentials
+type HTTPIntrospector struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	Client       *http.Client
+}
+
+// NewHTTPIntrospector returns HTTPIntrospector for given endpoint and client credentials
+func NewHTTPIntrospector(url, clientID, clientSecret string) *HTTPIntrospector {
+	return &HTTPIntrospector{URL: url, ClientID: clientID, ClientSecret: clientSecret, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Introspect implements Introspector
+func (i *HTTPIntrospector) Introspect(ctx context.Context, token string) (*Token, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequest("POST", i.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(i.ClientID, i.ClientSecret)
+
+	resp, err := i.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned %d", resp.StatusCode)
+	}
+	var t Token
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, err
+	}
+	if !t.Active || t.Expired() {
+		return nil, ErrInactive
+	}
+	return &t, nil
+}
@@ -0,0 +1,144 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/auth"
+	"github.com/wiskarindra/jenkins_jr/pkg/auth/authtest"
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+)
+
+func init() {
+	os.Setenv("ENV", "test")
+}
+
+func TestHTTPIntrospector(t *testing.T) {
+	server := authtest.NewServer("spyro", "secret")
+	defer server.Close()
+	server.Grant("editor-token", auth.Token{Scope: "posts:read posts:write", ClientID: "backoffice", Subject: "42"})
+	server.Grant("expired-token", auth.Token{Scope: "posts:write", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	ctx := context.Background()
+
+	token, err := server.Introspector().Introspect(ctx, "editor-token")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"posts:read", "posts:write"}, token.Scopes())
+	assert.True(t, token.HasScope(auth.ScopePostsWrite))
+	assert.False(t, token.HasScope(auth.ScopeInfluencersWrite))
+
+	_, err = server.Introspector().Introspect(ctx, "unknown-token")
+	assert.Equal(t, auth.ErrInactive, err)
+	_, err = server.Introspector().Introspect(ctx, "expired-token")
+	assert.Equal(t, auth.ErrInactive, err)
+
+	wrongClient := auth.NewHTTPIntrospector(server.URL, "spyro", "wrong")
+	_, err = wrongClient.Introspect(ctx, "editor-token")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, auth.ErrInactive, err)
+}
+
+func TestCachedIntrospector(t *testing.T) {
+	server := authtest.NewServer("spyro", "secret")
+	defer server.Close()
+	server.Grant("editor-token", auth.Token{Scope: "posts:write"})
+	ctx := context.Background()
+	cached := auth.NewCachedIntrospector(server.Introspector(), time.Minute, 100)
+
+	for k := 0; k < 3; k++ {
+		_, err := cached.Introspect(ctx, "editor-token")
+		assert.Nil(t, err)
+		_, err = cached.Introspect(ctx, "unknown-token")
+		assert.Equal(t, auth.ErrInactive, err)
+	}
+	assert.Equal(t, 2, server.Calls())
+
+	server.Revoke("editor-token")
+	_, err := cached.Introspect(ctx, "editor-token")
+	assert.Nil(t, err, "revocations are seen once cached results expire")
+}
+
+func TestRequire(t *testing.T) {
+	server := authtest.NewServer("spyro", "secret")
+	defer server.Close()
+	server.Grant("editor-token", auth.Token{Scope: "posts:write", Subject: "42", Roles: []string{currentuser.RoleEditor}})
+	server.Grant("viewer-token", auth.Token{Scope: "posts:read", Subject: "43"})
+
+	a := &auth.Authenticator{Introspector: server.Introspector()}
+	h := a.Require(auth.ScopePostsWrite, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		user := currentuser.FromContext(r.Context())
+		assert.Equal(t, int64(42), user.ID)
+		assert.Equal(t, []string{currentuser.RoleEditor}, user.Roles)
+		assert.Equal(t, []string{"posts:write"}, user.Scopes)
+		assert.Equal(t, "42", auth.FromContext(r.Context()).Subject)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cases := []struct {
+		authorization string
+		status        int
+		challenge     string
+	}{
+		{"", http.StatusUnauthorized, `Bearer realm="spyro"`},
+		{"Basic c3B5cm86c2VjcmV0", http.StatusUnauthorized, `Bearer realm="spyro"`},
+		{"Bearer unknown-token", http.StatusUnauthorized, `Bearer realm="spyro", error="invalid_token"`},
+		{"Bearer viewer-token", http.StatusForbidden, `Bearer realm="spyro", error="insufficient_scope", scope="posts:write"`},
+		{"bearer editor-token", http.StatusNoContent, ""},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("DELETE", "/posts/1", nil)
+		if c.authorization != "" {
+			r.Header.Set("Authorization", c.authorization)
+		}
+		w := httptest.NewRecorder()
+		h(w, r, nil)
+		assert.Equal(t, c.status, w.Code, c.authorization)
+		assert.Equal(t, c.challenge, w.Header().Get("WWW-Authenticate"), c.authorization)
+	}
+
+	server.Close()
+	r := httptest.NewRequest("DELETE", "/posts/1", nil)
+	r.Header.Set("Authorization", "Bearer editor-token")
+	w := httptest.NewRecorder()
+	h(w, r, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestAuthenticate(t *testing.T) {
+	server := authtest.NewServer("spyro", "secret")
+	defer server.Close()
+	server.Grant("admin-token", auth.Token{Scope: "posts:write", Subject: "7", Roles: []string{currentuser.RoleAdmin}})
+
+	a := &auth.Authenticator{Introspector: server.Introspector()}
+	h := a.Authenticate(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if currentuser.FromContext(r.Context()).Can(currentuser.PermissionPostsDelete) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		authorization string
+		status        int
+	}{
+		{"", http.StatusOK},
+		{"Bearer unknown-token", http.StatusUnauthorized},
+		{"Bearer admin-token", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/posts", nil)
+		if c.authorization != "" {
+			r.Header.Set("Authorization", c.authorization)
+		}
+		w := httptest.NewRecorder()
+		h(w, r, nil)
+		assert.Equal(t, c.status, w.Code, c.authorization)
+	}
+}
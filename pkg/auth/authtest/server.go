@@ -0,0 +1,75 @@
+// Package authtest provides a fake OAuth2 introspection endpoint for tests and local development
+package authtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/auth"
+)
+
+// Server is a fake introspection endpoint knowing a fixed set of tokens
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu     sync.Mutex
+	tokens map[string]auth.Token
+	calls  int
+}
+
+// NewServer starts a Server accepting given client credentials
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{ClientID: clientID, ClientSecret: clientSecret, tokens: map[string]auth.Token{}}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Grant makes token active with given attributes
+func (s *Server) Grant(token string, t auth.Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.Active = true
+	s.tokens[token] = t
+}
+
+// Revoke makes token inactive
+func (s *Server) Revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
+
+// Calls returns number of introspections served
+func (s *Server) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// Introspector returns an HTTPIntrospector calling this server
+func (s *Server) Introspector() *auth.HTTPIntrospector {
+	return auth.NewHTTPIntrospector(s.URL, s.ClientID, s.ClientSecret)
+}
+
+// ServeHTTP implements RFC 7662 token introspection
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if r.Method != "POST" || !ok || id != s.ClientID || secret != s.ClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls++
+	t, ok := s.tokens[r.PostFormValue("token")]
+	s.mu.Unlock()
+	if !ok {
+		t = auth.Token{Active: false}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
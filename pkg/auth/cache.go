@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+)
+
+// CachedIntrospector remembers introspection results for TTL, or until the token expires if sooner.
+// A revoked token is thus accepted for at most TTL. Concurrent introspections of a token are coalesced.
+type CachedIntrospector struct {
+	Introspector Introspector
+	TTL          time.Duration
+	cache        *cache.Cache
+}
+
+// NewCachedIntrospector returns CachedIntrospector remembering at most maxEntries tokens
+func NewCachedIntrospector(i Introspector, ttl time.Duration, maxEntries int) *CachedIntrospector {
+	return &CachedIntrospector{Introspector: i, TTL: ttl, cache: cache.New(maxEntries)}
+}
+
+// inactive is cached in place of ErrInactive, as cache does not keep errors
+type inactive struct{}
+
+// Introspect implements Introspector
+func (c *CachedIntrospector) Introspect(ctx context.Context, token string) (*Token, error) {
+	// raw tokens are not kept in memory longer than needed
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	v, err := c.cache.Fetch(ctx, key, c.TTL, func(ctx context.Context) (interface{}, []string, error) {
+		t, err := c.Introspector.Introspect(ctx, token)
+		if err == ErrInactive {
+			return inactive{}, nil, nil
+		}
+		return t, nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	t, ok := v.(*Token)
+	if !ok || t.Expired() {
+		return nil, ErrInactive
+	}
+	return t, nil
+}
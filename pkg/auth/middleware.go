@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+type key int
+
+// Key is token context key
+const Key key = 0
+
+// NewContext returns context containing given token
+func NewContext(ctx context.Context, t *Token) context.Context {
+	return context.WithValue(ctx, Key, t)
+}
+
+// FromContext returns the token contained in given context, or nil for unauthenticated requests
+func FromContext(ctx context.Context) *Token {
+	t, _ := ctx.Value(Key).(*Token)
+	return t
+}
+
+// Authenticator guards routes with bearer tokens
+type Authenticator struct {
+	Introspector Introspector
+}
+
+// Authenticate wraps h to put the bearer of the Authorization header, if any, in the request context,
+// as a token and as the current user. Requests without token are served anonymously, invalid tokens are rejected.
+func (a *Authenticator) Authenticate(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if BearerToken(r) == "" {
+			h(w, r, ps)
+			return
+		}
+		if t, ok := a.authenticate(w, r); ok {
+			h(w, r.WithContext(withToken(r.Context(), t)), ps)
+		}
+	}
+}
+
+// Require wraps h so that it is only served to bearers of an active token granted scope.
+// The token is stored in the request context, and becomes the current user.
+func (a *Authenticator) Require(scope string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if BearerToken(r) == "" {
+			challenge(w, http.StatusUnauthorized, `Bearer realm="spyro"`, "Missing bearer token")
+			return
+		}
+		t, ok := a.authenticate(w, r)
+		if !ok {
+			return
+		}
+		if !t.HasScope(scope) {
+			challenge(w, http.StatusForbidden, `Bearer realm="spyro", error="insufficient_scope", scope="`+scope+`"`, "Token lacks scope "+scope)
+			return
+		}
+		h(w, r.WithContext(withToken(r.Context(), t)), ps)
+	}
+}
+
+// authenticate introspects the bearer token of r, writing the error response when it can't be used
+func (a *Authenticator) authenticate(w http.ResponseWriter, r *http.Request) (*Token, bool) {
+	ctx := r.Context()
+	t, err := a.Introspector.Introspect(ctx, BearerToken(r))
+	if err == ErrInactive {
+		challenge(w, http.StatusUnauthorized, `Bearer realm="spyro", error="invalid_token"`, "Invalid or expired token")
+		return nil, false
+	}
+	if err != nil {
+		log.ErrLog(ctx, err, "auth", "Failed to introspect token")
+		response.Fail(w, http.StatusServiceUnavailable, "Failed to verify token")
+		return nil, false
+	}
+	return t, true
+}
+
+func withToken(ctx context.Context, t *Token) context.Context {
+	return currentuser.NewContext(NewContext(ctx, t), t.User())
+}
+
+// BearerToken returns the token of the Authorization header, empty when missing
+func BearerToken(r *http.Request) string {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+func challenge(w http.ResponseWriter, status int, authenticate, message string) {
+	w.Header().Set("WWW-Authenticate", authenticate)
+	response.Fail(w, status, message)
+}
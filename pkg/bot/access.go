@@ -0,0 +1,270 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/access"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+// Access restricts commands to the roles of their sender, auditing those requiring more than viewer
+type Access struct {
+	Policy *access.Policy
+}
+
+// Register adds commands managing roles to r
+func (a *Access) Register(r *Router) {
+	r.Handle("whoami", "/whoami", "Show your Telegram user ID and role in this chat", a.Whoami)
+	r.Handle("grant", "/grant user <id> <role> | /grant chat [id] <role>", "Give a role to a user, or to every member of a chat: viewer, developer, lead or admin", a.Require(access.RoleAdmin, a.Grant))
+	r.Handle("revoke", "/revoke user <id> | /revoke chat [id]", "Remove the role of a user or chat", a.Require(access.RoleAdmin, a.Revoke))
+	r.Handle("roles", "/roles", "List granted roles and job rules", a.Require(access.RoleAdmin, a.Roles))
+	r.Handle("restrict", "/restrict <job pattern> <role|off>", "Allow only a role to build and abort jobs matching a pattern such as deploy-production", a.Require(access.RoleAdmin, a.Restrict))
+}
+
+// Require wraps h so that it only runs for senders having role in the chat
+func (a *Access) Require(role string, h HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *Request) (string, error) {
+		userID, chatID := sender(req.Message), req.Message.Chat.ID
+		e := &access.Entry{UserID: userID, ChatID: chatID, Command: req.Command, Text: auditText(req)}
+		if err := a.check(ctx, userID, chatID, role, "/"+req.Command); err != nil {
+			a.audit(ctx, role, e, err)
+			return "", err
+		}
+		text, err := h(ctx, req)
+		a.audit(ctx, role, e, err)
+		return text, err
+	}
+}
+
+// RequireCallback wraps h so that it only runs for users having role in the chat of the button
+func (a *Access) RequireCallback(role string, h CallbackFunc) CallbackFunc {
+	return func(ctx context.Context, cb *Callback) (string, error) {
+		userID, chatID := cb.Query.From.ID, int64(0)
+		text := cb.Query.Data
+		if m := cb.Query.Message; m != nil {
+			chatID = m.Chat.ID
+			text += "\n" + m.Text
+		}
+		e := &access.Entry{UserID: userID, ChatID: chatID, Command: cb.Name, Text: text}
+		if err := a.check(ctx, userID, chatID, role, "press this button"); err != nil {
+			a.audit(ctx, role, e, err)
+			return "", err
+		}
+		reply, err := h(ctx, cb)
+		a.audit(ctx, role, e, err)
+		return reply, err
+	}
+}
+
+// RequireJob checks that a user may build and abort a job in a chat, as restricted by /restrict
+func (a *Access) RequireJob(ctx context.Context, userID, chatID int64, job string) error {
+	required, err := a.Policy.JobRole(ctx, job)
+	if err != nil {
+		return err
+	}
+	role, err := a.Policy.Role(ctx, userID, chatID)
+	if err != nil {
+		return err
+	}
+	if !access.Includes(role, required) {
+		return ForbiddenError(fmt.Sprintf("%s can only be built and aborted by the %s role", job, required))
+	}
+	return nil
+}
+
+func (a *Access) check(ctx context.Context, userID, chatID int64, required, action string) error {
+	role, err := a.Policy.Role(ctx, userID, chatID)
+	if err != nil {
+		return err
+	}
+	if !access.Includes(role, required) {
+		return ForbiddenError(fmt.Sprintf("You need the %s role to %s, ask an admin to grant it to user %d", required, action, userID))
+	}
+	return nil
+}
+
+// audit records commands requiring more than viewer, logging failures to do so
+func (a *Access) audit(ctx context.Context, role string, e *access.Entry, err error) {
+	if access.Level(role) <= access.Level(access.RoleViewer) {
+		return
+	}
+	switch err.(type) {
+	case nil:
+		e.Result = access.ResultOK
+	case ForbiddenError:
+		e.Result = access.ResultDenied
+	default:
+		e.Result = access.ResultFailed
+	}
+	if err := a.Policy.Store.Audit(ctx, e); err != nil {
+		log.ErrLog(ctx, err, "bot", "Failed to audit /"+e.Command)
+	}
+}
+
+// Whoami shows the user ID and role of the sender, so that an admin can grant them one
+func (a *Access) Whoami(ctx context.Context, req *Request) (string, error) {
+	userID, chatID := sender(req.Message), req.Message.Chat.ID
+	role, err := a.Policy.Role(ctx, userID, chatID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		role = "no role"
+	} else {
+		role = "the " + role + " role"
+	}
+	return fmt.Sprintf("You are user %d, with %s in chat %d", userID, role, chatID), nil
+}
+
+// Grant gives a role to a user or chat
+func (a *Access) Grant(ctx context.Context, req *Request) (string, error) {
+	usage := Errorf("Usage: /grant user <id> <role> or /grant chat [id] <role>")
+	if len(req.Args) < 2 {
+		return "", usage
+	}
+	subjectType, subjectID, err := subject(req, req.Args[:len(req.Args)-1])
+	if err != nil {
+		return "", usage
+	}
+	role, err := access.ParseRole(req.Args[len(req.Args)-1])
+	if err != nil {
+		return "", UserError(err.Error())
+	}
+	g := &access.Grant{SubjectType: subjectType, SubjectID: subjectID, Role: role, CreatedBy: sender(req.Message)}
+	if err := a.Policy.Store.Grant(ctx, g); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Granted %s to %s %d", role, subjectType, subjectID), nil
+}
+
+// Revoke removes the role of a user or chat
+func (a *Access) Revoke(ctx context.Context, req *Request) (string, error) {
+	subjectType, subjectID, err := subject(req, req.Args)
+	if err != nil {
+		return "", Errorf("Usage: /revoke user <id> or /revoke chat [id]")
+	}
+	revoked, err := a.Policy.Store.Revoke(ctx, subjectType, subjectID)
+	if err != nil {
+		return "", err
+	}
+	if !revoked {
+		return "", Errorf("%s %d has no role, see /roles", strings.Title(subjectType), subjectID)
+	}
+	reply := fmt.Sprintf("Revoked the role of %s %d", subjectType, subjectID)
+	if subjectType == access.SubjectUser && a.Policy.Admins[subjectID] {
+		reply += ", who stays admin as configured in BOT_ADMINS"
+	}
+	return reply, nil
+}
+
+// Roles lists granted roles and job rules
+func (a *Access) Roles(ctx context.Context, _ *Request) (string, error) {
+	grants, err := a.Policy.Store.AllGrants(ctx)
+	if err != nil {
+		return "", err
+	}
+	rules, err := a.Policy.Store.JobRules(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	admins := []string{}
+	for id := range a.Policy.Admins {
+		admins = append(admins, strconv.FormatInt(id, 10))
+	}
+	sort.Strings(admins)
+	lines := []string{}
+	if len(admins) > 0 {
+		lines = append(lines, "Admins: user "+strings.Join(admins, ", user "))
+	}
+	if len(grants) == 0 {
+		lines = append(lines, "No roles granted")
+	} else {
+		lines = append(lines, "Roles:")
+		for _, g := range grants {
+			lines = append(lines, fmt.Sprintf("  %s %d: %s", g.SubjectType, g.SubjectID, g.Role))
+		}
+	}
+	if len(rules) == 0 {
+		lines = append(lines, "Jobs can be built by developers")
+	} else {
+		lines = append(lines, "Job rules:")
+		for _, r := range rules {
+			lines = append(lines, fmt.Sprintf("  %s: %s", r.Pattern, r.Role))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Restrict sets or removes the role required to build and abort jobs matching a pattern
+func (a *Access) Restrict(ctx context.Context, req *Request) (string, error) {
+	if len(req.Args) != 2 {
+		return "", Errorf("Usage: /restrict <job pattern> <role|off>")
+	}
+	pattern := req.Args[0]
+	if req.Args[1] == "off" {
+		removed, err := a.Policy.Store.RemoveJobRule(ctx, pattern)
+		if err != nil {
+			return "", err
+		}
+		if !removed {
+			return "", Errorf("%s is not restricted, see /roles", pattern)
+		}
+		return fmt.Sprintf("Jobs matching %s can be built by developers", pattern), nil
+	}
+
+	if err := access.ValidPattern(pattern); err != nil {
+		return "", UserError(err.Error())
+	}
+	role, err := access.ParseRole(req.Args[1])
+	if err != nil {
+		return "", UserError(err.Error())
+	}
+	if !access.Includes(role, access.RoleDeveloper) {
+		return "", Errorf("Building jobs always needs the developer role or above")
+	}
+	if err := a.Policy.Store.SetJobRule(ctx, &access.JobRule{Pattern: pattern, Role: role, CreatedBy: sender(req.Message)}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Jobs matching %s can only be built and aborted by the %s role", pattern, role), nil
+}
+
+// subject parses user <id>, chat or chat <id>, the chat being the current one when its ID is omitted
+func subject(req *Request, args []string) (string, int64, error) {
+	if len(args) == 1 && args[0] == access.SubjectChat {
+		return access.SubjectChat, req.Message.Chat.ID, nil
+	}
+	if len(args) != 2 || (args[0] != access.SubjectUser && args[0] != access.SubjectChat) {
+		return "", 0, Errorf("Expected user <id> or chat [id]")
+	}
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || id == 0 {
+		return "", 0, Errorf("Invalid ID %s", args[1])
+	}
+	return args[0], id, nil
+}
+
+// sender returns the Telegram user who sent m, 0 for channel posts
+func sender(m *telegram.Message) int64 {
+	if m.From == nil {
+		return 0
+	}
+	return m.From.ID
+}
+
+// auditText returns a command as sent, without values of name=value arguments which may be passwords
+func auditText(req *Request) string {
+	fields := []string{"/" + req.Command}
+	for _, arg := range req.Args {
+		if eq := strings.Index(arg, "="); eq > 0 {
+			arg = arg[:eq+1] + "***"
+		}
+		fields = append(fields, arg)
+	}
+	return strings.Join(fields, " ")
+}
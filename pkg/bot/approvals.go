@@ -0,0 +1,305 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/access"
+	"github.com/wiskarindra/jenkins_jr/pkg/approval"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+const (
+	// approvalWindow is how long build requests wait for approvals by default
+	approvalWindow = time.Hour
+	// maxApprovalWindow caps the window of /protect
+	maxApprovalWindow = 24 * time.Hour
+	// maxApprovers caps the approvers of /protect
+	maxApprovers = 5
+	// historySize is the number of requests shown by /approvals
+	historySize = 10
+)
+
+// requestApproval turns a confirmed build of a protected job into a request waiting for approvers
+func (c *Commands) requestApproval(ctx context.Context, cb *Callback, p *pendingBuild, params map[string]string, rule *approval.Rule) (string, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	m := cb.Query.Message
+	r := &approval.Request{
+		ChatID:        m.Chat.ID,
+		MessageID:     m.MessageID,
+		Job:           p.job,
+		Params:        string(data),
+		Title:         p.describe(),
+		RequestedBy:   p.userID,
+		RequesterName: userName(&cb.Query.From),
+		Approvers:     rule.Approvers,
+		ExpiresAt:     time.Now().Add(time.Duration(rule.Window) * time.Second),
+	}
+	if err := c.Approvals.Create(ctx, r); err != nil {
+		return "", err
+	}
+	cb.Buttons = approvalButtons(r.ID)
+	return prompt(r, nil), nil
+}
+
+// Approve records the decision of a user on a build request, queuing the build once approved by enough users.
+// The requester can only withdraw the request.
+func (c *Commands) Approve(ctx context.Context, cb *Callback) (string, error) {
+	parts := strings.SplitN(cb.Data, ":", 2)
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if len(parts) != 2 || err != nil {
+		return "", Errorf("This button is no longer supported")
+	}
+	r, err := c.Approvals.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return "", Errorf("This build request no longer exists")
+	}
+	if r.Status != approval.StatusPending {
+		return "", Errorf("This build request is already %s", r.Status)
+	}
+
+	user := &cb.Query.From
+	if time.Now().After(r.ExpiresAt) {
+		return c.decide(ctx, r, approval.StatusExpired, fmt.Sprintf("Expired without %s: %s", plural(r.Approvers, "approval"), r.Title))
+	}
+	if user.ID == r.RequestedBy {
+		if parts[1] == "yes" {
+			return "", Errorf("You cannot approve your own build request")
+		}
+		return c.decide(ctx, r, approval.StatusCancelled, fmt.Sprintf("Withdrawn by %s: %s", userName(user), r.Title))
+	}
+	if err := c.Access.RequireJob(ctx, user.ID, r.ChatID, r.Job); err != nil {
+		return "", err
+	}
+
+	v := &approval.Vote{RequestID: r.ID, UserID: user.ID, UserName: userName(user), Decision: approval.DecisionApprove}
+	if parts[1] != "yes" {
+		v.Decision = approval.DecisionReject
+	}
+	voted, err := c.Approvals.Vote(ctx, v)
+	if err != nil {
+		return "", err
+	}
+	votes, err := c.Approvals.Votes(ctx, r.ID)
+	if err != nil {
+		return "", err
+	}
+	approvals, _ := approval.Count(votes)
+	if !voted {
+		return "", Errorf("You already decided on this build request")
+	}
+	if voted && v.Decision == approval.DecisionReject {
+		return c.decide(ctx, r, approval.StatusRejected, fmt.Sprintf("Rejected by %s: %s", v.UserName, r.Title))
+	}
+	if len(approvals) < r.Approvers {
+		cb.Buttons = approvalButtons(r.ID)
+		return prompt(r, approvals), nil
+	}
+
+	// approved before queuing, so that approvers pressing at once do not queue the build twice
+	r.Status = approval.StatusApproved
+	decided, err := c.Approvals.Decide(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	if !decided {
+		return "", Errorf("This build request was already decided")
+	}
+	id, err = c.queue(ctx, r)
+	if err != nil {
+		log.ErrLog(ctx, err, "bot", fmt.Sprintf("Failed to queue build of request %d", r.ID))
+		r.Status = approval.StatusFailed
+		if err := c.Approvals.Finish(ctx, r); err != nil {
+			log.ErrLog(ctx, err, "bot", fmt.Sprintf("Failed to save request %d", r.ID))
+		}
+		return fmt.Sprintf("Approved by %s, but %s could not be queued, send /build again", names(approvals), r.Title), nil
+	}
+	r.QueueID = id
+	if err := c.Approvals.Finish(ctx, r); err != nil {
+		log.ErrLog(ctx, err, "bot", fmt.Sprintf("Failed to save queue item of request %d", r.ID))
+	}
+	if err := c.Watcher.Start(ctx, r.ChatID, r.MessageID, r.Job, r.Title, id); err != nil {
+		log.ErrLog(ctx, err, "bot", "Failed to watch build")
+		return fmt.Sprintf("Approved by %s, queued %s as item %d, its progress cannot be shown", names(approvals), r.Title, id), nil
+	}
+	return "", nil
+}
+
+// queue triggers the build of an approved request, returning its queue item
+func (c *Commands) queue(ctx context.Context, r *approval.Request) (int64, error) {
+	params := map[string]string{}
+	if err := json.Unmarshal([]byte(r.Params), &params); err != nil {
+		return 0, err
+	}
+	return c.Jenkins.Trigger(ctx, r.Job, params)
+}
+
+// decide ends a pending request, replacing its prompt with text
+func (c *Commands) decide(ctx context.Context, r *approval.Request, status, text string) (string, error) {
+	r.Status = status
+	decided, err := c.Approvals.Decide(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	if !decided {
+		return "", Errorf("This build request was already decided")
+	}
+	return text, nil
+}
+
+// History shows protected jobs and the latest build requests, of a job when given
+func (c *Commands) History(ctx context.Context, req *Request) (string, error) {
+	if len(req.Args) > 1 {
+		return "", Errorf("Usage: /approvals [job]")
+	}
+	job := ""
+	if len(req.Args) == 1 {
+		job = req.Args[0]
+	}
+	rules, err := c.Approvals.Rules(ctx)
+	if err != nil {
+		return "", err
+	}
+	requests, err := c.Approvals.Requests(ctx, job, historySize)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	if len(rules) == 0 {
+		lines = append(lines, "No jobs need approval")
+	} else {
+		lines = append(lines, "Protected jobs:")
+		for _, r := range rules {
+			lines = append(lines, fmt.Sprintf("  %s: %s within %s", r.Pattern, plural(r.Approvers, "approval"), time.Duration(r.Window)*time.Second))
+		}
+	}
+	if len(requests) == 0 {
+		lines = append(lines, "No build requests")
+		return strings.Join(lines, "\n"), nil
+	}
+	lines = append(lines, "Build requests:")
+	for _, r := range requests {
+		votes, err := c.Approvals.Votes(ctx, r.ID)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("  #%d %s by %s on %s: %s", r.ID, r.Job, r.RequesterName, r.CreatedAt.Format("Jan 2 15:04"), outcome(&r, votes)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// outcome describes the status of a request and who decided it
+func outcome(r *approval.Request, votes []approval.Vote) string {
+	approvals, rejections := approval.Count(votes)
+	switch r.Status {
+	case approval.StatusApproved:
+		return "approved by " + names(approvals)
+	case approval.StatusRejected:
+		return "rejected by " + names(rejections)
+	case approval.StatusFailed:
+		return "approved by " + names(approvals) + ", failed to queue"
+	case approval.StatusPending:
+		if time.Now().After(r.ExpiresAt) {
+			return "expired"
+		}
+		return fmt.Sprintf("waiting, %d of %d approvals", len(approvals), r.Approvers)
+	}
+	return r.Status
+}
+
+// Protect sets or removes the approvers needed by builds of jobs matching a pattern
+func (c *Commands) Protect(ctx context.Context, req *Request) (string, error) {
+	usage := Errorf("Usage: /protect <job pattern> <approvers> [window] or /protect <job pattern> off")
+	if len(req.Args) < 2 || len(req.Args) > 3 {
+		return "", usage
+	}
+	pattern := req.Args[0]
+	if req.Args[1] == "off" && len(req.Args) == 2 {
+		removed, err := c.Approvals.RemoveRule(ctx, pattern)
+		if err != nil {
+			return "", err
+		}
+		if !removed {
+			return "", Errorf("%s is not protected, see /approvals", pattern)
+		}
+		return fmt.Sprintf("Builds of jobs matching %s do not need approval anymore", pattern), nil
+	}
+
+	if err := access.ValidPattern(pattern); err != nil {
+		return "", UserError(err.Error())
+	}
+	approvers, err := strconv.Atoi(req.Args[1])
+	if err != nil || approvers < 1 || approvers > maxApprovers {
+		return "", Errorf("Approvers must be between 1 and %d", maxApprovers)
+	}
+	window := approvalWindow
+	if len(req.Args) == 3 {
+		window, err = time.ParseDuration(req.Args[2])
+		if err != nil || window < time.Minute || window > maxApprovalWindow {
+			return "", Errorf("Window must be a duration between 1m and %s, such as 30m", maxApprovalWindow)
+		}
+	}
+
+	rule := &approval.Rule{Pattern: pattern, Approvers: approvers, Window: int64(window / time.Second), CreatedBy: sender(req.Message)}
+	if err := c.Approvals.SetRule(ctx, rule); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Builds of jobs matching %s need %s from other users within %s", pattern, plural(approvers, "approval"), window), nil
+}
+
+// prompt is the message of a request waiting for approvals
+func prompt(r *approval.Request, approvals []approval.Vote) string {
+	text := fmt.Sprintf("Approval needed to build %s\nRequested by %s, %d of %d approvals, expiring in %s",
+		r.Title, r.RequesterName, len(approvals), r.Approvers, time.Until(r.ExpiresAt).Round(time.Minute))
+	if len(approvals) > 0 {
+		text += "\nApproved by " + names(approvals)
+	}
+	return text
+}
+
+func approvalButtons(id int64) [][]telegram.InlineKeyboardButton {
+	data := strconv.FormatInt(id, 10)
+	return [][]telegram.InlineKeyboardButton{{
+		{Text: "Approve", CallbackData: CallbackData("approve", data+":yes")},
+		{Text: "Reject", CallbackData: CallbackData("approve", data+":no")},
+	}}
+}
+
+func names(votes []approval.Vote) string {
+	names := make([]string, len(votes))
+	for k, v := range votes {
+		names[k] = v.UserName
+	}
+	return strings.Join(names, ", ")
+}
+
+// userName returns the @username of a user, or their name when they have none
+func userName(u *telegram.User) string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		return "user " + strconv.FormatInt(u.ID, 10)
+	}
+	return name
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/resource"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+// maxBackoff caps the wait between failed polls
+const maxBackoff = 30 * time.Second
+
+// Bot answers commands received by long polling the Telegram Bot API
+type Bot struct {
+	API    *telegram.Bot
+	Router *Router
+	// PollTimeout is how long each poll waits for updates
+	PollTimeout time.Duration
+}
+
+// New returns Bot answering with router
+func New(api *telegram.Bot, router *Router) *Bot {
+	return &Bot{API: api, Router: router, PollTimeout: 50 * time.Second}
+}
+
+// Run polls and handles updates until ctx is done.
+// Failed calls to Telegram are retried with backoff, so that the API keeps serving while Telegram is unreachable.
+func (b *Bot) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		me, err := b.API.GetMe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			log.DevLog("Bot @" + me.Username + " polling updates")
+			b.Router.Username = me.Username
+			break
+		}
+		log.DevLog("Failed to get bot user:", err)
+		if !sleep(ctx, &backoff) {
+			return
+		}
+	}
+
+	var offset int64
+	backoff = time.Second
+	for {
+		updates, err := b.API.GetUpdates(ctx, offset, b.PollTimeout)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.DevLog("Failed to get updates:", err)
+			if !sleep(ctx, &backoff) {
+				return
+			}
+			continue
+		}
+		backoff = time.Second
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message != nil {
+				b.Handle(ctx, u.UpdateID, u.Message)
+			}
+			if u.CallbackQuery != nil {
+				b.HandleCallback(ctx, u.UpdateID, u.CallbackQuery)
+			}
+		}
+	}
+}
+
+// sleep waits for backoff, which it doubles up to maxBackoff. It returns false when ctx is done first.
+func sleep(ctx context.Context, backoff *time.Duration) bool {
+	select {
+	case <-time.After(*backoff):
+	case <-ctx.Done():
+		return false
+	}
+	if *backoff *= 2; *backoff > maxBackoff {
+		*backoff = maxBackoff
+	}
+	return true
+}
+
+// Handle answers a message if it is a command
+func (b *Bot) Handle(ctx context.Context, updateID int64, m *telegram.Message) {
+	name, _, ok := Parse(m.Text, b.Router.Username)
+	if !ok {
+		return
+	}
+	ctx = resource.NewContext(ctx, "update-"+strconv.FormatInt(updateID, 10), "bot/"+name, time.Now())
+
+	reply, err := b.Router.Dispatch(ctx, m)
+	if err != nil {
+		reply = Reply{Text: b.failure(ctx, "/"+name, err)}
+	}
+	// long replies are split, buttons going below the last part
+	parts := Split(reply.Text, telegram.MaxMessageLength)
+	for k, part := range parts {
+		if k == len(parts)-1 && len(reply.Buttons) > 0 {
+			_, err = b.API.SendKeyboard(ctx, m.Chat.ID, part, reply.Buttons)
+		} else {
+			_, err = b.API.SendMessage(ctx, m.Chat.ID, part)
+		}
+		if err != nil {
+			log.ErrLog(ctx, err, "bot", "Failed to send reply")
+			return
+		}
+	}
+	if d := reply.Document; d != nil {
+		if _, err := b.API.SendDocument(ctx, m.Chat.ID, d.Name, d.Data, d.Caption); err != nil {
+			log.ErrLog(ctx, err, "bot", "Failed to send document")
+		}
+	}
+}
+
+// Split cuts text into parts of at most max bytes, at line ends when possible
+func Split(text string, max int) []string {
+	parts := []string{}
+	for len(text) > max {
+		cut := strings.LastIndex(text[:max], "\n")
+		if cut <= 0 {
+			// a single line longer than max, cut at a character start
+			cut = max
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		parts = append(parts, text[:cut])
+		text = strings.TrimPrefix(text[cut:], "\n")
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
+
+// HandleCallback answers a button press, replacing the message of the button with the handler reply and its buttons
+func (b *Bot) HandleCallback(ctx context.Context, updateID int64, q *telegram.CallbackQuery) {
+	name := strings.SplitN(q.Data, ":", 2)[0]
+	ctx = resource.NewContext(ctx, "update-"+strconv.FormatInt(updateID, 10), "bot/callback/"+name, time.Now())
+
+	reply, err := b.Router.DispatchCallback(ctx, q)
+	answer := ""
+	if err != nil {
+		answer = b.failure(ctx, name, err)
+	}
+	if err := b.API.AnswerCallbackQuery(ctx, q.ID, answer); err != nil {
+		log.ErrLog(ctx, err, "bot", "Failed to answer callback query")
+	}
+	if reply.Text == "" || q.Message == nil {
+		return
+	}
+	if len(reply.Buttons) > 0 {
+		err = b.API.EditMessageKeyboard(ctx, q.Message.Chat.ID, q.Message.MessageID, reply.Text, reply.Buttons)
+	} else {
+		err = b.API.EditMessageText(ctx, q.Message.Chat.ID, q.Message.MessageID, reply.Text)
+	}
+	if err != nil {
+		log.ErrLog(ctx, err, "bot", "Failed to edit message")
+	}
+}
+
+// failure explains err to the user, logging it unless it is a UserError or ForbiddenError
+func (b *Bot) failure(ctx context.Context, action string, err error) string {
+	switch err.(type) {
+	case UserError, ForbiddenError:
+		return err.Error()
+	}
+	log.ErrLog(ctx, err, "bot", "Failed to run "+action)
+	return fmt.Sprintf("Failed to run %s: %v", action, err)
+}
@@ -0,0 +1,522 @@
+package bot_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/access"
+	"github.com/wiskarindra/jenkins_jr/pkg/approval"
+	"github.com/wiskarindra/jenkins_jr/pkg/bot"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins/jenkinstest"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram/telegramtest"
+	"github.com/wiskarindra/jenkins_jr/pkg/watch"
+)
+
+const (
+	chatID = 100
+	userID = 7
+)
+
+func init() {
+	os.Setenv("ENV", "test")
+}
+
+// start runs a bot against fake Jenkins and Telegram servers until the test ends, userID being admin.
+// Its watcher is not run, builds are checked again with Check.
+func start(t *testing.T) (*jenkinstest.Server, *telegramtest.Server, *watch.Watcher, func()) {
+	return startWith(t, access.NewMemoryStore(), subscription.NewMemoryStore())
+}
+
+// startWith is start keeping roles in store and subscriptions in subs
+func startWith(t *testing.T, store access.Store, subs subscription.Store) (*jenkinstest.Server, *telegramtest.Server, *watch.Watcher, func()) {
+	j := jenkinstest.NewServer()
+	tg := telegramtest.NewServer("123:token")
+
+	client := jenkins.New(j.URL, "bot", "token")
+	watcher := watch.New(watch.NewMemoryStore(), client, tg.Bot())
+	acl := &bot.Access{Policy: access.NewPolicy(store, userID)}
+	router := bot.NewRouter()
+	acl.Register(router)
+	approvals := approval.NewMemoryStore()
+	bot.NewCommands(client, watcher, acl, approvals).Register(router)
+	(&bot.Subscriptions{Store: subs, Location: time.UTC, Access: acl}).Register(router)
+	(&bot.Inputs{Store: subs, Jenkins: client, Access: acl, Approvals: approvals}).Register(router)
+	b := bot.New(tg.Bot(), router)
+	b.PollTimeout = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+	return j, tg, watcher, func() {
+		cancel()
+		<-done
+		tg.Close()
+		j.Close()
+	}
+}
+
+// ask sends text to the bot and returns its reply
+func ask(t *testing.T, tg *telegramtest.Server, text string) string {
+	return send(t, tg, userID, text).Text
+}
+
+// send sends text to the bot as a user and returns the reply message
+func send(t *testing.T, tg *telegramtest.Server, from int64, text string) telegramtest.Sent {
+	n := len(tg.Sent()) + 1
+	tg.Send(chatID, from, text)
+	sent := tg.Wait(n, 2*time.Second)
+	if !assert.Len(t, sent, n, text) {
+		return telegramtest.Sent{}
+	}
+	assert.Equal(t, int64(chatID), sent[n-1].ChatID)
+	return sent[n-1]
+}
+
+// press presses a button as a user and returns the n calls the bot made in response
+func press(t *testing.T, tg *telegramtest.Server, from int64, m telegramtest.Sent, data string, n int) []telegramtest.Sent {
+	before := len(tg.Sent())
+	tg.Press(chatID, from, m.MessageID, data)
+	sent := tg.Wait(before+n, 2*time.Second)
+	if !assert.Len(t, sent, before+n, data) {
+		return nil
+	}
+	return sent[before:]
+}
+
+// confirm sends /build and presses its Build button, returning the message once edited by the bot
+func confirm(t *testing.T, tg *telegramtest.Server, text string) string {
+	m := send(t, tg, userID, text)
+	if !assert.Len(t, m.Buttons(), 2, m.Text) {
+		return m.Text
+	}
+	for _, s := range press(t, tg, userID, m, m.Buttons()[0], 2) {
+		if s.Method == "editMessageText" {
+			return s.Text
+		}
+	}
+	return ""
+}
+
+func TestParse(t *testing.T) {
+	name, args, ok := bot.Parse("/Build@Jenkins_Jr_Bot deploy-web  #4", "jenkins_jr_bot")
+	assert.True(t, ok)
+	assert.Equal(t, "build", name)
+	assert.Equal(t, []string{"deploy-web", "#4"}, args)
+
+	name, _, ok = bot.Parse("/build deploy-web", "jenkins_jr_bot")
+	assert.True(t, ok)
+	assert.Equal(t, "build", name)
+
+	// commands addressed to other bots of a group are ignored
+	_, _, ok = bot.Parse("/build@other_bot deploy-web", "jenkins_jr_bot")
+	assert.False(t, ok)
+
+	_, _, ok = bot.Parse("build deploy-web", "jenkins_jr_bot")
+	assert.False(t, ok)
+	_, _, ok = bot.Parse("/", "jenkins_jr_bot")
+	assert.False(t, ok)
+}
+
+func TestTail(t *testing.T) {
+	assert.Equal(t, "c\nd", bot.Tail("a\nb\nc\nd\n", 2, 100))
+	assert.Equal(t, "d", bot.Tail("a\nb\nccc\nd\n", 3, 4))
+	assert.Equal(t, "é", bot.Tail("éé", 1, 3))
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []string{"abc"}, bot.Split("abc", 10))
+	assert.Equal(t, []string{"ab", "cd", "ef"}, bot.Split("ab\ncd\nef", 4))
+	assert.Equal(t, []string{"abcd", "ef"}, bot.Split("abcdef", 4))
+	assert.Equal(t, []string{"é", "é"}, bot.Split("éé", 3))
+}
+
+func TestCommands(t *testing.T) {
+	j, tg, _, stop := start(t)
+	defer stop()
+	j.AddJob("deploy-web")
+	j.AddJob("mobile/android")
+
+	assert.Contains(t, ask(t, tg, "/help"), "/build <job> [name=value ...] - Start a build, asking for confirmation")
+	assert.Equal(t, "Unknown command /deploy, see /help", ask(t, tg, "/deploy"))
+	assert.Equal(t, "deploy-web: not built\nmobile/android: not built", ask(t, tg, "/jobs"))
+
+	assert.Equal(t, "Usage: /build <job> [name=value ...]", ask(t, tg, "/build"))
+	assert.Equal(t, "Unknown job api, see /jobs", ask(t, tg, "/build api"))
+	assert.Equal(t, "deploy-web was never built", ask(t, tg, "/status deploy-web"))
+
+	reply := confirm(t, tg, "/build deploy-web")
+	assert.True(t, strings.HasPrefix(reply, "Building #1: deploy-web\n0s elapsed of about 1m0s\n"), reply)
+	assert.Contains(t, ask(t, tg, "/status deploy-web"), "deploy-web #1: building for")
+	assert.Equal(t, "deploy-web: not built, building\nmobile/android: not built", ask(t, tg, "/jobs"))
+
+	assert.True(t, strings.HasPrefix(ask(t, tg, "/abort deploy-web #1"), "Aborting deploy-web #1"))
+	assert.Equal(t, "deploy-web #1 is not running, it ended with ABORTED", ask(t, tg, "/abort deploy-web"))
+
+	confirm(t, tg, "/build mobile/android")
+	j.Finish("mobile/android", 1, jenkins.ResultFailure, "> Task :app:test FAILED\nBUILD FAILED in 42s\n")
+	assert.Contains(t, ask(t, tg, "/status mobile/android 1"), "mobile/android #1: FAILURE in")
+	assert.Equal(t, "mobile/android #1, last 30 lines:\nStarted by remote API\n> Task :app:test FAILED\nBUILD FAILED in 42s", ask(t, tg, "/log mobile/android"))
+	assert.Equal(t, "mobile/android has no build #2", ask(t, tg, "/log mobile/android 2"))
+	assert.Equal(t, "Invalid build number two", ask(t, tg, "/log mobile/android two"))
+	assert.Equal(t, "mobile/android #1, last 2 lines:\n> Task :app:test FAILED\nBUILD FAILED in 42s", ask(t, tg, "/log mobile/android #1 -n 2"))
+	assert.Equal(t, "Number of lines must be between 1 and 1000", ask(t, tg, "/log mobile/android -n 0"))
+	assert.Equal(t, "mobile/android #1, 2 lines matching FAIL, last 1:\n3: BUILD FAILED in 42s", ask(t, tg, "/log mobile/android -n 1 -grep FAIL"))
+	assert.Equal(t, "mobile/android #1, 1 lines matching :app:(test|lint) FAILED:\n2: > Task :app:test FAILED", ask(t, tg, "/log mobile/android -grep :app:(test|lint) FAILED"))
+	assert.Equal(t, "mobile/android #1 has no lines matching panic", ask(t, tg, "/log mobile/android -grep panic"))
+	assert.Equal(t, "Invalid regex (", ask(t, tg, "/log mobile/android -grep ("))
+	assert.Equal(t, "Usage: /log <job> [build] [-n lines] [-file] [-grep regex]", ask(t, tg, "/log mobile/android -all"))
+
+	// other messages, and commands addressed to other bots, are ignored
+	tg.Send(chatID, userID, "thanks!")
+	tg.Send(chatID, userID, "/deploy@other_bot")
+	assert.Equal(t, "Commands:", strings.Split(ask(t, tg, "/start@jenkins_jr_bot"), "\n")[0])
+}
+
+func TestLogFile(t *testing.T) {
+	j, tg, _, stop := start(t)
+	defer stop()
+	j.AddJob("mobile/android")
+	confirm(t, tg, "/build mobile/android")
+	console := strings.Repeat("> Task :app:compileDebugKotlin\n", 500)
+	j.Finish("mobile/android", 1, jenkins.ResultSuccess, console)
+
+	m := send(t, tg, userID, "/log mobile/android -file")
+	assert.Equal(t, "sendDocument", m.Method)
+	assert.Equal(t, "mobile_android-1.log.gz", m.Filename)
+	assert.Equal(t, "mobile/android #1 console output", m.Text)
+	gz, err := gzip.NewReader(bytes.NewReader(m.Document))
+	if assert.Nil(t, err) {
+		data, err := ioutil.ReadAll(gz)
+		assert.Nil(t, err)
+		assert.Equal(t, "Started by remote API\n"+console, string(data))
+	}
+
+	// long output is split in several messages, the file following them
+	n := len(tg.Sent())
+	tg.Send(chatID, userID, "/log mobile/android -n 500 -file")
+	sent := tg.Wait(n+5, 2*time.Second)
+	if assert.Len(t, sent, n+5) {
+		texts := []string{}
+		for _, m := range sent[n : n+4] {
+			assert.Equal(t, "sendMessage", m.Method)
+			texts = append(texts, m.Text)
+		}
+		assert.Equal(t, "mobile/android #1, last 500 lines:\n"+strings.TrimSuffix(console, "\n"), strings.Join(texts, "\n"))
+		assert.Equal(t, "sendDocument", sent[n+4].Method)
+	}
+}
+
+func TestParams(t *testing.T) {
+	defs := []jenkins.ParameterDefinition{
+		{Name: "BRANCH", Type: jenkins.ParamString, DefaultParameterValue: &jenkins.ParameterValue{Value: "master"}},
+		{Name: "ENV", Type: jenkins.ParamChoice, Choices: []string{"staging", "production"}},
+		{Name: "DEBUG", Type: jenkins.ParamBoolean, DefaultParameterValue: &jenkins.ParameterValue{Value: false}},
+		{Name: "TOKEN", Type: jenkins.ParamPassword},
+		{Name: "ARTIFACT", Type: "RunParameterDefinition"},
+	}
+
+	params, err := bot.Params("deploy", defs, []string{"DEBUG=yes", "TOKEN=s3cret"})
+	assert.Nil(t, err)
+	assert.Equal(t, []bot.Param{
+		{Definition: defs[0], Value: "master", Default: true},
+		{Definition: defs[1], Value: "staging", Default: true},
+		{Definition: defs[2], Value: "true"},
+		{Definition: defs[3], Value: "s3cret"},
+	}, params)
+
+	params, err = bot.Params("deploy", defs, []string{"BRANCH=feature=x"})
+	assert.Nil(t, err)
+	assert.Equal(t, "feature=x", params[0].Value)
+	assert.Len(t, params, 3)
+
+	for arg, message := range map[string]string{
+		"BRANCH":        "Expected name=value instead of BRANCH",
+		"=master":       "Expected name=value instead of =master",
+		"ENV=qa":        "ENV must be one of staging, production",
+		"DEBUG=maybe":   "DEBUG must be true or false",
+		"ARTIFACT=#3":   "ARTIFACT is a Run, which cannot be given here",
+		"branch=hotfix": "deploy has no parameter branch, it takes BRANCH, ENV, DEBUG, TOKEN, ARTIFACT",
+	} {
+		_, err := bot.Params("deploy", defs, []string{arg})
+		assert.Equal(t, bot.UserError(message), err, arg)
+	}
+	_, err = bot.Params("deploy", defs, []string{"ENV=qa", "ENV=staging"})
+	assert.Equal(t, bot.UserError("ENV is given twice"), err)
+	_, err = bot.Params("lint", nil, []string{"FOO=bar"})
+	assert.Equal(t, bot.UserError("lint takes no parameters"), err)
+}
+
+func TestBuildConfirmation(t *testing.T) {
+	j, tg, watcher, stop := start(t)
+	defer stop()
+	j.AddJob("deploy")
+	j.SetParameters("deploy",
+		jenkins.ParameterDefinition{Name: "BRANCH", Type: jenkins.ParamString, DefaultParameterValue: &jenkins.ParameterValue{Value: "master"}},
+		jenkins.ParameterDefinition{Name: "ENV", Type: jenkins.ParamChoice, Choices: []string{"staging", "production"}},
+		jenkins.ParameterDefinition{Name: "TOKEN", Type: jenkins.ParamPassword},
+	)
+
+	assert.Equal(t, "ENV must be one of staging, production", ask(t, tg, "/build deploy ENV=qa"))
+
+	// cancelled, and the buttons do nothing afterwards
+	m := send(t, tg, userID, "/build deploy")
+	assert.Equal(t, "Build deploy with:\n  BRANCH=master (default)\n  ENV=staging (default)", m.Text)
+	sent := press(t, tg, userID, m, m.Buttons()[1], 2)
+	assert.Equal(t, "answerCallbackQuery", sent[0].Method)
+	assert.Equal(t, "Cancelled build of deploy", sent[1].Text)
+	assert.Equal(t, m.MessageID, sent[1].MessageID)
+	sent = press(t, tg, userID, m, m.Buttons()[0], 1)
+	assert.Equal(t, "This build request expired, send /build again", sent[0].Text)
+	assert.Nil(t, j.LastBuild("deploy"))
+
+	// only the requester can confirm
+	ask(t, tg, "/grant user 8 developer")
+	m = send(t, tg, userID, "/build deploy ENV=production TOKEN=s3cret")
+	assert.Equal(t, "Build deploy with:\n  BRANCH=master (default)\n  ENV=production\n  TOKEN=****", m.Text)
+	sent = press(t, tg, 8, m, m.Buttons()[0], 1)
+	assert.Equal(t, "Only the user who sent /build can confirm it", sent[0].Text)
+
+	// queued, then edited by the watcher once started
+	j.Hold()
+	sent = press(t, tg, userID, m, m.Buttons()[0], 2)
+	assert.Equal(t, "editMessageText", sent[0].Method)
+	assert.Equal(t, m.MessageID, sent[0].MessageID)
+	assert.Equal(t, "Queued: deploy with:\n  BRANCH=master (default)\n  ENV=production\n  TOKEN=****\nWaiting for next available executor", sent[0].Text)
+	assert.Equal(t, "answerCallbackQuery", sent[1].Method)
+
+	j.Release()
+	watcher.Check(context.Background())
+	sent = tg.Sent()
+	last := sent[len(sent)-1]
+	assert.Equal(t, m.MessageID, last.MessageID)
+	assert.True(t, strings.HasPrefix(last.Text, "Building #1: deploy with:\n"), last.Text)
+	assert.Equal(t, map[string]string{"BRANCH": "master", "ENV": "production", "TOKEN": "s3cret"}, j.Params("deploy", 1))
+}
+
+func TestSubscriptionCommands(t *testing.T) {
+	_, tg, _, stop := start(t)
+	defer stop()
+
+	assert.Equal(t, "No subscriptions, see /subscribe", ask(t, tg, "/subscriptions"))
+	assert.Equal(t, "Usage: /subscribe <job pattern> [events]", ask(t, tg, "/subscribe"))
+	assert.Equal(t, "invalid job pattern deploy-[", ask(t, tg, "/subscribe deploy-["))
+	assert.Equal(t, "unknown event red, expected failure, fixed, unstable, all", ask(t, tg, "/subscribe deploy-* red"))
+
+	assert.Equal(t, "Subscribed to deploy-* (failure, fixed)", ask(t, tg, "/subscribe deploy-*"))
+	assert.Equal(t, "Subscribed to mobile/* (unstable, all)", ask(t, tg, "/subscribe mobile/* all,unstable"))
+	assert.Equal(t, "Subscribed to deploy-* (failure)", ask(t, tg, "/subscribe deploy-* failure"))
+
+	assert.Equal(t, "No quiet hours", ask(t, tg, "/quiet"))
+	assert.Equal(t, "invalid time 25:00, expected HH:MM", ask(t, tg, "/quiet 22:00-25:00"))
+	assert.Equal(t, "Quiet hours 22:00-07:00 UTC, alerts are sent once they end", ask(t, tg, "/quiet 22:00-07:00"))
+	assert.Equal(t, "Subscriptions:\ndeploy-*: failure\nmobile/*: unstable, all\nQuiet hours 22:00-07:00 UTC, alerts are sent once they end", ask(t, tg, "/subscriptions"))
+
+	assert.Equal(t, "Quiet hours removed", ask(t, tg, "/quiet off"))
+	assert.Equal(t, "Unsubscribed from deploy-*", ask(t, tg, "/unsubscribe deploy-*"))
+	assert.Equal(t, "This chat is not subscribed to deploy-*, see /subscriptions", ask(t, tg, "/unsubscribe deploy-*"))
+	assert.Equal(t, "Subscriptions:\nmobile/*: unstable, all", ask(t, tg, "/subscriptions"))
+}
+
+func TestAccess(t *testing.T) {
+	store := access.NewMemoryStore()
+	j, tg, _, stop := startWith(t, store, subscription.NewMemoryStore())
+	defer stop()
+	j.AddJob("deploy-production")
+	const other = 8
+	as := func(text string) string {
+		return send(t, tg, other, text).Text
+	}
+
+	assert.Equal(t, "You are user 8, with no role in chat 100", as("/whoami"))
+	assert.Equal(t, "You need the viewer role to /jobs, ask an admin to grant it to user 8", as("/jobs"))
+	assert.Equal(t, "You need the admin role to /grant, ask an admin to grant it to user 8", as("/grant user 8 admin"))
+
+	// a chat role applies to its members
+	assert.Equal(t, "Granted viewer to chat 100", ask(t, tg, "/grant chat viewer"))
+	assert.Equal(t, "deploy-production: not built", as("/jobs"))
+	assert.Equal(t, "You need the developer role to /build, ask an admin to grant it to user 8", as("/build deploy-production"))
+
+	assert.Equal(t, "Granted developer to user 8", ask(t, tg, "/grant user 8 developer"))
+	assert.Equal(t, "Jobs matching deploy-* can only be built and aborted by the lead role", ask(t, tg, "/restrict deploy-* lead"))
+	assert.Equal(t, "deploy-production can only be built and aborted by the lead role", as("/build deploy-production"))
+
+	assert.Equal(t, "Granted lead to user 8", ask(t, tg, "/grant user 8 lead"))
+	assert.Equal(t, "deploy-production takes no parameters", as("/build deploy-production VERSION=2"))
+	m := send(t, tg, other, "/build deploy-production")
+	assert.Len(t, m.Buttons(), 2)
+	assert.Equal(t, "Admins: user 7\nRoles:\n  user 8: lead\n  chat 100: viewer\nJob rules:\n  deploy-*: lead", ask(t, tg, "/roles"))
+
+	// the role is checked again when the build is confirmed
+	assert.Equal(t, "Revoked the role of user 8", ask(t, tg, "/revoke user 8"))
+	sent := press(t, tg, other, m, m.Buttons()[0], 1)
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "answerCallbackQuery", sent[0].Method)
+		assert.Equal(t, "You need the developer role to press this button, ask an admin to grant it to user 8", sent[0].Text)
+	}
+	assert.Nil(t, j.LastBuild("deploy-production"))
+
+	assert.Equal(t, "Unknown role boss, expected viewer, developer, lead, admin", ask(t, tg, "/grant user 8 boss"))
+	assert.Equal(t, "Building jobs always needs the developer role or above", ask(t, tg, "/restrict deploy-* viewer"))
+	assert.Equal(t, "Jobs matching deploy-* can be built by developers", ask(t, tg, "/restrict deploy-* off"))
+	assert.Equal(t, "User 8 has no role, see /roles", ask(t, tg, "/revoke user 8"))
+
+	// privileged commands are audited, viewer ones are not
+	entries := store.Entries()
+	results := []string{}
+	for _, e := range entries {
+		if e.UserID == other {
+			results = append(results, e.Command+" "+e.Result)
+		}
+	}
+	assert.Equal(t, []string{"grant denied", "build denied", "build denied", "build failed", "build ok", "build denied"}, results)
+	for _, e := range entries {
+		if e.UserID == other && e.Result == access.ResultFailed {
+			assert.Equal(t, "/build deploy-production VERSION=***", e.Text)
+		}
+	}
+	assert.Equal(t, access.Entry{UserID: userID, ChatID: chatID, Command: "grant", Text: "/grant chat viewer", Result: access.ResultOK}, entries[1])
+}
+
+func TestApproval(t *testing.T) {
+	j, tg, _, stop := start(t)
+	defer stop()
+	j.AddJob("deploy-production")
+	j.SetParameters("deploy-production", jenkins.ParameterDefinition{Name: "TOKEN", Type: jenkins.ParamPassword})
+	const requester = 8
+	// request sends /build as requester and confirms it, returning the approval prompt
+	request := func() telegramtest.Sent {
+		m := send(t, tg, requester, "/build deploy-production")
+		assert.Equal(t, "Build deploy-production\nIt needs 2 approvals from other users", m.Text)
+		sent := press(t, tg, requester, m, m.Buttons()[0], 2)
+		if !assert.Len(t, sent, 2) {
+			return telegramtest.Sent{}
+		}
+		return sent[1]
+	}
+
+	ask(t, tg, "/grant chat developer")
+	assert.Equal(t, "Builds of jobs matching deploy-* need 2 approvals from other users within 30m0s", ask(t, tg, "/protect deploy-* 2 30m"))
+	assert.Equal(t, "deploy-production needs approval, its password parameters cannot be given in chat", send(t, tg, requester, "/build deploy-production TOKEN=s3cret").Text)
+
+	// without the message to show the request in, the build is not queued either
+	old := send(t, tg, userID, "/build deploy-production")
+	sent := press(t, tg, userID, telegramtest.Sent{}, old.Buttons()[0], 1)
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "deploy-production needs approval, send /build again", sent[0].Text)
+	}
+	assert.Nil(t, j.LastBuild("deploy-production"))
+
+	m := request()
+	assert.True(t, strings.HasPrefix(m.Text, "Approval needed to build deploy-production\nRequested by @user8, 0 of 2 approvals, expiring in 30m"), m.Text)
+	assert.Equal(t, []string{"approve:1:yes", "approve:1:no"}, m.Buttons())
+
+	sent = press(t, tg, requester, m, "approve:1:yes", 1)
+	assert.Equal(t, "You cannot approve your own build request", sent[0].Text)
+	sent = press(t, tg, 9, m, "approve:1:yes", 2)
+	if assert.Len(t, sent, 2) {
+		assert.Contains(t, sent[1].Text, "1 of 2 approvals")
+		assert.True(t, strings.HasSuffix(sent[1].Text, "\nApproved by @user9"), sent[1].Text)
+		assert.Len(t, sent[1].Buttons(), 2)
+	}
+	sent = press(t, tg, 9, m, "approve:1:no", 1)
+	assert.Equal(t, "You already decided on this build request", sent[0].Text)
+	assert.Nil(t, j.LastBuild("deploy-production"))
+
+	// the second approval queues the build, shown in the prompt
+	sent = press(t, tg, 10, m, "approve:1:yes", 2)
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, "editMessageText", sent[0].Method)
+		assert.True(t, strings.HasPrefix(sent[0].Text, "Building #1: deploy-production\n"), sent[0].Text)
+	}
+	assert.NotNil(t, j.LastBuild("deploy-production"))
+	sent = press(t, tg, 11, m, "approve:1:yes", 1)
+	assert.Equal(t, "This build request is already approved", sent[0].Text)
+
+	m = request()
+	sent = press(t, tg, 9, m, "approve:2:no", 2)
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, "Rejected by @user9: deploy-production", sent[1].Text)
+	}
+	m = request()
+	sent = press(t, tg, requester, m, "approve:3:no", 2)
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, "Withdrawn by @user8: deploy-production", sent[1].Text)
+	}
+
+	history := strings.Split(ask(t, tg, "/approvals deploy-production"), "\n")
+	if assert.Len(t, history, 6) {
+		assert.Equal(t, "Protected jobs:", history[0])
+		assert.Equal(t, "  deploy-*: 2 approvals within 30m0s", history[1])
+		assert.Equal(t, "Build requests:", history[2])
+	}
+	text := ask(t, tg, "/approvals")
+	assert.Regexp(t, `  #3 deploy-production by @user8 on .*: cancelled\n  #2 deploy-production by @user8 on .*: rejected by @user9\n  #1 deploy-production by @user8 on .*: approved by @user9, @user10$`, text)
+
+	assert.Equal(t, "Approvers must be between 1 and 5", ask(t, tg, "/protect deploy-* 0"))
+	assert.Equal(t, "Builds of jobs matching deploy-* do not need approval anymore", ask(t, tg, "/protect deploy-* off"))
+	assert.Equal(t, "Build deploy-production", send(t, tg, requester, "/build deploy-production").Text)
+}
+
+func TestInputs(t *testing.T) {
+	subs := subscription.NewMemoryStore()
+	j, tg, _, stop := startWith(t, access.NewMemoryStore(), subs)
+	defer stop()
+	ctx := context.Background()
+	client := jenkins.New(j.URL, "bot", "token")
+	j.AddJob("deploy")
+	client.Trigger(ctx, "deploy", nil)
+	client.Trigger(ctx, "deploy", nil)
+	target := jenkins.ParameterDefinition{Name: "TARGET", Type: jenkins.ParamChoice, Choices: []string{"canary", "all"}}
+	j.SetInput("deploy", 1, jenkins.InputAction{ID: "Release", Message: "Release?", ProceedText: "Release", Inputs: []jenkins.ParameterDefinition{target}})
+	j.SetInput("deploy", 2, jenkins.InputAction{ID: "Release", Message: "Release?"})
+
+	ask(t, tg, "/subscribe deploy")
+	subscription.NewPoller(subs, client, tg.Bot()).Check(ctx)
+	sent := tg.Sent()
+	if !assert.Len(t, sent, 3) {
+		return
+	}
+	first, second := sent[1], sent[2]
+	assert.Equal(t, "deploy #1 is waiting for input: Release?\nProceeding submits TARGET=canary\n"+j.URL+"/job/deploy/1/input/", first.Text)
+	assert.Equal(t, []string{"input:1:proceed", "input:1:abort"}, first.Buttons())
+
+	sent = press(t, tg, 8, first, "input:1:proceed", 1)
+	assert.Equal(t, "You need the developer role to press this button, ask an admin to grant it to user 8", sent[0].Text)
+	ask(t, tg, "/grant chat developer")
+	sent = press(t, tg, 8, first, "input:1:proceed", 2)
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, "editMessageText", sent[1].Method)
+		assert.Equal(t, first.Text+"\nProceeded by @user8", sent[1].Text)
+	}
+	answer, ok := j.Answer("deploy", 1, "Release")
+	assert.True(t, ok)
+	assert.Equal(t, []jenkins.ParameterValue{{Name: "TARGET", Value: "canary"}}, answer.Values)
+	sent = press(t, tg, 9, first, "input:1:abort", 1)
+	assert.Equal(t, "This input is already proceeded", sent[0].Text)
+
+	// inputs of jobs needing approval cannot be proceeded by a single user
+	ask(t, tg, "/protect deploy 1")
+	sent = press(t, tg, 9, second, "input:2:proceed", 1)
+	assert.Equal(t, "deploy needs approval, proceed on Jenkins", sent[0].Text)
+	_, ok = j.Answer("deploy", 2, "Release")
+	assert.False(t, ok)
+
+	sent = press(t, tg, 9, second, "input:2:abort", 2)
+	if assert.Len(t, sent, 2) {
+		assert.True(t, strings.HasSuffix(sent[1].Text, "\nAborted by @user9"), sent[1].Text)
+	}
+	assert.Equal(t, jenkins.ResultAborted, j.LastBuild("deploy").Result)
+}
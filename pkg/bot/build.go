@@ -0,0 +1,245 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/approval"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+// Param is the value of a job parameter
+type Param struct {
+	Definition jenkins.ParameterDefinition
+	Value      string
+	// Default is true when the value was not given
+	Default bool
+}
+
+// pendingBuild is a build waiting for confirmation by the user who requested it
+type pendingBuild struct {
+	job     string
+	params  []Param
+	userID  int64
+	expires time.Time
+}
+
+// Params validates name=value arguments against parameter definitions of a job, filling defaults of missing ones.
+// Parameters of types other than string, text, boolean, choice and password are left to Jenkins defaults.
+func Params(job string, defs []jenkins.ParameterDefinition, args []string) ([]Param, error) {
+	given := map[string]string{}
+	for _, arg := range args {
+		eq := strings.Index(arg, "=")
+		if eq <= 0 {
+			return nil, Errorf("Expected name=value instead of %s", arg)
+		}
+		name := arg[:eq]
+		if _, ok := given[name]; ok {
+			return nil, Errorf("%s is given twice", name)
+		}
+		given[name] = arg[eq+1:]
+	}
+
+	names := make([]string, len(defs))
+	params := []Param{}
+	for k, d := range defs {
+		names[k] = d.Name
+		value, ok := given[d.Name]
+		if !ok {
+			// Jenkins keeps password defaults secret, it fills them when omitted
+			if d.Type == jenkins.ParamPassword {
+				continue
+			}
+			if _, err := d.Parse(d.Default()); err == nil {
+				params = append(params, Param{Definition: d, Value: d.Default(), Default: true})
+			}
+			continue
+		}
+		delete(given, d.Name)
+		value, err := d.Parse(value)
+		if err != nil {
+			return nil, UserError(err.Error())
+		}
+		params = append(params, Param{Definition: d, Value: value})
+	}
+
+	for name := range given {
+		if len(defs) == 0 {
+			return nil, Errorf("%s takes no parameters", job)
+		}
+		return nil, Errorf("%s has no parameter %s, it takes %s", job, name, strings.Join(names, ", "))
+	}
+	return params, nil
+}
+
+// Build asks to confirm a build of a job with its parameters
+func (c *Commands) Build(ctx context.Context, req *Request) (string, error) {
+	if len(req.Args) < 1 {
+		return "", Errorf("Usage: /build <job> [name=value ...]")
+	}
+	j, err := c.job(ctx, req.Args[0])
+	if err != nil {
+		return "", err
+	}
+	if !j.Buildable {
+		return "", Errorf("%s is disabled", j.FullName)
+	}
+	if err := c.Access.RequireJob(ctx, sender(req.Message), req.Message.Chat.ID, j.FullName); err != nil {
+		return "", err
+	}
+	defs, err := c.Jenkins.Parameters(ctx, j.FullName)
+	if err != nil {
+		return "", err
+	}
+	params, err := Params(j.FullName, defs, req.Args[1:])
+	if err != nil {
+		return "", err
+	}
+	rule, err := approval.Find(ctx, c.Approvals, j.FullName)
+	if err != nil {
+		return "", err
+	}
+	if rule != nil {
+		// they would be kept with the request until approved
+		for _, param := range params {
+			if param.Definition.Type == jenkins.ParamPassword {
+				return "", Errorf("%s needs approval, its password parameters cannot be given in chat", j.FullName)
+			}
+		}
+	}
+
+	p := &pendingBuild{job: j.FullName, params: params, userID: sender(req.Message), expires: time.Now().Add(c.ConfirmTTL)}
+	token, err := c.add(p)
+	if err != nil {
+		return "", err
+	}
+	req.Buttons = [][]telegram.InlineKeyboardButton{{
+		{Text: "Build", CallbackData: CallbackData("build", token+":yes")},
+		{Text: "Cancel", CallbackData: CallbackData("build", token+":no")},
+	}}
+	text := "Build " + p.describe()
+	if rule != nil {
+		text += fmt.Sprintf("\nIt needs %s from other users", plural(rule.Approvers, "approval"))
+	}
+	return text, nil
+}
+
+// Confirm queues or cancels a build when its confirmation buttons are pressed
+func (c *Commands) Confirm(ctx context.Context, cb *Callback) (string, error) {
+	parts := strings.SplitN(cb.Data, ":", 2)
+	if len(parts) != 2 {
+		return "", Errorf("This button is no longer supported")
+	}
+	p, err := c.take(parts[0], cb.Query.From.ID)
+	if err != nil {
+		return "", err
+	}
+	if parts[1] != "yes" {
+		return "Cancelled build of " + p.job, nil
+	}
+	// the role may have been revoked since /build
+	m := cb.Query.Message
+	var chatID int64
+	if m != nil {
+		chatID = m.Chat.ID
+	}
+	if err := c.Access.RequireJob(ctx, cb.Query.From.ID, chatID, p.job); err != nil {
+		return "", err
+	}
+
+	params := map[string]string{}
+	for _, param := range p.params {
+		params[param.Definition.Name] = param.Value
+	}
+	rule, err := approval.Find(ctx, c.Approvals, p.job)
+	if err != nil {
+		return "", err
+	}
+	if rule != nil {
+		// the request is shown by editing the message, so builds needing approval are never queued without it
+		if m == nil {
+			return "", Errorf("%s needs approval, send /build again", p.job)
+		}
+		return c.requestApproval(ctx, cb, p, params, rule)
+	}
+	return c.start(ctx, m, p.job, p.describe(), params)
+}
+
+// start queues a build, its progress being shown in m
+func (c *Commands) start(ctx context.Context, m *telegram.Message, job, title string, params map[string]string) (string, error) {
+	id, err := c.Jenkins.Trigger(ctx, job, params)
+	if err != nil {
+		return "", err
+	}
+	if m == nil {
+		// too old to be edited, which is unlikely within ConfirmTTL
+		return "", nil
+	}
+	if err := c.Watcher.Start(ctx, m.Chat.ID, m.MessageID, job, title, id); err != nil {
+		log.ErrLog(ctx, err, "bot", "Failed to watch build")
+		return fmt.Sprintf("Queued %s as item %d, its progress cannot be shown", title, id), nil
+	}
+	return "", nil
+}
+
+// add stores a build waiting for confirmation, returning its token
+func (c *Commands) add(p *pendingBuild) (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for t, old := range c.pending {
+		if now.After(old.expires) {
+			delete(c.pending, t)
+		}
+	}
+	c.pending[token] = p
+	return token, nil
+}
+
+// take removes a build waiting for confirmation, which only its requester can do
+func (c *Commands) take(token string, userID int64) (*pendingBuild, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.pending[token]
+	if p == nil || time.Now().After(p.expires) {
+		delete(c.pending, token)
+		return nil, Errorf("This build request expired, send /build again")
+	}
+	if p.userID != userID {
+		return nil, Errorf("Only the user who sent /build can confirm it")
+	}
+	delete(c.pending, token)
+	return p, nil
+}
+
+// describe returns the job name followed by its parameters, one per line
+func (p *pendingBuild) describe() string {
+	if len(p.params) == 0 {
+		return p.job
+	}
+	lines := []string{p.job + " with:"}
+	for _, param := range p.params {
+		value := param.Value
+		if param.Definition.Type == jenkins.ParamPassword {
+			value = "****"
+		}
+		line := fmt.Sprintf("  %s=%s", param.Definition.Name, value)
+		if param.Default {
+			line += " (default)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/access"
+	"github.com/wiskarindra/jenkins_jr/pkg/approval"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins_jr"
+	"github.com/wiskarindra/jenkins_jr/pkg/watch"
+)
+
+// Commands are the Jenkins commands of the bot
+type Commands struct {
+	Jenkins jenkins_jr.JenkinsClient
+	// Watcher shows the progress of confirmed builds
+	Watcher *watch.Watcher
+	// ConfirmTTL is how long a build request waits for confirmation
+	ConfirmTTL time.Duration
+	// Access restricts commands to roles
+	Access *Access
+	// Approvals keeps builds of protected jobs until other users approve them
+	Approvals approval.Store
+
+	mu      sync.Mutex
+	pending map[string]*pendingBuild
+}
+
+// NewCommands returns Commands calling client, confirmed builds being followed by watcher
+func NewCommands(client jenkins_jr.JenkinsClient, watcher *watch.Watcher, acl *Access, approvals approval.Store) *Commands {
+	return &Commands{Jenkins: client, Watcher: watcher, ConfirmTTL: 5 * time.Minute, Access: acl, Approvals: approvals, pending: map[string]*pendingBuild{}}
+}
+
+// Register adds commands to r
+func (c *Commands) Register(r *Router) {
+	r.Handle("jobs", "/jobs", "List jobs with the status of their last build", c.Access.Require(access.RoleViewer, c.Jobs))
+	r.Handle("build", "/build <job> [name=value ...]", "Start a build, asking for confirmation", c.Access.Require(access.RoleDeveloper, c.Build))
+	r.HandleCallback("build", c.Access.RequireCallback(access.RoleDeveloper, c.Confirm))
+	r.Handle("status", "/status <job> [build]", "Show a build, the last one by default", c.Access.Require(access.RoleViewer, c.Status))
+	r.Handle("log", "/log <job> [build] [-n lines] [-file] [-grep regex]", "Show the end of a build console output or its lines matching a regex, -file attaching the whole output", c.Access.Require(access.RoleViewer, c.Log))
+	r.Handle("abort", "/abort <job> [build]", "Stop a running build", c.Access.Require(access.RoleDeveloper, c.Abort))
+	r.HandleCallback("approve", c.Access.RequireCallback(access.RoleDeveloper, c.Approve))
+	r.Handle("approvals", "/approvals [job]", "Show protected jobs and the latest build requests waiting for or decided by approvers", c.Access.Require(access.RoleViewer, c.History))
+	r.Handle("protect", "/protect <job pattern> <approvers> [window] | /protect <job pattern> off", "Make builds of jobs matching a pattern wait for approvers other than the requester, within a window such as 30m (default 1h)", c.Access.Require(access.RoleAdmin, c.Protect))
+}
+
+// Jobs lists jobs
+func (c *Commands) Jobs(ctx context.Context, _ *Request) (string, error) {
+	jobs, err := c.Jenkins.Jobs(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(jobs) == 0 {
+		return "No jobs", nil
+	}
+	lines := make([]string, len(jobs))
+	for k, j := range jobs {
+		lines[k] = fmt.Sprintf("%s: %s", j.FullName, j.Status())
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Status describes a build
+func (c *Commands) Status(ctx context.Context, req *Request) (string, error) {
+	job, number, err := c.buildArgs(ctx, req, "/status <job> [build]")
+	if err != nil {
+		return "", err
+	}
+	b, err := c.build(ctx, job, number)
+	if err != nil {
+		return "", err
+	}
+	return Describe(job, b), nil
+}
+
+// Describe summarizes a build in one line followed by its URL
+func Describe(job string, b *jenkins.Build) string {
+	if b.Building {
+		return fmt.Sprintf("%s #%d: building for %s (estimated %s)\n%s", job, b.Number, round(b.Elapsed()), round(time.Duration(b.EstimatedDuration)*time.Millisecond), b.URL)
+	}
+	return fmt.Sprintf("%s #%d: %s in %s\n%s", job, b.Number, b.Result, round(b.Elapsed()), b.URL)
+}
+
+// Abort stops a running build
+func (c *Commands) Abort(ctx context.Context, req *Request) (string, error) {
+	job, number, err := c.buildArgs(ctx, req, "/abort <job> [build]")
+	if err != nil {
+		return "", err
+	}
+	if err := c.Access.RequireJob(ctx, sender(req.Message), req.Message.Chat.ID, job); err != nil {
+		return "", err
+	}
+	b, err := c.build(ctx, job, number)
+	if err != nil {
+		return "", err
+	}
+	if !b.Building {
+		return "", Errorf("%s #%d is not running, it ended with %s", job, b.Number, b.Result)
+	}
+	if err := c.Jenkins.Abort(ctx, job, b.Number); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Aborting %s #%d", job, b.Number), nil
+}
+
+// buildArgs parses <job> [build] arguments, build being 0 when omitted
+func (c *Commands) buildArgs(ctx context.Context, req *Request, usage string) (string, int64, error) {
+	if len(req.Args) < 1 || len(req.Args) > 2 {
+		return "", 0, Errorf("Usage: %s", usage)
+	}
+	var number int64
+	if len(req.Args) == 2 {
+		var err error
+		if number, err = parseNumber(req.Args[1]); err != nil {
+			return "", 0, err
+		}
+	}
+	j, err := c.job(ctx, req.Args[0])
+	if err != nil {
+		return "", 0, err
+	}
+	return j.FullName, number, nil
+}
+
+// parseNumber parses a build number such as 12 or #12
+func parseNumber(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, Errorf("Invalid build number %s", s)
+	}
+	return n, nil
+}
+
+func (c *Commands) job(ctx context.Context, name string) (*jenkins.Job, error) {
+	j, err := c.Jenkins.Job(ctx, name)
+	if err == jenkins.ErrNotFound {
+		return nil, Errorf("Unknown job %s, see /jobs", name)
+	}
+	return j, err
+}
+
+func (c *Commands) build(ctx context.Context, job string, number int64) (*jenkins.Build, error) {
+	b, err := c.Jenkins.Build(ctx, job, number)
+	if err == jenkins.ErrNotFound {
+		if number == 0 {
+			return nil, Errorf("%s was never built", job)
+		}
+		return nil, Errorf("%s has no build #%d", job, number)
+	}
+	return b, err
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Second)
+}
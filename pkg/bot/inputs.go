@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/access"
+	"github.com/wiskarindra/jenkins_jr/pkg/approval"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins_jr"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+)
+
+// Inputs answers input steps of builds from the buttons of prompts sent by the poller
+type Inputs struct {
+	Store   subscription.Store
+	Jenkins jenkins_jr.JenkinsClient
+	// Access restricts answers to those allowed to build the job
+	Access *Access
+	// Approvals protects jobs whose input steps cannot be proceeded from chat, as a single user would
+	Approvals approval.Store
+}
+
+// Register adds the callback of prompts to r
+func (in *Inputs) Register(r *Router) {
+	r.HandleCallback(subscription.InputCallback, in.Access.RequireCallback(access.RoleDeveloper, in.Answer))
+}
+
+// Answer proceeds an input step with its default values, or aborts it and so its build.
+// Input steps of jobs needing approval can only be aborted.
+func (in *Inputs) Answer(ctx context.Context, cb *Callback) (string, error) {
+	parts := strings.SplitN(cb.Data, ":", 2)
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if len(parts) != 2 || err != nil || (parts[1] != "proceed" && parts[1] != "abort") {
+		return "", Errorf("This button is no longer supported")
+	}
+	p, err := in.Store.InputPrompt(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "", Errorf("This input no longer exists")
+	}
+	if p.Status != subscription.InputPending {
+		return "", Errorf("This input is already %s", p.Status)
+	}
+	user := &cb.Query.From
+	if err := in.Access.RequireJob(ctx, user.ID, p.ChatID, p.Job); err != nil {
+		return "", err
+	}
+
+	actions, err := in.Jenkins.PendingInputs(ctx, p.Job, p.BuildNumber)
+	if err != nil {
+		return "", err
+	}
+	a := subscription.FindInput(actions, p.InputID)
+	if a == nil {
+		return in.decide(ctx, p, subscription.InputGone, fmt.Sprintf("%s #%d is not waiting for this input anymore", p.Job, p.BuildNumber))
+	}
+
+	if parts[1] == "proceed" {
+		rule, err := approval.Find(ctx, in.Approvals, p.Job)
+		if err != nil {
+			return "", err
+		}
+		if rule != nil {
+			return "", Errorf("%s needs approval, proceed on Jenkins", p.Job)
+		}
+	}
+
+	p.DecidedBy = userName(user)
+	if parts[1] == "abort" {
+		if err := in.Jenkins.AbortInput(ctx, p.Job, p.BuildNumber, a.ID); err != nil {
+			return "", err
+		}
+		return in.decide(ctx, p, subscription.InputAborted, fmt.Sprintf("%s\nAborted by %s", p.Text, p.DecidedBy))
+	}
+	values, err := a.Defaults()
+	if err != nil {
+		return "", UserError(err.Error() + ", proceed on Jenkins")
+	}
+	if err := in.Jenkins.SubmitInput(ctx, p.Job, p.BuildNumber, a.ID, values); err != nil {
+		return "", err
+	}
+	return in.decide(ctx, p, subscription.InputProceeded, fmt.Sprintf("%s\nProceeded by %s", p.Text, p.DecidedBy))
+}
+
+// decide ends a pending prompt, replacing it with text
+func (in *Inputs) decide(ctx context.Context, p *subscription.InputPrompt, status, text string) (string, error) {
+	p.Status = status
+	decided, err := in.Store.DecideInput(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	if !decided {
+		return "", Errorf("This input was already answered")
+	}
+	return text, nil
+}
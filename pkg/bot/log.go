@@ -0,0 +1,222 @@
+package bot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+const (
+	// logLines is the number of console lines shown by /log by default
+	logLines = 30
+	// maxLogLines caps the lines asked with -n
+	maxLogLines = 1000
+	// maxLogMessages caps the messages of a /log reply, longer output being attached with -file
+	maxLogMessages = 4
+	// maxLogSize caps the console output fetched, protecting the bot from huge logs
+	maxLogSize = 32 << 20
+)
+
+const logUsage = "/log <job> [build] [-n lines] [-file] [-grep regex]"
+
+// logRequest are the arguments of /log
+type logRequest struct {
+	job    string
+	number int64
+	lines  int
+	grep   *regexp.Regexp
+	file   bool
+}
+
+// Log shows the last lines of a build console output, or its last lines matching a regex, and attaches it gzipped with -file
+func (c *Commands) Log(ctx context.Context, req *Request) (string, error) {
+	lr, err := c.logArgs(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	b, err := c.build(ctx, lr.job, lr.number)
+	if err != nil {
+		return "", err
+	}
+	text, truncated, err := c.fetchLog(ctx, lr.job, b.Number)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s #%d", lr.job, b.Number)
+
+	if lr.file {
+		d, err := logDocument(lr.job, b.Number, text)
+		if err != nil {
+			return "", err
+		}
+		d.Caption = name + " console output"
+		if truncated {
+			d.Caption += fmt.Sprintf(", truncated at %d MB", maxLogSize>>20)
+		}
+		req.Document = d
+		// -file alone only attaches the output
+		if lr.grep == nil && lr.lines == 0 {
+			return "", nil
+		}
+	}
+	if lr.lines == 0 {
+		lr.lines = logLines
+	}
+
+	var header, body string
+	if lr.grep == nil {
+		header = fmt.Sprintf("%s, last %d lines:\n", name, lr.lines)
+		body = strings.Join(last(Lines(text), lr.lines), "\n")
+	} else {
+		matches := Grep(text, lr.grep)
+		if len(matches) == 0 {
+			return fmt.Sprintf("%s has no lines matching %s", name, lr.grep), nil
+		}
+		header = fmt.Sprintf("%s, %d lines matching %s", name, len(matches), lr.grep)
+		if len(matches) > lr.lines {
+			header += fmt.Sprintf(", last %d", lr.lines)
+		}
+		header += ":\n"
+		body = strings.Join(last(matches, lr.lines), "\n")
+	}
+	// keep the end of the output when it does not fit, the part of the header excepted
+	return header + Tail(body, maxLogLines, maxLogMessages*telegram.MaxMessageLength-len(header)-maxLogMessages), nil
+}
+
+// logArgs parses <job> [build] [-n lines] [-file] [-grep regex], the regex being the rest of the message
+func (c *Commands) logArgs(ctx context.Context, req *Request) (*logRequest, error) {
+	if len(req.Args) < 1 {
+		return nil, Errorf("Usage: %s", logUsage)
+	}
+	lr := &logRequest{}
+	args := req.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		var err error
+		if lr.number, err = parseNumber(args[0]); err != nil {
+			return nil, err
+		}
+		args = args[1:]
+	}
+
+	for k := 0; k < len(args); k++ {
+		switch args[k] {
+		case "-n":
+			if k+1 == len(args) {
+				return nil, Errorf("Usage: %s", logUsage)
+			}
+			k++
+			n, err := strconv.Atoi(args[k])
+			if err != nil || n <= 0 || n > maxLogLines {
+				return nil, Errorf("Number of lines must be between 1 and %d", maxLogLines)
+			}
+			lr.lines = n
+		case "-file":
+			lr.file = true
+		case "-grep":
+			expr := strings.Join(args[k+1:], " ")
+			if expr == "" {
+				return nil, Errorf("Usage: %s", logUsage)
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, Errorf("Invalid regex %s", expr)
+			}
+			lr.grep = re
+			k = len(args)
+		default:
+			return nil, Errorf("Usage: %s", logUsage)
+		}
+	}
+
+	j, err := c.job(ctx, req.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	lr.job = j.FullName
+	return lr, nil
+}
+
+// fetchLog reads the console output of a build so far, truncated at maxLogSize
+func (c *Commands) fetchLog(ctx context.Context, job string, number int64) (string, bool, error) {
+	var text bytes.Buffer
+	var start int64
+	for {
+		chunk, err := c.Jenkins.ProgressiveText(ctx, job, number, start)
+		if err != nil {
+			return "", false, err
+		}
+		text.WriteString(chunk.Text)
+		if text.Len() >= maxLogSize {
+			return string(text.Bytes()[:maxLogSize]), true, nil
+		}
+		// a running build has more data, but not until it prints again
+		if !chunk.More || chunk.Text == "" {
+			return text.String(), false, nil
+		}
+		start = chunk.Next
+	}
+}
+
+// logDocument gzips a console output as a file named after the build
+func logDocument(job string, number int64, text string) (*Document, error) {
+	var data bytes.Buffer
+	gz := gzip.NewWriter(&data)
+	if _, err := gz.Write([]byte(text)); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s-%d.log.gz", strings.Replace(job, "/", "_", -1), number)
+	return &Document{Name: name, Data: data.Bytes()}, nil
+}
+
+// Lines splits a console output into lines, without the trailing empty one
+func Lines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// Grep returns lines of text matching re, prefixed with their line number
+func Grep(text string, re *regexp.Regexp) []string {
+	matches := []string{}
+	for k, line := range Lines(text) {
+		if re.MatchString(line) {
+			matches = append(matches, fmt.Sprintf("%d: %s", k+1, line))
+		}
+	}
+	return matches
+}
+
+func last(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// Tail returns the last lines of text, at most maxBytes long
+func Tail(text string, lines, maxBytes int) string {
+	tail := strings.Join(last(Lines(text), lines), "\n")
+	if len(tail) > maxBytes {
+		tail = tail[len(tail)-maxBytes:]
+		// do not start in the middle of a line, nor of a multi byte character
+		if nl := strings.Index(tail, "\n"); nl >= 0 {
+			tail = tail[nl+1:]
+		}
+		for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+			tail = tail[1:]
+		}
+	}
+	return tail
+}
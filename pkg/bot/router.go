@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+// Request is a command sent to the bot
+type Request struct {
+	Message *telegram.Message
+	// Command is the command name without slash nor bot mention, e.g. build for /build@jenkins_jr_bot
+	Command string
+	Args    []string
+	// Buttons are set by handlers to show inline keyboard rows below their reply
+	Buttons [][]telegram.InlineKeyboardButton
+	// Document is set by handlers to send a file after their reply
+	Document *Document
+}
+
+// Document is a file sent by the bot
+type Document struct {
+	Name    string
+	Data    []byte
+	Caption string
+}
+
+// Reply is the answer to a command
+type Reply struct {
+	Text     string
+	Buttons  [][]telegram.InlineKeyboardButton
+	Document *Document
+}
+
+// Callback is a press on a button sent by the bot
+type Callback struct {
+	Query *telegram.CallbackQuery
+	// Name routes the press to its handler, it is the button data up to the first colon
+	Name string
+	// Data is the rest of the button data
+	Data string
+	// Buttons are set by handlers to keep inline keyboard rows below the replaced message
+	Buttons [][]telegram.InlineKeyboardButton
+}
+
+// UserError is a failure explained to the user as is, e.g. wrong usage or unknown job
+type UserError string
+
+func (e UserError) Error() string {
+	return string(e)
+}
+
+// ForbiddenError is a command refused to its sender for lack of role, explained as is
+type ForbiddenError string
+
+func (e ForbiddenError) Error() string {
+	return string(e)
+}
+
+// Errorf returns a UserError
+func Errorf(format string, args ...interface{}) error {
+	return UserError(fmt.Sprintf(format, args...))
+}
+
+// HandlerFunc handles a command, returning the reply text
+type HandlerFunc func(ctx context.Context, req *Request) (string, error)
+
+// CallbackFunc handles a button press, returning the text replacing the message of the button
+type CallbackFunc func(ctx context.Context, cb *Callback) (string, error)
+
+type command struct {
+	name    string
+	usage   string
+	help    string
+	handler HandlerFunc
+}
+
+// Router dispatches commands and button presses to their handler
+type Router struct {
+	// Username is the username of the bot, commands mentioning another bot being ignored. Bot.Run sets it.
+	Username string
+
+	commands  map[string]*command
+	callbacks map[string]CallbackFunc
+}
+
+// NewRouter returns Router knowing only /help and /start
+func NewRouter() *Router {
+	r := &Router{commands: map[string]*command{}, callbacks: map[string]CallbackFunc{}}
+	r.Handle("help", "/help", "List commands", r.help)
+	r.Handle("start", "/start", "List commands", r.help)
+	return r
+}
+
+// Handle registers h for /name, usage and help being shown by /help
+func (r *Router) Handle(name, usage, help string, h HandlerFunc) {
+	r.commands[name] = &command{name: name, usage: usage, help: help, handler: h}
+}
+
+// HandleCallback registers h for presses on buttons whose data was built by CallbackData with name
+func (r *Router) HandleCallback(name string, h CallbackFunc) {
+	r.callbacks[name] = h
+}
+
+// CallbackData returns button data routed to the callback handler of name
+func CallbackData(name, data string) string {
+	return name + ":" + data
+}
+
+// Parse splits a command message into command name and arguments, ok is false for other messages.
+// Commands mentioning a bot other than username, e.g. /build@other_bot in a group, are not for this bot.
+func Parse(text, username string) (name string, args []string, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil, false
+	}
+	name = strings.TrimPrefix(fields[0], "/")
+	if at := strings.Index(name, "@"); at >= 0 {
+		if username != "" && !strings.EqualFold(name[at+1:], username) {
+			return "", nil, false
+		}
+		name = name[:at]
+	}
+	return strings.ToLower(name), fields[1:], name != ""
+}
+
+// Dispatch runs the handler of the command in m, replying nothing to other messages
+func (r *Router) Dispatch(ctx context.Context, m *telegram.Message) (Reply, error) {
+	name, args, ok := Parse(m.Text, r.Username)
+	if !ok {
+		return Reply{}, nil
+	}
+	c, found := r.commands[name]
+	if !found {
+		return Reply{Text: fmt.Sprintf("Unknown command /%s, see /help", name)}, nil
+	}
+	req := &Request{Message: m, Command: name, Args: args}
+	text, err := c.handler(ctx, req)
+	return Reply{Text: text, Buttons: req.Buttons, Document: req.Document}, err
+}
+
+// DispatchCallback runs the handler of a button press
+func (r *Router) DispatchCallback(ctx context.Context, q *telegram.CallbackQuery) (Reply, error) {
+	parts := strings.SplitN(q.Data, ":", 2)
+	h, found := r.callbacks[parts[0]]
+	if !found {
+		return Reply{}, Errorf("This button is no longer supported")
+	}
+	cb := &Callback{Query: q, Name: parts[0]}
+	if len(parts) == 2 {
+		cb.Data = parts[1]
+	}
+	text, err := h(ctx, cb)
+	return Reply{Text: text, Buttons: cb.Buttons}, err
+}
+
+func (r *Router) help(_ context.Context, _ *Request) (string, error) {
+	names := make([]string, 0, len(r.commands))
+	for name := range r.commands {
+		if name != "start" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	lines := []string{"Commands:"}
+	for _, name := range names {
+		c := r.commands[name]
+		lines = append(lines, fmt.Sprintf("%s - %s", c.usage, c.help))
+	}
+	return strings.Join(lines, "\n"), nil
+}
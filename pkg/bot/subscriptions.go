@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/access"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+)
+
+// Subscriptions are the commands managing build alerts of a chat
+type Subscriptions struct {
+	Store subscription.Store
+	// Location is the time zone of quiet hours
+	Location *time.Location
+	// Access restricts commands to roles
+	Access *Access
+}
+
+// Register adds commands to r
+func (s *Subscriptions) Register(r *Router) {
+	r.Handle("subscribe", "/subscribe <job pattern> [events]", "Alert this chat of builds of jobs matching a pattern such as deploy-*, events being failure, fixed, unstable or all (default failure,fixed)", s.Access.Require(access.RoleViewer, s.Subscribe))
+	r.Handle("unsubscribe", "/unsubscribe <job pattern>", "Stop alerts of a pattern", s.Access.Require(access.RoleViewer, s.Unsubscribe))
+	r.Handle("subscriptions", "/subscriptions", "List alerts of this chat", s.Access.Require(access.RoleViewer, s.List))
+	r.Handle("quiet", "/quiet [HH:MM-HH:MM|off]", "Show or set hours during which alerts wait", s.Access.Require(access.RoleViewer, s.Quiet))
+}
+
+// Subscribe subscribes the chat to a job pattern
+func (s *Subscriptions) Subscribe(ctx context.Context, req *Request) (string, error) {
+	if len(req.Args) < 1 || len(req.Args) > 2 {
+		return "", Errorf("Usage: /subscribe <job pattern> [events]")
+	}
+	pattern := req.Args[0]
+	if err := subscription.ValidPattern(pattern); err != nil {
+		return "", UserError(err.Error())
+	}
+	events := subscription.DefaultEvents
+	if len(req.Args) == 2 {
+		var err error
+		if events, err = subscription.ParseEvents(req.Args[1]); err != nil {
+			return "", UserError(err.Error())
+		}
+	}
+
+	sub := &subscription.Subscription{ChatID: req.Message.Chat.ID, Pattern: pattern, Events: strings.Join(events, ",")}
+	if req.Message.From != nil {
+		sub.CreatedBy = req.Message.From.ID
+	}
+	if err := s.Store.Subscribe(ctx, sub); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Subscribed to %s (%s)", pattern, strings.Join(events, ", ")), nil
+}
+
+// Unsubscribe removes a subscription of the chat
+func (s *Subscriptions) Unsubscribe(ctx context.Context, req *Request) (string, error) {
+	if len(req.Args) != 1 {
+		return "", Errorf("Usage: /unsubscribe <job pattern>")
+	}
+	removed, err := s.Store.Unsubscribe(ctx, req.Message.Chat.ID, req.Args[0])
+	if err != nil {
+		return "", err
+	}
+	if !removed {
+		return "", Errorf("This chat is not subscribed to %s, see /subscriptions", req.Args[0])
+	}
+	return "Unsubscribed from " + req.Args[0], nil
+}
+
+// List shows subscriptions and quiet hours of the chat
+func (s *Subscriptions) List(ctx context.Context, req *Request) (string, error) {
+	subs, err := s.Store.Subscriptions(ctx, req.Message.Chat.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(subs) == 0 {
+		return "No subscriptions, see /subscribe", nil
+	}
+	lines := []string{"Subscriptions:"}
+	for _, sub := range subs {
+		lines = append(lines, fmt.Sprintf("%s: %s", sub.Pattern, strings.Replace(sub.Events, ",", ", ", -1)))
+	}
+	q, err := s.Store.QuietHours(ctx, req.Message.Chat.ID)
+	if err != nil {
+		return "", err
+	}
+	if q != nil {
+		lines = append(lines, s.describe(q))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Quiet shows, sets or removes quiet hours of the chat
+func (s *Subscriptions) Quiet(ctx context.Context, req *Request) (string, error) {
+	chatID := req.Message.Chat.ID
+	switch {
+	case len(req.Args) == 0:
+		q, err := s.Store.QuietHours(ctx, chatID)
+		if err != nil {
+			return "", err
+		}
+		if q == nil {
+			return "No quiet hours", nil
+		}
+		return s.describe(q), nil
+	case len(req.Args) > 1:
+		return "", Errorf("Usage: /quiet [HH:MM-HH:MM|off]")
+	case strings.ToLower(req.Args[0]) == "off":
+		if err := s.Store.RemoveQuietHours(ctx, chatID); err != nil {
+			return "", err
+		}
+		return "Quiet hours removed", nil
+	}
+
+	starts, ends, err := subscription.ParseQuietHours(req.Args[0])
+	if err != nil {
+		return "", UserError(err.Error())
+	}
+	q := &subscription.QuietHours{ChatID: chatID, StartsAt: starts, EndsAt: ends}
+	if err := s.Store.SetQuietHours(ctx, q); err != nil {
+		return "", err
+	}
+	return s.describe(q), nil
+}
+
+func (s *Subscriptions) describe(q *subscription.QuietHours) string {
+	return fmt.Sprintf("Quiet hours %s %s, alerts are sent once they end", q, s.Location)
+}
@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultLoadTimeout bounds loads of Fetch by default
+const DefaultLoadTimeout = 10 * time.Second
+
+// Cache is a size bounded, in-process LRU cache with per entry TTL.
+// Entries carry tags so that every entry depending on a record can be invalidated at once.
+// Concurrent misses of the same key are coalesced into a single load.
+type Cache struct {
+	// LoadTimeout bounds every load of Fetch
+	LoadTimeout time.Duration
+
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	entries    map[string]*list.Element
+	tags       map[string]map[string]bool
+	calls      map[string]*call
+	// generation is bumped by every invalidation, so that loads started before it are not stored
+	generation uint64
+	hits       uint64
+	misses     uint64
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+	tags    []string
+}
+
+// call is a load in flight, done being closed once value and err are set
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Stats are cache counters
+type Stats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// New returns Cache holding at most maxEntries entries
+func New(maxEntries int) *Cache {
+	return &Cache{
+		LoadTimeout: DefaultLoadTimeout,
+		maxEntries:  maxEntries,
+		ll:          list.New(),
+		entries:     map[string]*list.Element{},
+		tags:        map[string]map[string]bool{},
+		calls:       map[string]*call{},
+	}
+}
+
+// Get returns the value cached under key, if not expired
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+func (c *Cache) get(key string) (interface{}, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !time.Now().Before(e.expires) {
+		c.remove(el)
+		c.misses++
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	c.hits++
+	return e.value, true
+}
+
+// Set caches value under key for ttl, tagged with tags
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl, tags)
+}
+
+func (c *Cache) set(key string, value interface{}, ttl time.Duration, tags []string) {
+	if c.maxEntries <= 0 || ttl <= 0 {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	e := &entry{key: key, value: value, expires: time.Now().Add(ttl), tags: tags}
+	c.entries[key] = c.ll.PushFront(e)
+	for _, t := range tags {
+		if c.tags[t] == nil {
+			c.tags[t] = map[string]bool{}
+		}
+		c.tags[t][key] = true
+	}
+
+	for c.ll.Len() > c.maxEntries {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*entry)
+	delete(c.entries, e.key)
+	for _, t := range e.tags {
+		delete(c.tags[t], e.key)
+		if len(c.tags[t]) == 0 {
+			delete(c.tags, t)
+		}
+	}
+}
+
+// Fetch returns the value cached under key, or loads it with load and caches it for ttl with the tags load returns.
+// Concurrent callers missing the same key wait for a single load and share its result.
+// The load runs with a context detached from ctx, bounded by LoadTimeout, so that a caller giving up
+// does not fail the load for everyone else; ctx only bounds how long the caller waits for it.
+// Errors are not cached, a panicking load being returned as an error.
+func (c *Cache) Fetch(ctx context.Context, key string, ttl time.Duration, load func(context.Context) (interface{}, []string, error)) (interface{}, error) {
+	c.mu.Lock()
+	if v, ok := c.get(key); ok {
+		c.mu.Unlock()
+		return v, nil
+	}
+	cl, ok := c.calls[key]
+	if !ok {
+		cl = &call{done: make(chan struct{})}
+		c.calls[key] = cl
+		go c.load(ctx, key, ttl, cl, c.generation, load)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, cl *call, generation uint64, load func(context.Context) (interface{}, []string, error)) {
+	var tags []string
+	defer func() {
+		if r := recover(); r != nil {
+			cl.value, cl.err = nil, fmt.Errorf("cache: load of %s panicked: %v", key, r)
+		}
+		c.mu.Lock()
+		delete(c.calls, key)
+		// a value loaded while an invalidation happened may already be stale
+		if cl.err == nil && generation == c.generation {
+			c.set(key, cl.value, ttl, tags)
+		}
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(detached{ctx}, c.LoadTimeout)
+	defer cancel()
+	cl.value, tags, cl.err = load(ctx)
+}
+
+// detached keeps values of a context, such as the request id used in logs, without its deadline and cancellation
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+// Invalidate removes every entry tagged with any of tags
+func (c *Cache) Invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, t := range tags {
+		for key := range c.tags[t] {
+			c.remove(c.entries[key])
+		}
+	}
+}
+
+// Purge removes every entry
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.ll.Init()
+	c.entries = map[string]*list.Element{}
+	c.tags = map[string]map[string]bool{}
+}
+
+// Stats returns cache counters
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{Entries: c.ll.Len(), Hits: c.hits, Misses: c.misses}
+}
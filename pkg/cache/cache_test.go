@@ -0,0 +1,170 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+)
+
+func TestLRUEviction(t *testing.T) {
+	c := cache.New(2)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+	c.Get("a")
+	c.Set("c", 3, time.Minute)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.Stats().Entries)
+}
+
+func TestExpiry(t *testing.T) {
+	c := cache.New(10)
+	c.Set("a", 1, 20*time.Millisecond)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	time.Sleep(30 * time.Millisecond)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, cache.Stats{Entries: 0, Hits: 1, Misses: 1}, c.Stats())
+}
+
+func TestInvalidate(t *testing.T) {
+	c := cache.New(10)
+	c.Set("posts", 1, time.Minute, "posts", "post:1", "post:2")
+	c.Set("posts?influencer_id=7", 2, time.Minute, "influencer:7", "post:2")
+	c.Set("posts?categories=3", 3, time.Minute, "category:3", "post:4")
+
+	c.Invalidate("post:2")
+	_, ok := c.Get("posts")
+	assert.False(t, ok)
+	_, ok = c.Get("posts?influencer_id=7")
+	assert.False(t, ok)
+	_, ok = c.Get("posts?categories=3")
+	assert.True(t, ok)
+
+	c.Purge()
+	assert.Equal(t, 0, c.Stats().Entries)
+}
+
+func TestFetchCoalescesMisses(t *testing.T) {
+	c := cache.New(10)
+	var loads int32
+	release := make(chan struct{})
+	load := func(context.Context) (interface{}, []string, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "page", []string{"posts"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 5)
+	for k := range results {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			results[k], _ = c.Fetch(context.Background(), "posts", time.Minute, load)
+		}(k)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+	for _, r := range results {
+		assert.Equal(t, "page", r)
+	}
+
+	v, err := c.Fetch(context.Background(), "posts", time.Minute, load)
+	assert.Nil(t, err)
+	assert.Equal(t, "page", v)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads), "fetched value is cached")
+
+	c.Invalidate("posts")
+	_, ok := c.Get("posts")
+	assert.False(t, ok, "fetched value is tagged")
+}
+
+func TestFetchErrorsAndStaleLoads(t *testing.T) {
+	c := cache.New(10)
+	_, err := c.Fetch(context.Background(), "a", time.Minute, func(context.Context) (interface{}, []string, error) {
+		return nil, nil, errors.New("db is down")
+	})
+	assert.NotNil(t, err)
+	_, ok := c.Get("a")
+	assert.False(t, ok, "errors are not cached")
+
+	v, err := c.Fetch(context.Background(), "b", time.Minute, func(context.Context) (interface{}, []string, error) {
+		c.Invalidate("post:1")
+		return 1, nil, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	_, ok = c.Get("b")
+	assert.False(t, ok, "values loaded during an invalidation are not cached")
+}
+
+func TestFetchDetachesLoads(t *testing.T) {
+	c := cache.New(10)
+	release := make(chan struct{})
+	load := func(ctx context.Context) (interface{}, []string, error) {
+		select {
+		case <-release:
+			return "page", nil, nil
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	// the first caller giving up neither fails the load nor keeps others from its result
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := c.Fetch(ctx, "posts", time.Minute, load)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	v, err := c.Fetch(context.Background(), "posts", time.Minute, load)
+	assert.Nil(t, err)
+	assert.Equal(t, "page", v)
+
+	// loads are bounded by LoadTimeout
+	c.LoadTimeout = 10 * time.Millisecond
+	_, err = c.Fetch(context.Background(), "slow", time.Minute, func(ctx context.Context) (interface{}, []string, error) {
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestFetchPanickingLoad(t *testing.T) {
+	c := cache.New(10)
+	_, err := c.Fetch(context.Background(), "posts", time.Minute, func(context.Context) (interface{}, []string, error) {
+		panic("nil map")
+	})
+	assert.NotNil(t, err)
+
+	v, err := c.Fetch(context.Background(), "posts", time.Minute, func(context.Context) (interface{}, []string, error) {
+		return "page", nil, nil
+	})
+	assert.Nil(t, err, "the key is released after a panic")
+	assert.Equal(t, "page", v)
+}
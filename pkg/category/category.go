@@ -0,0 +1,26 @@
+package category
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Category is a Bukalapak category usable as post filter
+type Category struct {
+	ID                    int64     `db:"id" json:"id"`
+	BukalapakCategoryID   int64     `db:"bukalapak_category_id" json:"bukalapak_category_id"`
+	BukalapakCategoryName string    `db:"bukalapak_category_name" json:"bukalapak_category_name"`
+	Count                 int       `db:"count" json:"count"`
+	Deleted               bool      `db:"deleted" json:"-"`
+	CreatedAt             time.Time `db:"created_at" json:"-"`
+	UpdatedAt             time.Time `db:"updated_at" json:"-"`
+}
+
+// All returns every category, including the ones removed from Bukalapak
+func All(ctx context.Context, db sqlx.QueryerContext) ([]Category, error) {
+	categories := []Category{}
+	err := sqlx.SelectContext(ctx, db, &categories, "SELECT id, bukalapak_category_id, COALESCE(bukalapak_category_name, '') AS bukalapak_category_name, count, deleted, created_at, updated_at FROM categories ORDER BY bukalapak_category_id")
+	return categories, err
+}
@@ -0,0 +1,93 @@
+package category
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// SourceCategory is a category as listed by a CategorySource
+type SourceCategory struct {
+	ID       int64            `json:"id"`
+	Name     string           `json:"name"`
+	Children []SourceCategory `json:"children,omitempty"`
+}
+
+// CategorySource lists the categories currently available on Bukalapak
+type CategorySource interface {
+	Categories(ctx context.Context) ([]SourceCategory, error)
+}
+
+type sourcePayload struct {
+	Data []SourceCategory `json:"data"`
+}
+
+// HTTPSource fetches categories from a Bukalapak JSON endpoint
+type HTTPSource struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPSource returns HTTPSource for given URL
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{URL: url, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Categories implements CategorySource
+func (s *HTTPSource) Categories(ctx context.Context) ([]SourceCategory, error) {
+	req, err := http.NewRequest("GET", s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("category source returned %d", resp.StatusCode)
+	}
+	return decode(resp.Body)
+}
+
+// FileSource reads categories from a local JSON file with the same payload as HTTPSource
+type FileSource struct {
+	Path string
+}
+
+// Categories implements CategorySource
+func (s *FileSource) Categories(_ context.Context) ([]SourceCategory, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decode(f)
+}
+
+func decode(r io.Reader) ([]SourceCategory, error) {
+	var payload sourcePayload
+	if err := json.NewDecoder(r).Decode(&payload); err != nil {
+		return nil, err
+	}
+	return flatten(payload.Data), nil
+}
+
+// flatten turns nested category tree into a flat list, parents first
+func flatten(categories []SourceCategory) []SourceCategory {
+	result := []SourceCategory{}
+	for _, c := range categories {
+		children := c.Children
+		c.Children = nil
+		result = append(result, c)
+		result = append(result, flatten(children)...)
+	}
+	return result
+}
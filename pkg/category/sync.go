@@ -0,0 +1,154 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+)
+
+// ErrEmptySource is returned when the source lists no category at all, to avoid removing every category
+var ErrEmptySource = errors.New("category source returned no category")
+
+// Change describes a single difference between stored and source categories
+type Change struct {
+	BukalapakCategoryID int64  `json:"bukalapak_category_id"`
+	OldName             string `json:"old_name,omitempty"`
+	NewName             string `json:"new_name,omitempty"`
+}
+
+// Report lists differences found by a sync
+type Report struct {
+	Added    []Change `json:"added"`
+	Renamed  []Change `json:"renamed"`
+	Removed  []Change `json:"removed"`
+	Restored []Change `json:"restored"`
+	DryRun   bool     `json:"dry_run"`
+}
+
+// Empty tells whether stored categories are already in sync with the source
+func (r *Report) Empty() bool {
+	return len(r.Added) == 0 && len(r.Renamed) == 0 && len(r.Removed) == 0 && len(r.Restored) == 0
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	if r.DryRun {
+		b.WriteString("[dry run] ")
+	}
+	fmt.Fprintf(&b, "%d added, %d renamed, %d removed, %d restored\n", len(r.Added), len(r.Renamed), len(r.Removed), len(r.Restored))
+	for _, c := range r.Added {
+		fmt.Fprintf(&b, "+ %d %s\n", c.BukalapakCategoryID, c.NewName)
+	}
+	for _, c := range r.Renamed {
+		fmt.Fprintf(&b, "~ %d %s -> %s\n", c.BukalapakCategoryID, c.OldName, c.NewName)
+	}
+	for _, c := range r.Removed {
+		fmt.Fprintf(&b, "- %d %s\n", c.BukalapakCategoryID, c.OldName)
+	}
+	for _, c := range r.Restored {
+		fmt.Fprintf(&b, "^ %d %s\n", c.BukalapakCategoryID, c.NewName)
+	}
+	return b.String()
+}
+
+// Diff compares stored categories against the ones listed by the source
+func Diff(stored []Category, source []SourceCategory) *Report {
+	report := &Report{Added: []Change{}, Renamed: []Change{}, Removed: []Change{}, Restored: []Change{}}
+
+	existing := map[int64]Category{}
+	for _, c := range stored {
+		existing[c.BukalapakCategoryID] = c
+	}
+
+	seen := map[int64]bool{}
+	for _, s := range source {
+		if s.ID == 0 || seen[s.ID] {
+			continue
+		}
+		seen[s.ID] = true
+
+		c, ok := existing[s.ID]
+		switch {
+		case !ok:
+			report.Added = append(report.Added, Change{BukalapakCategoryID: s.ID, NewName: s.Name})
+		case c.Deleted:
+			report.Restored = append(report.Restored, Change{BukalapakCategoryID: s.ID, OldName: c.BukalapakCategoryName, NewName: s.Name})
+		case c.BukalapakCategoryName != s.Name:
+			report.Renamed = append(report.Renamed, Change{BukalapakCategoryID: s.ID, OldName: c.BukalapakCategoryName, NewName: s.Name})
+		}
+	}
+
+	for _, c := range stored {
+		if !c.Deleted && !seen[c.BukalapakCategoryID] {
+			report.Removed = append(report.Removed, Change{BukalapakCategoryID: c.BukalapakCategoryID, OldName: c.BukalapakCategoryName})
+		}
+	}
+	return report
+}
+
+// Syncer keeps categories table in sync with a CategorySource
+type Syncer struct {
+	DB     *sqlx.DB
+	Source CategorySource
+	DryRun bool
+}
+
+// Sync fetches source categories and applies the differences, unless DryRun is set
+func (s *Syncer) Sync(ctx context.Context) (*Report, error) {
+	source, err := s.Source.Categories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(source) == 0 {
+		return nil, ErrEmptySource
+	}
+
+	stored, err := All(ctx, s.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	report := Diff(stored, source)
+	report.DryRun = s.DryRun
+	if s.DryRun || report.Empty() {
+		return report, nil
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := apply(ctx, tx, report); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return report, tx.Commit()
+}
+
+func apply(ctx context.Context, tx *sqlx.Tx, report *Report) error {
+	now := time.Now().Format(config.DatabaseDatetimeFormat)
+
+	upserts := append(append(append([]Change{}, report.Added...), report.Renamed...), report.Restored...)
+	for _, c := range upserts {
+		_, err := tx.ExecContext(ctx, `INSERT INTO categories (bukalapak_category_id, bukalapak_category_name, deleted, created_at, updated_at)
+			VALUES (?, ?, false, ?, ?)
+			ON DUPLICATE KEY UPDATE bukalapak_category_name = VALUES(bukalapak_category_name), deleted = false, updated_at = VALUES(updated_at)`,
+			c.BukalapakCategoryID, c.NewName, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, c := range report.Removed {
+		if _, err := tx.ExecContext(ctx, "UPDATE categories SET deleted = true, updated_at = ? WHERE bukalapak_category_id = ?", now, c.BukalapakCategoryID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package category_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/category"
+)
+
+func TestFileSourceFlattensChildren(t *testing.T) {
+	source := &category.FileSource{Path: "testdata/categories.json"}
+	categories, err := source.Categories(context.Background())
+
+	assert.Nil(t, err)
+	assert.Len(t, categories, 5)
+	assert.Equal(t, int64(2), categories[0].ID)
+	assert.Equal(t, "Dress", categories[1].Name)
+	assert.Nil(t, categories[1].Children)
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/categories.json")
+	}))
+	defer server.Close()
+
+	categories, err := category.NewHTTPSource(server.URL).Categories(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, categories, 5)
+}
+
+func TestHTTPSourceError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := category.NewHTTPSource(server.URL).Categories(context.Background())
+	assert.NotNil(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	source, _ := (&category.FileSource{Path: "testdata/categories.json"}).Categories(context.Background())
+	stored := []category.Category{
+		{BukalapakCategoryID: 2, BukalapakCategoryName: "Fashion Wanita"},
+		{BukalapakCategoryID: 3, BukalapakCategoryName: "Pakaian Pria"},
+		{BukalapakCategoryID: 4, BukalapakCategoryName: "Handphone"},
+		{BukalapakCategoryID: 5, BukalapakCategoryName: "Perawatan & Kecantikan", Deleted: true},
+		{BukalapakCategoryID: 9, BukalapakCategoryName: "Elektronik", Deleted: true},
+	}
+
+	report := category.Diff(stored, source)
+
+	assert.Equal(t, []category.Change{{BukalapakCategoryID: 21, NewName: "Dress"}, {BukalapakCategoryID: 22, NewName: "Atasan Wanita"}}, report.Added)
+	assert.Equal(t, []category.Change{{BukalapakCategoryID: 3, OldName: "Pakaian Pria", NewName: "Fashion Pria"}}, report.Renamed)
+	assert.Equal(t, []category.Change{{BukalapakCategoryID: 4, OldName: "Handphone"}}, report.Removed)
+	assert.Equal(t, []category.Change{{BukalapakCategoryID: 5, OldName: "Perawatan & Kecantikan", NewName: "Perawatan & Kecantikan"}}, report.Restored)
+	assert.False(t, report.Empty())
+}
+
+func TestDiffInSync(t *testing.T) {
+	stored := []category.Category{{BukalapakCategoryID: 3, BukalapakCategoryName: "Fashion Pria"}}
+	report := category.Diff(stored, []category.SourceCategory{{ID: 3, Name: "Fashion Pria"}})
+
+	assert.True(t, report.Empty())
+}
@@ -0,0 +1,82 @@
+package conditional
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Validator identifies a version of a resource representation
+type Validator struct {
+	// ETag is a quoted entity tag, weak ones prefixed with W/
+	ETag string
+	// LastModified is the last change of the resource, zero when unknown
+	LastModified time.Time
+}
+
+// NewETag returns a weak entity tag hashing given parts.
+// It is weak because equal data may be encoded differently, e.g. compressed or not.
+func NewETag(parts ...interface{}) string {
+	h := sha1.New()
+	for _, p := range parts {
+		fmt.Fprintf(h, "%v|", p)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil))[:20] + `"`
+}
+
+// NotModified sets ETag and Last-Modified headers of v, then checks If-None-Match and If-Modified-Since of r.
+// It writes 304 Not Modified and returns true when the client copy is still fresh; the caller must then stop.
+// If-Modified-Since is ignored when If-None-Match is given, as required by RFC 7232.
+func NotModified(w http.ResponseWriter, r *http.Request, v Validator) bool {
+	if v.ETag != "" {
+		w.Header().Set("ETag", v.ETag)
+	}
+	if !v.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if v.ETag == "" || !matchETag(inm, v.ETag) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || v.LastModified.IsZero() || v.LastModified.Truncate(time.Second).After(ims) {
+			return false
+		}
+	}
+
+	// Content-Type describes no body on 304
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// matchETag compares an If-None-Match list against etag with the weak comparison
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheControl wraps h to send given Cache-Control header, e.g. "no-cache" or "public, max-age=60"
+func CacheControl(value string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if value != "" {
+			w.Header().Set("Cache-Control", value)
+		}
+		h(w, r, ps)
+	}
+}
@@ -0,0 +1,71 @@
+package conditional_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/conditional"
+)
+
+var modified = time.Date(2018, 8, 10, 7, 45, 20, 500, time.UTC)
+
+func serve(headers map[string]string) *httptest.ResponseRecorder {
+	v := conditional.Validator{ETag: conditional.NewETag(1, 2, modified.Unix()), LastModified: modified}
+	h := conditional.CacheControl("no-cache", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if conditional.NotModified(w, r, v) {
+			return
+		}
+		w.Write([]byte("posts"))
+	})
+
+	r := httptest.NewRequest("GET", "/posts", nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h(w, r, nil)
+	return w
+}
+
+func TestNewETag(t *testing.T) {
+	assert.Equal(t, conditional.NewETag(1, "a"), conditional.NewETag(1, "a"))
+	assert.NotEqual(t, conditional.NewETag(1, "a"), conditional.NewETag(1, "b"))
+	assert.Regexp(t, `^W/"[0-9a-f]{20}"$`, conditional.NewETag(1))
+}
+
+func TestNotModified(t *testing.T) {
+	fresh := serve(nil)
+	assert.Equal(t, http.StatusOK, fresh.Code)
+	assert.Equal(t, "posts", fresh.Body.String())
+	assert.Equal(t, "no-cache", fresh.Header().Get("Cache-Control"))
+	assert.Equal(t, "Fri, 10 Aug 2018 07:45:20 GMT", fresh.Header().Get("Last-Modified"))
+	etag := fresh.Header().Get("ETag")
+
+	cases := []struct {
+		headers map[string]string
+		status  int
+	}{
+		{map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": `"other", ` + etag[2:]}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": `W/"other"`}, http.StatusOK},
+		{map[string]string{"If-Modified-Since": "Fri, 10 Aug 2018 07:45:20 GMT"}, http.StatusNotModified},
+		{map[string]string{"If-Modified-Since": "Fri, 10 Aug 2018 07:45:19 GMT"}, http.StatusOK},
+		{map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+		// If-None-Match wins over If-Modified-Since
+		{map[string]string{"If-None-Match": `W/"other"`, "If-Modified-Since": "Fri, 10 Aug 2018 07:45:20 GMT"}, http.StatusOK},
+	}
+	for _, c := range cases {
+		w := serve(c.headers)
+		assert.Equal(t, c.status, w.Code, "%v", c.headers)
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+		if c.status == http.StatusNotModified {
+			assert.Empty(t, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package currentuser
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+// Roles of back office users
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Permissions, named like the OAuth2 scopes granting them to clients
+const (
+	PermissionPostsRead         = "posts:read"
+	PermissionPostsWrite        = "posts:write"
+	PermissionPostsDelete       = "posts:delete"
+	PermissionInfluencersWrite  = "influencers:write"
+	PermissionInfluencersDelete = "influencers:delete"
+	PermissionHistoriesRead     = "histories:read"
+)
+
+// RolePermissions lists permissions granted by each role, every role including the previous one
+var RolePermissions = map[string][]string{
+	RoleViewer: {PermissionPostsRead, PermissionHistoriesRead},
+	RoleEditor: {PermissionPostsRead, PermissionHistoriesRead, PermissionPostsWrite, PermissionInfluencersWrite},
+	RoleAdmin:  {PermissionPostsRead, PermissionHistoriesRead, PermissionPostsWrite, PermissionInfluencersWrite, PermissionPostsDelete, PermissionInfluencersDelete},
+}
+
+// ScopePermissions lists permissions a client scope grants besides its own.
+// Write scopes keep allowing deletes, which they did before delete permissions existed.
+var ScopePermissions = map[string][]string{
+	PermissionPostsWrite:       {PermissionPostsDelete},
+	PermissionInfluencersWrite: {PermissionInfluencersDelete},
+}
+
+// CurrentUser is the user performing the request
+type CurrentUser struct {
+	ID int64
+	// Roles of a back office user, empty for clients acting on their own
+	Roles []string
+	// Scopes granted to the client performing the request
+	Scopes []string
+}
+
+// Anonymous tells whether the request is unauthenticated
+func (u *CurrentUser) Anonymous() bool {
+	return u.ID == 0 && len(u.Roles) == 0 && len(u.Scopes) == 0
+}
+
+// HasRole tells whether the user has given role
+func (u *CurrentUser) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can tells whether the user is allowed given permission.
+// Users are allowed what their roles grant. Clients without user roles are allowed what their scopes grant.
+func (u *CurrentUser) Can(permission string) bool {
+	if len(u.Roles) == 0 {
+		for _, s := range u.Scopes {
+			if s == permission {
+				return true
+			}
+			for _, p := range ScopePermissions[s] {
+				if p == permission {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, r := range u.Roles {
+		for _, p := range RolePermissions[r] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type key int
+
+// Key is current user context key
+const Key key = 0
+
+// NewContext returns context containing given CurrentUser
+func NewContext(ctx context.Context, user *CurrentUser) context.Context {
+	return context.WithValue(ctx, Key, user)
+}
+
+// FromContext returns CurrentUser contained in given context, or an anonymous user with ID 0
+func FromContext(ctx context.Context) *CurrentUser {
+	user, ok := ctx.Value(Key).(*CurrentUser)
+	if !ok || user == nil {
+		return &CurrentUser{}
+	}
+	return user
+}
+
+// Require wraps h so that it is only served to users allowed permission.
+// The current user must be put in the request context beforehand, e.g. by auth.Authenticator.
+func Require(permission string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		user := FromContext(r.Context())
+		if user.Anonymous() {
+			response.Fail(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if !user.Can(permission) {
+			response.Fail(w, http.StatusForbidden, "Not allowed to "+permission)
+			return
+		}
+		h(w, r, ps)
+	}
+}
@@ -0,0 +1,80 @@
+package currentuser_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+)
+
+func TestFromContext(t *testing.T) {
+	assert.True(t, currentuser.FromContext(context.Background()).Anonymous())
+
+	user := &currentuser.CurrentUser{ID: 42, Roles: []string{currentuser.RoleEditor}}
+	ctx := currentuser.NewContext(context.Background(), user)
+	assert.Equal(t, user, currentuser.FromContext(ctx))
+	assert.True(t, user.HasRole(currentuser.RoleEditor))
+	assert.False(t, user.HasRole(currentuser.RoleAdmin))
+}
+
+func TestCan(t *testing.T) {
+	viewer := &currentuser.CurrentUser{ID: 1, Roles: []string{currentuser.RoleViewer}}
+	editor := &currentuser.CurrentUser{ID: 2, Roles: []string{currentuser.RoleEditor}}
+	admin := &currentuser.CurrentUser{ID: 3, Roles: []string{currentuser.RoleViewer, currentuser.RoleAdmin}}
+	// roles prevail over scopes of the client the user signed in with
+	scopedEditor := &currentuser.CurrentUser{ID: 4, Roles: []string{currentuser.RoleEditor}, Scopes: []string{currentuser.PermissionPostsDelete}}
+	client := &currentuser.CurrentUser{Scopes: []string{currentuser.PermissionPostsRead, currentuser.PermissionPostsWrite}}
+
+	cases := []struct {
+		user       *currentuser.CurrentUser
+		permission string
+		allowed    bool
+	}{
+		{viewer, currentuser.PermissionPostsRead, true},
+		{viewer, currentuser.PermissionPostsWrite, false},
+		{editor, currentuser.PermissionPostsWrite, true},
+		{editor, currentuser.PermissionInfluencersWrite, true},
+		{editor, currentuser.PermissionPostsDelete, false},
+		{admin, currentuser.PermissionPostsDelete, true},
+		{admin, currentuser.PermissionInfluencersDelete, true},
+		{scopedEditor, currentuser.PermissionPostsDelete, false},
+		{client, currentuser.PermissionPostsWrite, true},
+		{client, currentuser.PermissionHistoriesRead, false},
+		// write scopes still allow clients to delete
+		{client, currentuser.PermissionPostsDelete, true},
+		{client, currentuser.PermissionInfluencersDelete, false},
+		{&currentuser.CurrentUser{}, currentuser.PermissionPostsRead, false},
+	}
+	for k, c := range cases {
+		assert.Equal(t, c.allowed, c.user.Can(c.permission), "case %d", k)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	h := currentuser.Require(currentuser.PermissionPostsDelete, func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cases := []struct {
+		user   *currentuser.CurrentUser
+		status int
+	}{
+		{nil, http.StatusUnauthorized},
+		{&currentuser.CurrentUser{ID: 2, Roles: []string{currentuser.RoleEditor}}, http.StatusForbidden},
+		{&currentuser.CurrentUser{ID: 3, Roles: []string{currentuser.RoleAdmin}}, http.StatusNoContent},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("DELETE", "/posts/1", nil)
+		if c.user != nil {
+			r = r.WithContext(currentuser.NewContext(r.Context(), c.user))
+		}
+		w := httptest.NewRecorder()
+		h(w, r, nil)
+		assert.Equal(t, c.status, w.Code)
+	}
+}
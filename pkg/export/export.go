@@ -0,0 +1,246 @@
+package export
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+)
+
+// WatermarksFile is the name of the watermarks file in the output directory
+const WatermarksFile = "watermarks.json"
+
+// DefaultMargin is how long before the snapshot incremental exports stop by default
+const DefaultMargin = time.Minute
+
+// Table is an exported table
+type Table struct {
+	Name    string
+	Columns []string
+}
+
+// Tables lists tables exported by default. Every table has an updated_at column used for incremental exports,
+// soft deleted rows being exported by their deleted_at too.
+var Tables = []Table{
+	{Name: "posts", Columns: []string{"id", "title", "description", "influencer_name", "influencer_id", "published", "first_published_at", "last_published_at", "like_count", "deleted", "deleted_at", "score", "created_at", "updated_at"}},
+	{Name: "post_images", Columns: []string{"id", "post_id", "url", "width", "height", "position", "deleted_at", "created_at", "updated_at"}},
+	{Name: "post_tags", Columns: []string{"id", "post_id", "post_image_id", "name", "url", "coord_x", "coord_y", "reference_type", "reference_id", "bukalapak_category_id", "deleted_at", "created_at", "updated_at"}},
+	{Name: "post_likes", Columns: []string{"id", "post_id", "bukalapak_user_id", "liked", "created_at", "updated_at"}},
+	{Name: "post_filters", Columns: []string{"id", "post_id", "bukalapak_category_id", "deleted_at", "created_at", "updated_at"}},
+}
+
+// File is an exported gzip file
+type File struct {
+	Table string
+	Path  string
+	Rows  int
+	// From is the lower bound of updated_at, zero for full exports
+	From time.Time
+	// To is the exclusive upper bound of updated_at, Margin before the snapshot
+	To time.Time
+}
+
+// Exporter dumps tables into gzip files of a local directory.
+// All tables are read from the same snapshot, in a single read only transaction.
+type Exporter struct {
+	DB     *sqlx.DB
+	Dir    string
+	Format string
+	// Full exports every row, ignoring watermarks
+	Full   bool
+	Tables []Table
+	// Margin leaves out rows updated shortly before the snapshot, their transactions possibly not committed yet.
+	// A transaction committing more than Margin after setting updated_at is missed by incremental exports.
+	Margin time.Duration
+}
+
+// Run exports every table, then moves watermarks to the snapshot time.
+// Watermarks are left untouched when any table fails, so that the next run exports the same rows again.
+func (e *Exporter) Run(ctx context.Context) ([]File, error) {
+	if err := os.MkdirAll(e.Dir, 0755); err != nil {
+		return nil, err
+	}
+	watermarksPath := filepath.Join(e.Dir, WatermarksFile)
+	watermarks, err := LoadWatermarks(watermarksPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// a transaction started with a consistent snapshot reads every table as of its start,
+	// which database/sql cannot ask for, so it is started on a dedicated connection
+	conn, err := e.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY"); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), "ROLLBACK")
+
+	var to time.Time
+	if err := conn.QueryRowContext(ctx, "SELECT NOW() - INTERVAL ? SECOND", int64(e.Margin/time.Second)).Scan(&to); err != nil {
+		return nil, err
+	}
+
+	files := []File{}
+	for _, t := range e.Tables {
+		f := File{Table: t.Name, To: to}
+		if !e.Full {
+			f.From = watermarks[t.Name]
+		}
+		f.Path = filepath.Join(e.Dir, fmt.Sprintf("%s_%s.%s.gz", t.Name, to.Format("20060102T150405"), e.Format))
+		if f.Rows, err = e.dump(ctx, conn, t, f); err != nil {
+			return files, fmt.Errorf("export %s: %v", t.Name, err)
+		}
+		files = append(files, f)
+	}
+
+	for _, f := range files {
+		watermarks[f.Table] = f.To
+	}
+	return files, watermarks.Save(watermarksPath)
+}
+
+// dump writes rows of t updated within [f.From, f.To) into f.Path, returning number of rows
+func (e *Exporter) dump(ctx context.Context, conn *sql.Conn, t Table, f File) (int, error) {
+	query, args := Query(t, f.From, f.To)
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+
+	tmp := f.Path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	w, err := NewWriter(gz, e.Format, t.Columns)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	values := make([]interface{}, len(t.Columns))
+	dest := make([]interface{}, len(t.Columns))
+	for k := range dest {
+		dest[k] = &values[k]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return count, err
+		}
+		for k, v := range values {
+			values[k] = convert(types[k].DatabaseTypeName(), v)
+		}
+		if err := w.Write(values); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	if err := w.Flush(); err != nil {
+		return count, err
+	}
+	if err := gz.Close(); err != nil {
+		return count, err
+	}
+	if err := out.Close(); err != nil {
+		return count, err
+	}
+	return count, os.Rename(tmp, f.Path)
+}
+
+// Query returns the query selecting rows of t updated within [from, to), from being zero for full exports.
+// Rows of tables with a deleted_at column are also selected when trashed within [from, to), so that
+// soft deletions reach incremental exports even when the row's updated_at was left untouched.
+func Query(t Table, from, to time.Time) (string, []interface{}) {
+	query := "SELECT " + strings.Join(t.Columns, ", ") + " FROM " + t.Name + " WHERE "
+	bounds := []interface{}{to.Format(config.DatabaseDatetimeFormat)}
+	if from.IsZero() {
+		return query + "updated_at < ? ORDER BY id", bounds
+	}
+	bounds = append(bounds, from.Format(config.DatabaseDatetimeFormat))
+	if !t.softDeleted() {
+		return query + "updated_at < ? AND updated_at >= ? ORDER BY id", bounds
+	}
+	query += "(updated_at < ? AND updated_at >= ?) OR (deleted_at < ? AND deleted_at >= ?) ORDER BY id"
+	return query, append(bounds, bounds...)
+}
+
+// softDeleted tells whether rows of t are soft deleted through a deleted_at column
+func (t Table) softDeleted() bool {
+	for _, c := range t.Columns {
+		if c == "deleted_at" {
+			return true
+		}
+	}
+	return false
+}
+
+// convert turns raw bytes returned by the driver into typed values according to the column type
+func convert(dbType string, v interface{}) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	s := string(b)
+	switch dbType {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "FLOAT", "DOUBLE", "DECIMAL":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	}
+	return s
+}
+
+// ParseTables returns tables named in a comma separated list, all of them when names is empty
+func ParseTables(names string) ([]Table, error) {
+	if strings.TrimSpace(names) == "" {
+		return Tables, nil
+	}
+	tables := []Table{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, t := range Tables {
+			if t.Name == name {
+				tables = append(tables, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("export: unknown table %q", name)
+		}
+	}
+	return tables, nil
+}
@@ -0,0 +1,112 @@
+package export_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/export"
+)
+
+var columns = []string{"id", "title", "score", "published_at"}
+
+func rows() [][]interface{} {
+	publishedAt := time.Date(2018, 8, 10, 7, 45, 20, 0, time.UTC)
+	return [][]interface{}{
+		{int64(1), "Gaya \"Kasual\"", 1.5, publishedAt},
+		{int64(2), "Pesta, Malam", float64(0), nil},
+	}
+}
+
+func TestWriterJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.NewWriter(&buf, export.FormatJSONL, columns)
+	assert.Nil(t, err)
+	for _, r := range rows() {
+		assert.Nil(t, w.Write(r))
+	}
+	assert.Nil(t, w.Flush())
+
+	assert.Equal(t, `{"id":1,"published_at":"2018-08-10T07:45:20Z","score":1.5,"title":"Gaya \"Kasual\""}
+{"id":2,"published_at":null,"score":0,"title":"Pesta, Malam"}
+`, buf.String())
+}
+
+func TestWriterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.NewWriter(&buf, export.FormatCSV, columns)
+	assert.Nil(t, err)
+	for _, r := range rows() {
+		assert.Nil(t, w.Write(r))
+	}
+	assert.Nil(t, w.Flush())
+
+	assert.Equal(t, `id,title,score,published_at
+1,"Gaya ""Kasual""",1.5,2018-08-10T07:45:20Z
+2,"Pesta, Malam",0,
+`, buf.String())
+
+	_, err = export.NewWriter(&buf, "xml", columns)
+	assert.NotNil(t, err)
+}
+
+func TestWatermarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, export.WatermarksFile)
+
+	w, err := export.LoadWatermarks(path)
+	assert.Nil(t, err)
+	assert.Empty(t, w)
+
+	at := time.Date(2018, 8, 10, 7, 45, 20, 0, time.UTC)
+	w["posts"] = at
+	assert.Nil(t, w.Save(path))
+
+	w, err = export.LoadWatermarks(path)
+	assert.Nil(t, err)
+	assert.True(t, at.Equal(w["posts"]))
+}
+
+func TestQuery(t *testing.T) {
+	table := export.Table{Name: "post_likes", Columns: []string{"id", "post_id"}}
+	from := time.Date(2018, 8, 9, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2018, 8, 10, 0, 0, 0, 0, time.UTC)
+
+	query, args := export.Query(table, time.Time{}, to)
+	assert.Equal(t, "SELECT id, post_id FROM post_likes WHERE updated_at < ? ORDER BY id", query)
+	assert.Equal(t, []interface{}{"2018-08-10 00:00:00"}, args)
+
+	query, args = export.Query(table, from, to)
+	assert.Equal(t, "SELECT id, post_id FROM post_likes WHERE updated_at < ? AND updated_at >= ? ORDER BY id", query)
+	assert.Equal(t, []interface{}{"2018-08-10 00:00:00", "2018-08-09 00:00:00"}, args)
+
+	// rows trashed or restored are exported by their deleted_at too
+	table = export.Table{Name: "post_images", Columns: []string{"id", "deleted_at"}}
+	query, args = export.Query(table, time.Time{}, to)
+	assert.Equal(t, "SELECT id, deleted_at FROM post_images WHERE updated_at < ? ORDER BY id", query)
+	assert.Len(t, args, 1)
+	query, args = export.Query(table, from, to)
+	assert.Equal(t, "SELECT id, deleted_at FROM post_images WHERE (updated_at < ? AND updated_at >= ?) OR (deleted_at < ? AND deleted_at >= ?) ORDER BY id", query)
+	assert.Equal(t, []interface{}{"2018-08-10 00:00:00", "2018-08-09 00:00:00", "2018-08-10 00:00:00", "2018-08-09 00:00:00"}, args)
+}
+
+func TestParseTables(t *testing.T) {
+	tables, err := export.ParseTables("")
+	assert.Nil(t, err)
+	assert.Equal(t, export.Tables, tables)
+
+	tables, err = export.ParseTables("post_likes, posts")
+	assert.Nil(t, err)
+	assert.Equal(t, "post_likes", tables[0].Name)
+	assert.Equal(t, "posts", tables[1].Name)
+
+	_, err = export.ParseTables("users")
+	assert.NotNil(t, err)
+}
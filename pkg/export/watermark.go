@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Watermarks maps table name to the upper bound of its last export.
+// The next incremental export of a table starts from its watermark.
+type Watermarks map[string]time.Time
+
+// LoadWatermarks reads watermarks from path, missing file means no table was exported yet
+func LoadWatermarks(path string) (Watermarks, error) {
+	w := Watermarks{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, json.Unmarshal(b, &w)
+}
+
+// Save writes watermarks to path, replacing the previous file only once fully written
+func (w Watermarks) Save(path string) error {
+	b, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
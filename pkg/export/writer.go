@@ -0,0 +1,98 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Output formats
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// TimeFormat is the format of exported datetimes
+const TimeFormat = time.RFC3339
+
+// Writer encodes rows of a table
+type Writer interface {
+	// Write encodes a row, values are in the order of the columns given to NewWriter
+	Write(values []interface{}) error
+	// Flush writes any buffered data to the underlying writer
+	Flush() error
+}
+
+// NewWriter returns Writer of given format. CSV output starts with a header row.
+func NewWriter(w io.Writer, format string, columns []string) (Writer, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(w), columns: columns}, nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw, record: make([]string, len(columns))}, nil
+	}
+	return nil, fmt.Errorf("export: unknown format %q", format)
+}
+
+type jsonlWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+func (w *jsonlWriter) Write(values []interface{}) error {
+	row := make(map[string]interface{}, len(w.columns))
+	for k, c := range w.columns {
+		v := values[k]
+		if t, ok := v.(time.Time); ok {
+			v = t.Format(TimeFormat)
+		}
+		row[c] = v
+	}
+	return w.enc.Encode(row)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return nil
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (w *csvWriter) Write(values []interface{}) error {
+	for k, v := range values {
+		w.record[k] = csvValue(v)
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(TimeFormat)
+	}
+	return fmt.Sprint(v)
+}
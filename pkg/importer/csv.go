@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Columns lists CSV header columns. A post spans one row per tag, or per image without tag,
+// rows sharing the same ref. Post attributes are read from the first row of the post.
+var Columns = []string{
+	"ref", "title", "description", "influencer_name", "published", "categories",
+	"image_url", "image_width", "image_height", "image_position",
+	"tag_name", "tag_url", "tag_coord_x", "tag_coord_y", "tag_reference_type", "tag_reference_id", "tag_bukalapak_category_id",
+}
+
+var requiredColumns = []string{"ref", "title", "image_url"}
+
+// csvRow reads columns of a record by header name
+type csvRow struct {
+	line   int
+	index  map[string]int
+	record []string
+	errs   *[]RowError
+}
+
+func (r csvRow) str(column string) string {
+	i, ok := r.index[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func (r csvRow) fail(column, message string) {
+	*r.errs = append(*r.errs, RowError{Row: r.line, Field: column, Message: message})
+}
+
+func (r csvRow) int(column string) int64 {
+	s := r.str(column)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		r.fail(column, "is not a number")
+	}
+	return v
+}
+
+func (r csvRow) float(column string) float64 {
+	s := r.str(column)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		r.fail(column, "is not a number")
+	}
+	return v
+}
+
+func (r csvRow) bool(column string) bool {
+	switch strings.ToLower(r.str(column)) {
+	case "", "0", "false", "no", "n":
+		return false
+	case "1", "true", "yes", "y":
+		return true
+	}
+	r.fail(column, "is not a boolean")
+	return false
+}
+
+func (r csvRow) ints(column string) []int64 {
+	values := []int64{}
+	for _, s := range strings.FieldsFunc(r.str(column), func(c rune) bool { return c == '|' || c == ';' || c == ' ' }) {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			r.fail(column, "must be numbers separated by |")
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func parseCSV(r io.Reader) ([]Post, []RowError) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, []RowError{{Field: "body", Message: "is not a valid CSV: " + err.Error()}}
+	}
+	if len(records) == 0 {
+		return nil, []RowError{{Row: 1, Field: "header", Message: "is missing"}}
+	}
+
+	index := map[string]int{}
+	for i, column := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	errs := []RowError{}
+	for _, column := range requiredColumns {
+		if _, ok := index[column]; !ok {
+			errs = append(errs, RowError{Row: 1, Field: column, Message: "column is missing"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	posts := []Post{}
+	byRef := map[string]int{}
+	for k, record := range records[1:] {
+		row := csvRow{line: k + 2, index: index, record: record, errs: &errs}
+
+		ref := row.str("ref")
+		if ref == "" {
+			row.fail("ref", "can't be blank")
+			continue
+		}
+		p, ok := byRef[ref]
+		if !ok {
+			posts = append(posts, Post{
+				Ref:            ref,
+				Title:          row.str("title"),
+				Description:    row.str("description"),
+				InfluencerName: row.str("influencer_name"),
+				Published:      row.bool("published"),
+				Categories:     row.ints("categories"),
+				Row:            row.line,
+			})
+			p = len(posts) - 1
+			byRef[ref] = p
+		}
+		post := &posts[p]
+
+		imageURL := row.str("image_url")
+		img := -1
+		for i := range post.Images {
+			if post.Images[i].URL == imageURL {
+				img = i
+			}
+		}
+		if img < 0 {
+			post.Images = append(post.Images, Image{
+				URL:      imageURL,
+				Width:    int(row.int("image_width")),
+				Height:   int(row.int("image_height")),
+				Position: int(row.int("image_position")),
+				Row:      row.line,
+			})
+			img = len(post.Images) - 1
+		}
+
+		if row.str("tag_name") == "" && row.str("tag_url") == "" {
+			continue
+		}
+		post.Images[img].Tags = append(post.Images[img].Tags, Tag{
+			Name:                row.str("tag_name"),
+			URL:                 row.str("tag_url"),
+			CoordX:              row.float("tag_coord_x"),
+			CoordY:              row.float("tag_coord_y"),
+			ReferenceType:       row.str("tag_reference_type"),
+			ReferenceID:         row.int("tag_reference_id"),
+			BukalapakCategoryID: row.int("tag_bukalapak_category_id"),
+			Row:                 row.line,
+		})
+	}
+	return posts, errs
+}
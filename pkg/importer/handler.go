@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+// maxBodySize caps uploaded import files
+const maxBodySize = 10 << 20
+
+// Handler serves post import endpoint
+type Handler struct {
+	DB *sqlx.DB
+	// Cache holds public post listings, invalidated by imports
+	Cache *cache.Cache
+}
+
+// Import creates posts from a CSV (text/csv) or JSON body, all or nothing.
+// With dry_run=true it only validates and previews the posts.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	format := FormatJSON
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") || r.URL.Query().Get("format") == FormatCSV {
+		format = FormatCSV
+	}
+	posts, errs := Parse(http.MaxBytesReader(w, r.Body, maxBodySize), format)
+	if len(errs) > 0 {
+		response.Write(w, response.Body{Data: &Report{Errors: errs}, Meta: response.Meta{HTTPStatus: http.StatusUnprocessableEntity}})
+		return
+	}
+
+	im := &Importer{DB: h.DB}
+	report, err := im.Import(ctx, posts, r.URL.Query().Get("dry_run") == "true")
+	if err != nil {
+		log.ErrLog(ctx, err, "import", "Failed to import posts")
+		response.Fail(w, http.StatusInternalServerError, "Failed to import posts")
+		return
+	}
+	if len(report.Errors) > 0 {
+		response.Write(w, response.Body{Data: report, Meta: response.Meta{HTTPStatus: http.StatusUnprocessableEntity}})
+		return
+	}
+	if report.DryRun {
+		response.OK(w, report, response.Meta{})
+		return
+	}
+	ids := make([]int64, len(report.Posts))
+	for k, p := range report.Posts {
+		ids[k] = p.ID
+	}
+	if err := post.Invalidate(ctx, h.DB, h.Cache, ids...); err != nil {
+		log.ErrLog(ctx, err, "import", "Failed to invalidate cached listings")
+	}
+	log.InfoLog(ctx, fmt.Sprintf("imported %d posts", len(report.Posts)), "post", "import")
+	response.Created(w, report)
+}
@@ -0,0 +1,216 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+)
+
+// Report is the outcome of an import, or its preview on dry run
+type Report struct {
+	DryRun bool       `json:"dry_run"`
+	Posts  []Post     `json:"posts"`
+	Images int        `json:"images"`
+	Tags   int        `json:"tags"`
+	Errors []RowError `json:"errors"`
+}
+
+// Importer inserts parsed posts, all or nothing
+type Importer struct {
+	DB *sqlx.DB
+}
+
+// Import validates every post, then inserts all of them in a single transaction unless dryRun is set.
+// Nothing is inserted when any row is invalid; the errors are listed in the report.
+func (im *Importer) Import(ctx context.Context, posts []Post, dryRun bool) (*Report, error) {
+	report := &Report{DryRun: dryRun, Posts: posts, Errors: Validate(posts)}
+	for _, p := range posts {
+		report.Images += len(p.Images)
+		for _, img := range p.Images {
+			report.Tags += len(img.Tags)
+		}
+	}
+
+	errs, err := im.resolve(ctx, posts)
+	if err != nil {
+		return nil, err
+	}
+	report.Errors = append(report.Errors, errs...)
+	if dryRun || len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	err = mysql.Transaction(ctx, im.DB, func(tx *sqlx.Tx) error {
+		for k := range posts {
+			if err := insert(ctx, tx, &posts[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return report, err
+}
+
+// resolve links influencer names to influencers and checks referenced categories and influencers exist
+func (im *Importer) resolve(ctx context.Context, posts []Post) ([]RowError, error) {
+	influencers := []struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}{}
+	if err := im.DB.SelectContext(ctx, &influencers, "SELECT id, COALESCE(name, '') AS name FROM influencers"); err != nil {
+		return nil, err
+	}
+	influencerIDs := map[int64]string{}
+	influencerNames := map[string]int64{}
+	for _, i := range influencers {
+		influencerIDs[i.ID] = i.Name
+		influencerNames[strings.ToLower(strings.TrimSpace(i.Name))] = i.ID
+	}
+
+	categoryIDs := []int64{}
+	if err := im.DB.SelectContext(ctx, &categoryIDs, "SELECT bukalapak_category_id FROM categories WHERE deleted = false"); err != nil {
+		return nil, err
+	}
+	categories := map[int64]bool{}
+	for _, id := range categoryIDs {
+		categories[id] = true
+	}
+
+	errs := []RowError{}
+	for k := range posts {
+		p := &posts[k]
+		switch {
+		case p.InfluencerID > 0:
+			name, ok := influencerIDs[p.InfluencerID]
+			if !ok {
+				errs = append(errs, RowError{Row: p.Row, Field: "influencer_id", Message: "does not exist"})
+			}
+			p.InfluencerName = name
+		case p.InfluencerName != "":
+			// unknown names are kept as they are, to be reconciled later
+			p.InfluencerID = influencerNames[strings.ToLower(strings.TrimSpace(p.InfluencerName))]
+		}
+
+		for _, c := range p.Categories {
+			if !categories[c] {
+				errs = append(errs, RowError{Row: p.Row, Field: "categories", Message: "contains unknown category"})
+				break
+			}
+		}
+	}
+	return errs, nil
+}
+
+// createdTag is the audited state of an inserted post_tags row
+type createdTag struct {
+	ID                  int64       `db:"id"`
+	PostID              int64       `db:"post_id"`
+	PostImageID         int64       `db:"post_image_id"`
+	Name                string      `db:"name"`
+	URL                 string      `db:"url"`
+	CoordX              float64     `db:"coord_x"`
+	CoordY              float64     `db:"coord_y"`
+	ReferenceID         interface{} `db:"reference_id"`
+	ReferenceType       interface{} `db:"reference_type"`
+	BukalapakCategoryID int64       `db:"bukalapak_category_id"`
+	CreatedAt           time.Time   `db:"created_at"`
+}
+
+func insert(ctx context.Context, tx *sqlx.Tx, p *Post) error {
+	now := time.Now()
+	nowStr := now.Format(config.DatabaseDatetimeFormat)
+	var publishedAt interface{}
+	if p.Published {
+		publishedAt = nowStr
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO posts (title, description, influencer_name, influencer_id, published, first_published_at, last_published_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		p.Title, p.Description, p.InfluencerName, p.InfluencerID, p.Published, publishedAt, publishedAt, nowStr, nowStr)
+	if err != nil {
+		return err
+	}
+	if p.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	for _, img := range p.Images {
+		res, err := tx.ExecContext(ctx, "INSERT INTO post_images (post_id, url, width, height, position, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			p.ID, img.URL, img.Width, img.Height, img.Position, nowStr, nowStr)
+		if err != nil {
+			return err
+		}
+		imageID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		image := post.Image{ID: imageID, PostID: p.ID, URL: img.URL, Width: img.Width, Height: img.Height, Position: img.Position, CreatedAt: now, UpdatedAt: now}
+		if err := audit.Record(ctx, tx, audit.RecordPostImage, imageID, nil, &image); err != nil {
+			return err
+		}
+
+		for _, t := range img.Tags {
+			var referenceID, referenceType interface{}
+			if t.ReferenceID > 0 {
+				referenceID, referenceType = t.ReferenceID, t.ReferenceType
+			}
+			res, err := tx.ExecContext(ctx, "INSERT INTO post_tags (post_id, post_image_id, name, url, coord_x, coord_y, reference_id, reference_type, bukalapak_category_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				p.ID, imageID, t.Name, t.URL, t.CoordX, t.CoordY, referenceID, referenceType, t.BukalapakCategoryID, nowStr, nowStr)
+			if err != nil {
+				return err
+			}
+			tagID, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			tag := createdTag{
+				ID:                  tagID,
+				PostID:              p.ID,
+				PostImageID:         imageID,
+				Name:                t.Name,
+				URL:                 t.URL,
+				CoordX:              t.CoordX,
+				CoordY:              t.CoordY,
+				ReferenceID:         referenceID,
+				ReferenceType:       referenceType,
+				BukalapakCategoryID: t.BukalapakCategoryID,
+				CreatedAt:           now,
+			}
+			if err := audit.Record(ctx, tx, audit.RecordPostTag, tagID, nil, &tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	seen := map[int64]bool{}
+	for _, c := range p.Categories {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		if _, err := tx.ExecContext(ctx, "INSERT INTO post_filters (post_id, bukalapak_category_id) VALUES (?, ?)", p.ID, c); err != nil {
+			return err
+		}
+	}
+
+	created := post.Post{
+		ID:             p.ID,
+		Title:          p.Title,
+		Description:    p.Description,
+		InfluencerName: p.InfluencerName,
+		InfluencerID:   p.InfluencerID,
+		Published:      p.Published,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if p.Published {
+		created.FirstPublishedAt, created.LastPublishedAt = &now, &now
+	}
+	return audit.Record(ctx, tx, audit.RecordPost, p.ID, nil, &created)
+}
@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Formats accepted by Parse
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Tag is a tagged product or seller on an image
+type Tag struct {
+	Name                string  `json:"name"`
+	URL                 string  `json:"url"`
+	CoordX              float64 `json:"coord_x"`
+	CoordY              float64 `json:"coord_y"`
+	ReferenceType       string  `json:"reference_type"`
+	ReferenceID         int64   `json:"reference_id"`
+	BukalapakCategoryID int64   `json:"bukalapak_category_id"`
+
+	Row int `json:"-"`
+}
+
+// Image is an image of an imported post
+type Image struct {
+	URL      string `json:"url"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Position int    `json:"position"`
+	Tags     []Tag  `json:"tags"`
+
+	Row int `json:"-"`
+}
+
+// Post is an imported post with its images and tags
+type Post struct {
+	ID             int64   `json:"id,omitempty"`
+	Ref            string  `json:"ref"`
+	Title          string  `json:"title"`
+	Description    string  `json:"description"`
+	InfluencerName string  `json:"influencer_name"`
+	InfluencerID   int64   `json:"influencer_id,omitempty"`
+	Published      bool    `json:"published"`
+	Categories     []int64 `json:"categories"`
+	Images         []Image `json:"images"`
+
+	Row int `json:"-"`
+}
+
+// RowError is a validation error of a source row.
+// Row is the line number for CSV and the 1-based post index for JSON.
+type RowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s %s", e.Row, e.Field, e.Message)
+}
+
+// Parse reads posts in given format
+func Parse(r io.Reader, format string) ([]Post, []RowError) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatJSON:
+		return parseJSON(r)
+	}
+	return nil, []RowError{{Field: "format", Message: "must be csv or json"}}
+}
+
+func parseJSON(r io.Reader) ([]Post, []RowError) {
+	posts := []Post{}
+	if err := json.NewDecoder(r).Decode(&posts); err != nil {
+		return nil, []RowError{{Field: "body", Message: "is not a valid JSON array of posts: " + err.Error()}}
+	}
+	for k := range posts {
+		posts[k].Row = k + 1
+		for i := range posts[k].Images {
+			posts[k].Images[i].Row = k + 1
+			for j := range posts[k].Images[i].Tags {
+				posts[k].Images[i].Tags[j].Row = k + 1
+			}
+		}
+	}
+	return posts, nil
+}
+
+// Validate checks posts attributes that do not need the database
+func Validate(posts []Post) []RowError {
+	errs := []RowError{}
+	add := func(row int, field, message string) {
+		errs = append(errs, RowError{Row: row, Field: field, Message: message})
+	}
+
+	refs := map[string]bool{}
+	for _, p := range posts {
+		if p.Ref != "" {
+			if refs[p.Ref] {
+				add(p.Row, "ref", "is duplicated")
+			}
+			refs[p.Ref] = true
+		}
+		if strings.TrimSpace(p.Title) == "" {
+			add(p.Row, "title", "can't be blank")
+		}
+		if len(p.Title) > 255 {
+			add(p.Row, "title", "is too long (maximum is 255 characters)")
+		}
+		if len(p.InfluencerName) > 255 {
+			add(p.Row, "influencer_name", "is too long (maximum is 255 characters)")
+		}
+		if len(p.Images) == 0 {
+			add(p.Row, "images", "can't be empty")
+		}
+
+		positions := map[int]bool{}
+		for _, img := range p.Images {
+			if !validURL(img.URL) {
+				add(img.Row, "image_url", "is not a valid URL")
+			}
+			if img.Width <= 0 || img.Height <= 0 {
+				add(img.Row, "image_size", "must be positive")
+			}
+			if positions[img.Position] {
+				add(img.Row, "image_position", "is duplicated")
+			}
+			positions[img.Position] = true
+
+			for _, t := range img.Tags {
+				if strings.TrimSpace(t.Name) == "" {
+					add(t.Row, "tag_name", "can't be blank")
+				}
+				if !validURL(t.URL) {
+					add(t.Row, "tag_url", "is not a valid URL")
+				}
+				// coordinates are percentages of image width and height
+				if t.CoordX < 0 || t.CoordX > 100 || t.CoordY < 0 || t.CoordY > 100 {
+					add(t.Row, "tag_coord", "must be between 0 and 100")
+				}
+				if (t.ReferenceType == "") != (t.ReferenceID == 0) {
+					add(t.Row, "tag_reference", "needs both type and id")
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,91 @@
+package importer_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/importer"
+)
+
+func parseFile(t *testing.T, path, format string) []importer.Post {
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	posts, errs := importer.Parse(f, format)
+	assert.Empty(t, errs)
+	return posts
+}
+
+func TestParseCSVAndJSONAgree(t *testing.T) {
+	fromCSV := parseFile(t, "testdata/posts.csv", importer.FormatCSV)
+	fromJSON := parseFile(t, "testdata/posts.json", importer.FormatJSON)
+
+	assert.Len(t, fromCSV, 2)
+	assert.Len(t, fromJSON, 2)
+	for k := range fromCSV {
+		c, j := fromCSV[k], fromJSON[k]
+		assert.Equal(t, j.Ref, c.Ref)
+		assert.Equal(t, j.Title, c.Title)
+		assert.Equal(t, j.Published, c.Published)
+		assert.Equal(t, len(j.Images), len(c.Images))
+		for i := range c.Images {
+			assert.Equal(t, j.Images[i].URL, c.Images[i].URL)
+			assert.Equal(t, j.Images[i].Position, c.Images[i].Position)
+			assert.Equal(t, len(j.Images[i].Tags), len(c.Images[i].Tags))
+		}
+	}
+
+	assert.Equal(t, []int64{2, 21}, fromCSV[0].Categories)
+	assert.Equal(t, 40.5, fromCSV[0].Images[0].Tags[0].CoordX)
+	assert.Equal(t, 3, fromCSV[0].Images[0].Tags[1].Row)
+	assert.Empty(t, importer.Validate(fromCSV))
+	assert.Empty(t, importer.Validate(fromJSON))
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	_, errs := importer.Parse(strings.NewReader("title,image_url\nA,https://a.com/a.jpg\n"), importer.FormatCSV)
+	assert.Equal(t, []importer.RowError{{Row: 1, Field: "ref", Message: "column is missing"}}, errs)
+
+	csv := "ref,title,image_url,image_width,published\n" +
+		"a,A,https://a.com/a.jpg,wide,maybe\n" +
+		",B,https://a.com/b.jpg,1,true\n"
+	_, errs = importer.Parse(strings.NewReader(csv), importer.FormatCSV)
+	assert.Equal(t, []importer.RowError{
+		{Row: 2, Field: "published", Message: "is not a boolean"},
+		{Row: 2, Field: "image_width", Message: "is not a number"},
+		{Row: 3, Field: "ref", Message: "can't be blank"},
+	}, errs)
+
+	_, errs = importer.Parse(strings.NewReader("{}"), importer.FormatJSON)
+	assert.Len(t, errs, 1)
+
+	_, errs = importer.Parse(strings.NewReader(""), "xlsx")
+	assert.Len(t, errs, 1)
+}
+
+func TestValidate(t *testing.T) {
+	posts := []importer.Post{
+		{Ref: "a", Row: 2, Images: []importer.Image{
+			{URL: "1.jpg", Width: 0, Height: 10, Row: 2, Tags: []importer.Tag{
+				{Name: "Kemeja", URL: "https://www.bukalapak.com/p/kemeja", CoordX: 120, ReferenceType: "product", Row: 2},
+			}},
+			{URL: "https://a.com/2.jpg", Width: 10, Height: 10, Row: 3},
+		}},
+		{Ref: "a", Title: "B", Row: 4},
+	}
+
+	assert.Equal(t, []importer.RowError{
+		{Row: 2, Field: "title", Message: "can't be blank"},
+		{Row: 2, Field: "image_url", Message: "is not a valid URL"},
+		{Row: 2, Field: "image_size", Message: "must be positive"},
+		{Row: 2, Field: "tag_coord", Message: "must be between 0 and 100"},
+		{Row: 2, Field: "tag_reference", Message: "needs both type and id"},
+		{Row: 3, Field: "image_position", Message: "is duplicated"},
+		{Row: 4, Field: "ref", Message: "is duplicated"},
+		{Row: 4, Field: "images", Message: "can't be empty"},
+	}, importer.Validate(posts))
+}
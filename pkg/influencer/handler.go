@@ -0,0 +1,235 @@
+package influencer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+	"github.com/wiskarindra/jenkins_jr/pkg/conditional"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+	"github.com/wiskarindra/jenkins_jr/pkg/request"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+// Handler serves influencer endpoints
+type Handler struct {
+	DB *sqlx.DB
+	// Cache holds public post listings, which show influencer names
+	Cache *cache.Cache
+}
+
+// params are the attributes accepted on create and update, nil means unchanged
+type params struct {
+	Name        *string      `json:"name"`
+	Slug        *string      `json:"slug"`
+	AvatarURL   *string      `json:"avatar_url"`
+	Bio         *string      `json:"bio"`
+	SocialLinks *SocialLinks `json:"social_links"`
+}
+
+func (p params) apply(i *Influencer) {
+	if p.Name != nil {
+		i.Name = *p.Name
+	}
+	if p.Slug != nil {
+		i.Slug = *p.Slug
+	}
+	if p.AvatarURL != nil {
+		i.AvatarURL = *p.AvatarURL
+	}
+	if p.Bio != nil {
+		i.Bio = *p.Bio
+	}
+	if p.SocialLinks != nil {
+		i.SocialLinks = *p.SocialLinks
+	}
+}
+
+// lookup finds influencer by numeric ID or by slug
+func (h *Handler) lookup(ctx context.Context, key string) (*Influencer, error) {
+	if id := request.ID(key); id > 0 {
+		return Find(ctx, h.DB, id)
+	}
+	return FindBySlug(ctx, h.DB, key)
+}
+
+func (h *Handler) fail(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	if err == ErrNotFound {
+		response.Fail(w, http.StatusNotFound, err.Error())
+		return
+	}
+	log.ErrLog(ctx, err, "influencer", message)
+	response.Fail(w, http.StatusInternalServerError, message)
+}
+
+// List returns influencers ordered by name
+func (h *Handler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	limit, offset := request.Page(r)
+
+	influencers, err := List(ctx, h.DB, limit, offset)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to list influencers")
+		return
+	}
+	total, err := Count(ctx, h.DB)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to count influencers")
+		return
+	}
+	response.OK(w, influencers, response.Meta{Limit: limit, Offset: offset, Total: total})
+}
+
+// Show returns public profile of an influencer, looked up by ID or slug
+func (h *Handler) Show(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	i, err := h.lookup(ctx, ps.ByName("id"))
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to get influencer")
+		return
+	}
+	response.OK(w, i, response.Meta{})
+}
+
+// Posts returns published posts of an influencer, looked up by ID or slug
+func (h *Handler) Posts(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	i, err := h.lookup(ctx, ps.ByName("id"))
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to get influencer")
+		return
+	}
+
+	limit, offset := request.Page(r)
+	posts, err := post.ListPublishedByInfluencer(ctx, h.DB, i.ID, limit, offset)
+	if err == nil {
+		err = post.LoadImages(ctx, h.DB, posts)
+	}
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to list influencer posts")
+		return
+	}
+	total, err := post.CountPublishedByInfluencer(ctx, h.DB, i.ID)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to count influencer posts")
+		return
+	}
+	v, err := post.Validator(ctx, h.DB, posts, limit, offset, total)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to list influencer posts")
+		return
+	}
+	if conditional.NotModified(w, r, v) {
+		return
+	}
+	response.OK(w, posts, response.Meta{Limit: limit, Offset: offset, Total: total})
+}
+
+// Create creates an influencer
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var p params
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		response.Fail(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	i := &Influencer{}
+	p.apply(i)
+	if err := i.Validate(); err != nil {
+		response.Fail(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	err := mysql.Transaction(ctx, h.DB, func(tx *sqlx.Tx) error {
+		if err := Create(ctx, tx, i); err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.RecordInfluencer, i.ID, nil, i)
+	})
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to create influencer")
+		return
+	}
+	log.InfoLog(ctx, fmt.Sprintf("created influencer %d", i.ID), "influencer", "create")
+	response.Created(w, i)
+}
+
+// Update updates attributes of an influencer
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	i, err := Find(ctx, h.DB, request.ID(ps.ByName("id")))
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to get influencer")
+		return
+	}
+
+	var p params
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		response.Fail(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	before := *i
+	p.apply(i)
+	if err := i.Validate(); err != nil {
+		response.Fail(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	err = mysql.Transaction(ctx, h.DB, func(tx *sqlx.Tx) error {
+		if err := Update(ctx, tx, i); err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.RecordInfluencer, i.ID, &before, i)
+	})
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to update influencer")
+		return
+	}
+	if h.Cache != nil {
+		h.Cache.Invalidate(post.InfluencerTag(i.ID))
+	}
+	log.InfoLog(ctx, fmt.Sprintf("updated influencer %d", i.ID), "influencer", "update")
+	response.OK(w, i, response.Meta{})
+}
+
+// Delete deletes an influencer, its posts are kept without influencer
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	i, err := Find(ctx, h.DB, request.ID(ps.ByName("id")))
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to get influencer")
+		return
+	}
+
+	var detached []int64
+	err = mysql.Transaction(ctx, h.DB, func(tx *sqlx.Tx) error {
+		if detached, err = Delete(ctx, tx, i.ID); err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.RecordInfluencer, i.ID, i, nil)
+	})
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to delete influencer")
+		return
+	}
+	if h.Cache != nil {
+		h.Cache.Invalidate(post.InfluencerTag(i.ID))
+		// detached posts are now listed without influencer
+		if err := post.Invalidate(ctx, h.DB, h.Cache, detached...); err != nil {
+			log.ErrLog(ctx, err, "influencer", "Failed to invalidate cached listings")
+		}
+	}
+	log.InfoLog(ctx, fmt.Sprintf("deleted influencer %d", i.ID), "influencer", "delete")
+	response.OK(w, i, response.Meta{})
+}
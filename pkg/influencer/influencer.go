@@ -0,0 +1,208 @@
+package influencer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+)
+
+const columns = "id, COALESCE(name, '') AS name, COALESCE(slug, '') AS slug, COALESCE(avatar_url, '') AS avatar_url, COALESCE(bio, '') AS bio, social_links, created_at, updated_at"
+
+// ErrNotFound is returned when influencer does not exist
+var ErrNotFound = errors.New("influencer not found")
+
+// SocialNetworks lists accepted SocialLinks keys
+var SocialNetworks = []string{"instagram", "youtube", "twitter", "facebook", "website"}
+
+// SocialLinks maps social network to profile URL, stored as JSON text
+type SocialLinks map[string]string
+
+// Value implements driver.Valuer
+func (s SocialLinks) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (s *SocialLinks) Scan(src interface{}) error {
+	*s = SocialLinks{}
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if len(v) == 0 {
+			return nil
+		}
+		return json.Unmarshal(v, s)
+	case string:
+		if v == "" {
+			return nil
+		}
+		return json.Unmarshal([]byte(v), s)
+	}
+	return fmt.Errorf("cannot scan %T into SocialLinks", src)
+}
+
+// Influencer is the author of inspiration posts
+type Influencer struct {
+	ID          int64       `db:"id" json:"id"`
+	Name        string      `db:"name" json:"name"`
+	Slug        string      `db:"slug" json:"slug"`
+	AvatarURL   string      `db:"avatar_url" json:"avatar_url"`
+	Bio         string      `db:"bio" json:"bio"`
+	SocialLinks SocialLinks `db:"social_links" json:"social_links"`
+	CreatedAt   time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time   `db:"updated_at" json:"updated_at"`
+
+	URL string `db:"-" json:"url"`
+}
+
+// ProfileURL returns canonical profile URL built from config.InfluencerIndexURL
+func ProfileURL(slug string) string {
+	return strings.TrimRight(config.InfluencerIndexURL(), "/") + "/" + url.PathEscape(slug)
+}
+
+// Validate checks influencer attributes
+func (i *Influencer) Validate() error {
+	if strings.TrimSpace(i.Name) == "" {
+		return errors.New("name can't be blank")
+	}
+	if len(i.Name) > 255 {
+		return errors.New("name is too long")
+	}
+	if i.AvatarURL != "" && !validURL(i.AvatarURL) {
+		return errors.New("avatar_url is not a valid URL")
+	}
+	for network, link := range i.SocialLinks {
+		if !knownNetwork(network) {
+			return fmt.Errorf("social_links.%s is not supported", network)
+		}
+		if !validURL(link) {
+			return fmt.Errorf("social_links.%s is not a valid URL", network)
+		}
+	}
+	return nil
+}
+
+func knownNetwork(network string) bool {
+	for _, n := range SocialNetworks {
+		if n == network {
+			return true
+		}
+	}
+	return false
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func get(ctx context.Context, db sqlx.QueryerContext, query string, args ...interface{}) (*Influencer, error) {
+	var i Influencer
+	err := sqlx.GetContext(ctx, db, &i, "SELECT "+columns+" FROM influencers "+query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	i.URL = ProfileURL(i.Slug)
+	return &i, nil
+}
+
+// Find returns influencer with given ID
+func Find(ctx context.Context, db sqlx.QueryerContext, id int64) (*Influencer, error) {
+	return get(ctx, db, "WHERE id = ?", id)
+}
+
+// FindBySlug returns influencer with given slug
+func FindBySlug(ctx context.Context, db sqlx.QueryerContext, slug string) (*Influencer, error) {
+	return get(ctx, db, "WHERE slug = ?", slug)
+}
+
+// List returns influencers ordered by name
+func List(ctx context.Context, db sqlx.QueryerContext, limit, offset int) ([]Influencer, error) {
+	influencers := []Influencer{}
+	if err := sqlx.SelectContext(ctx, db, &influencers, "SELECT "+columns+" FROM influencers ORDER BY name, id LIMIT ? OFFSET ?", limit, offset); err != nil {
+		return nil, err
+	}
+	for k := range influencers {
+		influencers[k].URL = ProfileURL(influencers[k].Slug)
+	}
+	return influencers, nil
+}
+
+// Count returns number of influencers
+func Count(ctx context.Context, db sqlx.QueryerContext) (int, error) {
+	var count int
+	err := sqlx.GetContext(ctx, db, &count, "SELECT COUNT(*) FROM influencers")
+	return count, err
+}
+
+// Create inserts influencer, generating its slug from name when empty
+func Create(ctx context.Context, tx *sqlx.Tx, i *Influencer) error {
+	slug, err := uniqueSlug(ctx, tx, i.Slug, i.Name, 0)
+	if err != nil {
+		return err
+	}
+	i.Slug = slug
+	i.URL = ProfileURL(slug)
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, "INSERT INTO influencers (name, slug, avatar_url, bio, social_links, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		i.Name, i.Slug, i.AvatarURL, i.Bio, i.SocialLinks, now.Format(config.DatabaseDatetimeFormat), now.Format(config.DatabaseDatetimeFormat))
+	if err != nil {
+		return err
+	}
+	i.ID, err = res.LastInsertId()
+	i.CreatedAt, i.UpdatedAt = now, now
+	return err
+}
+
+// Update saves influencer attributes and keeps posts.influencer_name in sync
+func Update(ctx context.Context, tx *sqlx.Tx, i *Influencer) error {
+	slug, err := uniqueSlug(ctx, tx, i.Slug, i.Name, i.ID)
+	if err != nil {
+		return err
+	}
+	i.Slug = slug
+	i.URL = ProfileURL(slug)
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE influencers SET name = ?, slug = ?, avatar_url = ?, bio = ?, social_links = ?, updated_at = ? WHERE id = ?",
+		i.Name, i.Slug, i.AvatarURL, i.Bio, i.SocialLinks, now.Format(config.DatabaseDatetimeFormat), i.ID)
+	if err != nil {
+		return err
+	}
+	i.UpdatedAt = now
+
+	_, err = tx.ExecContext(ctx, "UPDATE posts SET influencer_name = ?, updated_at = ? WHERE influencer_id = ? AND NOT (influencer_name <=> ?)", i.Name, now.Format(config.DatabaseDatetimeFormat), i.ID, i.Name)
+	return err
+}
+
+// Delete removes influencer and detaches its posts, returning IDs of the detached posts
+func Delete(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error) {
+	ids := []int64{}
+	if err := tx.SelectContext(ctx, &ids, "SELECT id FROM posts WHERE influencer_id = ? FOR UPDATE", id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE posts SET influencer_id = 0, updated_at = ? WHERE influencer_id = ?", time.Now().Format(config.DatabaseDatetimeFormat), id); err != nil {
+		return nil, err
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM influencers WHERE id = ?", id)
+	return ids, err
+}
@@ -0,0 +1,52 @@
+package influencer_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/influencer"
+)
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "raisa-andriana", influencer.Slugify("Raisa Andriana"))
+	assert.Equal(t, "dian-sastro", influencer.Slugify("  Dian -- Sastro! "))
+	assert.Equal(t, "influencer", influencer.Slugify("???"))
+	assert.Equal(t, "i-2018", influencer.Slugify("2018"))
+}
+
+func TestProfileURL(t *testing.T) {
+	url := os.Getenv("INFLUENCER_INDEX_URL")
+	defer os.Setenv("INFLUENCER_INDEX_URL", url)
+
+	os.Setenv("INFLUENCER_INDEX_URL", "http://www.local.host:5000/i/")
+	assert.Equal(t, "http://www.local.host:5000/i/raisa", influencer.ProfileURL("raisa"))
+}
+
+func TestValidate(t *testing.T) {
+	i := influencer.Influencer{Name: "Raisa", SocialLinks: influencer.SocialLinks{"instagram": "https://instagram.com/raisa6690"}}
+	assert.Nil(t, i.Validate())
+
+	i.Name = " "
+	assert.NotNil(t, i.Validate())
+
+	i = influencer.Influencer{Name: "Raisa", AvatarURL: "avatar.jpg"}
+	assert.NotNil(t, i.Validate())
+
+	i = influencer.Influencer{Name: "Raisa", SocialLinks: influencer.SocialLinks{"myspace": "https://myspace.com/raisa"}}
+	assert.NotNil(t, i.Validate())
+}
+
+func TestSocialLinksScan(t *testing.T) {
+	var links influencer.SocialLinks
+	assert.Nil(t, links.Scan([]byte(`{"instagram":"https://instagram.com/raisa6690"}`)))
+	assert.Equal(t, "https://instagram.com/raisa6690", links["instagram"])
+
+	assert.Nil(t, links.Scan(nil))
+	assert.Empty(t, links)
+
+	value, err := influencer.SocialLinks{}.Value()
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+}
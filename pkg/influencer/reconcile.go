@@ -0,0 +1,271 @@
+package influencer
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+)
+
+// Reconciliation actions
+const (
+	ActionMatched   = "matched"
+	ActionCreated   = "created"
+	ActionAmbiguous = "ambiguous"
+)
+
+// LegacyName is a free-text posts.influencer_name not linked to any influencer
+type LegacyName struct {
+	Name  string `db:"influencer_name"`
+	Posts int    `db:"posts"`
+}
+
+// Candidate is an influencer possibly matching a legacy name
+type Candidate struct {
+	ID    int64
+	Name  string
+	Score float64
+}
+
+// Match is the reconciliation decision for legacy names sharing the same normalized form
+type Match struct {
+	LegacyNames    []string
+	Posts          int
+	Action         string
+	InfluencerID   int64
+	InfluencerName string
+	Score          float64
+	Candidates     []Candidate
+}
+
+// Reconciler links legacy posts.influencer_name to influencers
+type Reconciler struct {
+	DB *sqlx.DB
+	// MatchThreshold is the minimum similarity to link a name to an existing influencer
+	MatchThreshold float64
+	// ReviewThreshold is the minimum similarity for a name to be reported for manual review instead of creating a new influencer
+	ReviewThreshold float64
+	// Margin is the minimum similarity gap between the two best candidates to pick the best one automatically
+	Margin float64
+	DryRun bool
+}
+
+// NewReconciler returns Reconciler with default thresholds
+func NewReconciler(db *sqlx.DB) *Reconciler {
+	return &Reconciler{DB: db, MatchThreshold: 0.9, ReviewThreshold: 0.7, Margin: 0.05}
+}
+
+// Run plans and, unless DryRun is set, applies the reconciliation
+func (r *Reconciler) Run(ctx context.Context) ([]Match, error) {
+	legacy := []LegacyName{}
+	err := r.DB.SelectContext(ctx, &legacy, "SELECT influencer_name, COUNT(*) AS posts FROM posts WHERE influencer_id = 0 AND influencer_name IS NOT NULL AND influencer_name <> '' GROUP BY influencer_name")
+	if err != nil {
+		return nil, err
+	}
+
+	influencers := []Influencer{}
+	if err := r.DB.SelectContext(ctx, &influencers, "SELECT "+columns+" FROM influencers"); err != nil {
+		return nil, err
+	}
+
+	matches := r.Plan(legacy, influencers)
+	if r.DryRun {
+		return matches, nil
+	}
+
+	err = mysql.Transaction(ctx, r.DB, func(tx *sqlx.Tx) error {
+		created := map[string]int64{}
+		for k := range matches {
+			if err := r.apply(ctx, tx, &matches[k], created); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// apply links posts of a match, created maps names of influencers created so far to their IDs
+func (r *Reconciler) apply(ctx context.Context, tx *sqlx.Tx, m *Match, created map[string]int64) error {
+	switch {
+	case m.Action == ActionAmbiguous:
+		return nil
+	case m.Action == ActionCreated:
+		i := &Influencer{Name: m.InfluencerName}
+		if err := Create(ctx, tx, i); err != nil {
+			return err
+		}
+		if err := audit.Record(ctx, tx, audit.RecordInfluencer, i.ID, nil, i); err != nil {
+			return err
+		}
+		m.InfluencerID = i.ID
+		created[i.Name] = i.ID
+	case m.InfluencerID == 0:
+		m.InfluencerID = created[m.InfluencerName]
+	}
+
+	query, args, err := sqlx.In("UPDATE posts SET influencer_id = ?, influencer_name = ?, updated_at = ? WHERE influencer_id = 0 AND influencer_name IN (?)",
+		m.InfluencerID, m.InfluencerName, time.Now().Format(config.DatabaseDatetimeFormat), m.LegacyNames)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Plan decides, for every group of legacy names, whether to link it, create a new influencer, or report it for review.
+// Influencers planned to be created take part in matching of the following groups with ID 0.
+func (r *Reconciler) Plan(legacy []LegacyName, influencers []Influencer) []Match {
+	pool := append([]Influencer{}, influencers...)
+
+	groups := map[string]*Match{}
+	keys := []string{}
+	for _, l := range legacy {
+		key := normalize(l.Name)
+		if key == "" {
+			continue
+		}
+		m, ok := groups[key]
+		if !ok {
+			m = &Match{}
+			groups[key] = m
+			keys = append(keys, key)
+		}
+		m.LegacyNames = append(m.LegacyNames, l.Name)
+		m.Posts += l.Posts
+	}
+	sort.Strings(keys)
+
+	matches := make([]Match, 0, len(keys))
+	for _, key := range keys {
+		m := groups[key]
+		m.Candidates = candidates(key, pool, r.ReviewThreshold)
+
+		switch {
+		case len(m.Candidates) == 0:
+			m.Action = ActionCreated
+			m.InfluencerName = strings.Join(strings.Fields(mostUsed(legacy, m.LegacyNames)), " ")
+			pool = append(pool, Influencer{Name: m.InfluencerName})
+		case m.Candidates[0].Score >= r.MatchThreshold && (len(m.Candidates) == 1 || m.Candidates[0].Score-m.Candidates[1].Score >= r.Margin):
+			m.Action = ActionMatched
+			m.InfluencerID = m.Candidates[0].ID
+			m.InfluencerName = m.Candidates[0].Name
+			m.Score = m.Candidates[0].Score
+		default:
+			m.Action = ActionAmbiguous
+			m.Score = m.Candidates[0].Score
+		}
+		matches = append(matches, *m)
+	}
+	return matches
+}
+
+// candidates returns influencers at least as similar as threshold, best first
+func candidates(key string, influencers []Influencer, threshold float64) []Candidate {
+	result := []Candidate{}
+	for _, i := range influencers {
+		if score := Similarity(key, normalize(i.Name)); score >= threshold {
+			result = append(result, Candidate{ID: i.ID, Name: i.Name, Score: score})
+		}
+	}
+	sort.SliceStable(result, func(a, b int) bool { return result[a].Score > result[b].Score })
+	return result
+}
+
+// mostUsed returns the legacy spelling used by most posts
+func mostUsed(legacy []LegacyName, names []string) string {
+	best, posts := names[0], -1
+	for _, l := range legacy {
+		for _, n := range names {
+			if l.Name == n && l.Posts > posts {
+				best, posts = n, l.Posts
+			}
+		}
+	}
+	return best
+}
+
+// normalize lowercases a name and strips everything but letters, digits and single spaces
+func normalize(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Similarity returns 1 minus the Levenshtein distance relative to the longer string
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// WriteCSV writes reconciliation matches as CSV report
+func WriteCSV(w io.Writer, matches []Match) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"legacy_names", "posts", "action", "influencer_id", "influencer_name", "score", "candidates"})
+	for _, m := range matches {
+		candidates := make([]string, len(m.Candidates))
+		for k, c := range m.Candidates {
+			candidates[k] = fmt.Sprintf("%d:%s:%.2f", c.ID, c.Name, c.Score)
+		}
+		cw.Write([]string{
+			strings.Join(m.LegacyNames, "|"),
+			strconv.Itoa(m.Posts),
+			m.Action,
+			strconv.FormatInt(m.InfluencerID, 10),
+			m.InfluencerName,
+			strconv.FormatFloat(m.Score, 'f', 2, 64),
+			strings.Join(candidates, "; "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,75 @@
+package influencer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/influencer"
+)
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, influencer.Similarity("raisa", "raisa"))
+	assert.Equal(t, 0.8, influencer.Similarity("raisa", "raisu"))
+	assert.Equal(t, 0.0, influencer.Similarity("abc", "xyz"))
+}
+
+func TestPlan(t *testing.T) {
+	influencers := []influencer.Influencer{
+		{ID: 1, Name: "Raisa Andriana"},
+		{ID: 2, Name: "Dian Sastro"},
+		{ID: 3, Name: "Dian Sastra"},
+	}
+	legacy := []influencer.LegacyName{
+		{Name: "raisa andriana", Posts: 3},
+		{Name: "Raisa  Andriana!", Posts: 1},
+		{Name: "Dian Sastrowardoyo", Posts: 2},
+		{Name: "Dian Sastr", Posts: 1},
+		{Name: "Tasya Farasya", Posts: 4},
+		{Name: "tasya farasyaa", Posts: 1},
+	}
+
+	matches := influencer.NewReconciler(nil).Plan(legacy, influencers)
+	assert.Len(t, matches, 5)
+
+	byName := map[string]influencer.Match{}
+	for _, m := range matches {
+		byName[m.LegacyNames[0]] = m
+	}
+
+	raisa := byName["raisa andriana"]
+	assert.Equal(t, influencer.ActionMatched, raisa.Action)
+	assert.Equal(t, int64(1), raisa.InfluencerID)
+	assert.Equal(t, 4, raisa.Posts)
+	assert.Equal(t, []string{"raisa andriana", "Raisa  Andriana!"}, raisa.LegacyNames)
+
+	assert.Equal(t, influencer.ActionAmbiguous, byName["Dian Sastr"].Action)
+	assert.Len(t, byName["Dian Sastr"].Candidates, 2)
+
+	assert.Equal(t, influencer.ActionCreated, byName["Dian Sastrowardoyo"].Action)
+	assert.Equal(t, "Dian Sastrowardoyo", byName["Dian Sastrowardoyo"].InfluencerName)
+
+	assert.Equal(t, influencer.ActionCreated, byName["Tasya Farasya"].Action)
+	assert.Equal(t, influencer.ActionMatched, byName["tasya farasyaa"].Action)
+	assert.Equal(t, int64(0), byName["tasya farasyaa"].InfluencerID)
+	assert.Equal(t, "Tasya Farasya", byName["tasya farasyaa"].InfluencerName)
+}
+
+func TestWriteCSV(t *testing.T) {
+	matches := []influencer.Match{{
+		LegacyNames: []string{"Dian Sastr"},
+		Posts:       1,
+		Action:      influencer.ActionAmbiguous,
+		Score:       0.91,
+		Candidates:  []influencer.Candidate{{ID: 2, Name: "Dian Sastro", Score: 0.91}, {ID: 3, Name: "Dian Sastra", Score: 0.91}},
+	}}
+
+	var b bytes.Buffer
+	assert.Nil(t, influencer.WriteCSV(&b, matches))
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(t, "legacy_names,posts,action,influencer_id,influencer_name,score,candidates", lines[0])
+	assert.Equal(t, "Dian Sastr,1,ambiguous,0,,0.91,2:Dian Sastro:0.91; 3:Dian Sastra:0.91", lines[1])
+}
@@ -0,0 +1,60 @@
+package influencer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Slugify turns a name into a lowercase URL friendly slug
+func Slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+			hyphen = false
+		case b.Len() > 0 && !hyphen:
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	slug := strings.TrimRight(b.String(), "-")
+	if slug == "" {
+		return "influencer"
+	}
+	// numeric slugs would be mistaken for IDs on profile endpoints
+	if strings.Trim(slug, "0123456789") == "" {
+		slug = "i-" + slug
+	}
+	return slug
+}
+
+// uniqueSlug returns requested slug (or one generated from name) suffixed with a number when already taken by another influencer
+func uniqueSlug(ctx context.Context, db sqlx.QueryerContext, requested, name string, id int64) (string, error) {
+	base := Slugify(requested)
+	if strings.TrimSpace(requested) == "" {
+		base = Slugify(name)
+	}
+
+	taken := []string{}
+	err := sqlx.SelectContext(ctx, db, &taken, "SELECT slug FROM influencers WHERE (slug = ? OR slug LIKE ?) AND id <> ?", base, base+"-%", id)
+	if err != nil {
+		return "", err
+	}
+
+	used := map[string]bool{}
+	for _, s := range taken {
+		used[s] = true
+	}
+
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
@@ -0,0 +1,562 @@
+package jenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Build results, as reported by Jenkins
+const (
+	ResultSuccess  = "SUCCESS"
+	ResultUnstable = "UNSTABLE"
+	ResultFailure  = "FAILURE"
+	ResultAborted  = "ABORTED"
+)
+
+// ErrNotFound is returned when a job, build or queue item does not exist
+var ErrNotFound = errors.New("not found on jenkins")
+
+// Job is a Jenkins job
+type Job struct {
+	Name      string     `json:"name"`
+	FullName  string     `json:"fullName"`
+	URL       string     `json:"url"`
+	Color     string     `json:"color"`
+	Buildable bool       `json:"buildable"`
+	LastBuild *BuildLink `json:"lastBuild"`
+	// LastCompletedBuild is the last build which is not running anymore
+	LastCompletedBuild *BuildLink `json:"lastCompletedBuild"`
+}
+
+// BuildLink refers to a build of a job
+type BuildLink struct {
+	Number int64  `json:"number"`
+	URL    string `json:"url"`
+}
+
+// Status describes the last build of a job from its ball color
+func (j *Job) Status() string {
+	color := strings.TrimSuffix(j.Color, "_anime")
+	status := map[string]string{
+		"blue":     "success",
+		"yellow":   "unstable",
+		"red":      "failed",
+		"aborted":  "aborted",
+		"notbuilt": "not built",
+		"disabled": "disabled",
+		"grey":     "pending",
+	}[color]
+	if status == "" {
+		status = "unknown"
+	}
+	if strings.HasSuffix(j.Color, "_anime") {
+		status += ", building"
+	}
+	return status
+}
+
+// Build is a build of a job
+type Build struct {
+	Number    int64  `json:"number"`
+	URL       string `json:"url"`
+	Building  bool   `json:"building"`
+	Result    string `json:"result"`
+	Timestamp int64  `json:"timestamp"`
+	Duration  int64  `json:"duration"`
+	// EstimatedDuration is in milliseconds, like Duration
+	EstimatedDuration int64 `json:"estimatedDuration"`
+}
+
+// StartedAt returns when the build started
+func (b *Build) StartedAt() time.Time {
+	return time.Unix(0, b.Timestamp*int64(time.Millisecond))
+}
+
+// Elapsed returns build duration, or time since its start while building
+func (b *Build) Elapsed() time.Duration {
+	if b.Building {
+		return time.Since(b.StartedAt())
+	}
+	return time.Duration(b.Duration) * time.Millisecond
+}
+
+// QueueItem is a build waiting for an executor
+type QueueItem struct {
+	ID         int64      `json:"id"`
+	Why        string     `json:"why"`
+	Cancelled  bool       `json:"cancelled"`
+	Executable *BuildLink `json:"executable"`
+}
+
+// Stage statuses, as reported by the pipeline API
+const (
+	StageSuccess      = "SUCCESS"
+	StageInProgress   = "IN_PROGRESS"
+	StageFailed       = "FAILED"
+	StageAborted      = "ABORTED"
+	StageNotExecuted  = "NOT_EXECUTED"
+	StagePendingInput = "PAUSED_PENDING_INPUT"
+)
+
+// Stage is a stage of a pipeline build
+type Stage struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// DurationMillis is the time spent in the stage so far
+	DurationMillis int64 `json:"durationMillis"`
+}
+
+// InputAction is an input step a pipeline build is paused on, as reported by the pipeline API
+type InputAction struct {
+	ID          string `json:"id"`
+	Message     string `json:"message"`
+	ProceedText string `json:"proceedText"`
+	// Inputs are the parameters asked by the step, none for a plain confirmation
+	Inputs []ParameterDefinition `json:"inputs"`
+}
+
+// Defaults returns the default values of the input parameters, as submitted by SubmitInput.
+// It fails for parameters which have no usable default, such as passwords.
+func (a *InputAction) Defaults() ([]ParameterValue, error) {
+	values := []ParameterValue{}
+	for _, p := range a.Inputs {
+		if p.Type == ParamPassword {
+			return nil, fmt.Errorf("%s is a password, which cannot be given here", p.Name)
+		}
+		value, err := p.Parse(p.Default())
+		if err != nil {
+			return nil, err
+		}
+		if p.Type == ParamBoolean {
+			values = append(values, ParameterValue{Name: p.Name, Value: value == "true"})
+		} else {
+			values = append(values, ParameterValue{Name: p.Name, Value: value})
+		}
+	}
+	return values, nil
+}
+
+// Parameter definition types handled by ParameterDefinition.Parse
+const (
+	ParamString   = "StringParameterDefinition"
+	ParamText     = "TextParameterDefinition"
+	ParamBoolean  = "BooleanParameterDefinition"
+	ParamChoice   = "ChoiceParameterDefinition"
+	ParamPassword = "PasswordParameterDefinition"
+)
+
+// ParameterDefinition is a parameter of a job
+type ParameterDefinition struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Choices     []string `json:"choices,omitempty"`
+	// DefaultParameterValue is nil for parameters without default, e.g. passwords
+	DefaultParameterValue *ParameterValue `json:"defaultParameterValue"`
+}
+
+// ParameterValue is the value of a parameter, a string or a bool depending on its type
+type ParameterValue struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// Default returns the default value of the parameter as sent by a build form
+func (p *ParameterDefinition) Default() string {
+	if p.DefaultParameterValue != nil && p.DefaultParameterValue.Value != nil {
+		return fmt.Sprint(p.DefaultParameterValue.Value)
+	}
+	// the first choice is the default, older Jenkins do not report it
+	if p.Type == ParamChoice && len(p.Choices) > 0 {
+		return p.Choices[0]
+	}
+	return ""
+}
+
+// Parse validates a value given for the parameter, returning it the way Jenkins expects it
+func (p *ParameterDefinition) Parse(value string) (string, error) {
+	switch p.Type {
+	case ParamString, ParamText, ParamPassword:
+		return value, nil
+	case ParamBoolean:
+		switch strings.ToLower(value) {
+		case "true", "yes", "y", "on", "1":
+			return "true", nil
+		case "false", "no", "n", "off", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%s must be true or false", p.Name)
+	case ParamChoice:
+		for _, c := range p.Choices {
+			if c == value {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s", p.Name, strings.Join(p.Choices, ", "))
+	}
+	return "", fmt.Errorf("%s is a %s, which cannot be given here", p.Name, strings.TrimSuffix(p.Type, "ParameterDefinition"))
+}
+
+// Client calls the Jenkins JSON API, authenticated with a user API token
+type Client struct {
+	URL   string
+	User  string
+	Token string
+	HTTP  *http.Client
+
+	mu    sync.Mutex
+	crumb *crumb
+}
+
+type crumb struct {
+	Field string `json:"crumbRequestField"`
+	Value string `json:"crumb"`
+}
+
+// New returns Client for Jenkins at given URL
+func New(url, user, token string) *Client {
+	return &Client{URL: strings.TrimSuffix(url, "/"), User: user, Token: token, HTTP: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// JobPath returns URL path of a job, folders being separated by slashes in name
+func JobPath(name string) string {
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for k, p := range parts {
+		parts[k] = "/job/" + url.PathEscape(p)
+	}
+	return strings.Join(parts, "")
+}
+
+// jobFields are the fields of Job requested to Jenkins
+const jobFields = "name,fullName,url,color,buildable,lastBuild[number,url],lastCompletedBuild[number,url]"
+
+func buildPath(job string, number int64) string {
+	if number <= 0 {
+		return JobPath(job) + "/lastBuild"
+	}
+	return JobPath(job) + "/" + strconv.FormatInt(number, 10)
+}
+
+// Jobs returns top level jobs
+func (c *Client) Jobs(ctx context.Context) ([]Job, error) {
+	var payload struct {
+		Jobs []Job `json:"jobs"`
+	}
+	err := c.getJSON(ctx, "/api/json?tree=jobs["+jobFields+"]", &payload)
+	return payload.Jobs, err
+}
+
+// Job returns a job by its full name
+func (c *Client) Job(ctx context.Context, name string) (*Job, error) {
+	var j Job
+	err := c.getJSON(ctx, JobPath(name)+"/api/json?tree="+jobFields, &j)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Parameters returns parameter definitions of a job, empty when it is not parameterized
+func (c *Client) Parameters(ctx context.Context, job string) ([]ParameterDefinition, error) {
+	var payload struct {
+		Property []struct {
+			ParameterDefinitions []ParameterDefinition `json:"parameterDefinitions"`
+		} `json:"property"`
+	}
+	err := c.getJSON(ctx, JobPath(job)+"/api/json?tree=property[parameterDefinitions[name,type,description,choices,defaultParameterValue[name,value]]]", &payload)
+	if err != nil {
+		return nil, err
+	}
+	params := []ParameterDefinition{}
+	for _, p := range payload.Property {
+		params = append(params, p.ParameterDefinitions...)
+	}
+	return params, nil
+}
+
+// Build returns a build of a job, the last one when number is 0
+func (c *Client) Build(ctx context.Context, job string, number int64) (*Build, error) {
+	var b Build
+	if err := c.getJSON(ctx, buildPath(job, number)+"/api/json", &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Trigger queues a build of a job, with parameters if any, and returns its queue item ID
+func (c *Client) Trigger(ctx context.Context, job string, params map[string]string) (int64, error) {
+	path := JobPath(job) + "/build"
+	var body io.Reader
+	if len(params) > 0 {
+		values := url.Values{}
+		for k, v := range params {
+			values.Set(k, v)
+		}
+		path = JobPath(job) + "/buildWithParameters"
+		body = strings.NewReader(values.Encode())
+	}
+
+	resp, err := c.post(ctx, path, body)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	// Location is the queue item, e.g. http://jenkins/queue/item/42/
+	location := strings.TrimSuffix(resp.Header.Get("Location"), "/")
+	id, err := strconv.ParseInt(location[strings.LastIndex(location, "/")+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("jenkins: unexpected queue location %q", resp.Header.Get("Location"))
+	}
+	return id, nil
+}
+
+// QueueItem returns a queued build, whose Executable is set once it started
+func (c *Client) QueueItem(ctx context.Context, id int64) (*QueueItem, error) {
+	var q QueueItem
+	if err := c.getJSON(ctx, "/queue/item/"+strconv.FormatInt(id, 10)+"/api/json", &q); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// Stages returns stages of a pipeline build, empty for other kinds of jobs
+func (c *Client) Stages(ctx context.Context, job string, number int64) ([]Stage, error) {
+	var payload struct {
+		Stages []Stage `json:"stages"`
+	}
+	err := c.getJSON(ctx, buildPath(job, number)+"/wfapi/describe", &payload)
+	if err == ErrNotFound {
+		// the build exists, checked by the caller, but is not a pipeline
+		return []Stage{}, nil
+	}
+	return payload.Stages, err
+}
+
+// ConsoleText returns the console output of a build, the last one when number is 0
+func (c *Client) ConsoleText(ctx context.Context, job string, number int64) (string, error) {
+	resp, err := c.get(ctx, buildPath(job, number)+"/consoleText")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
+
+// MaxChunkSize caps the console output returned by ProgressiveText, the rest following in the next chunks
+const MaxChunkSize = 1 << 20
+
+// LogChunk is a part of a console output
+type LogChunk struct {
+	Text string
+	// Next is the offset to request the following part from
+	Next int64
+	// More is true while the build is running, more output may follow
+	More bool
+}
+
+// ProgressiveText returns console output of a build from byte offset start, the last build when number is 0
+func (c *Client) ProgressiveText(ctx context.Context, job string, number, start int64) (*LogChunk, error) {
+	resp, err := c.get(ctx, buildPath(job, number)+"/logText/progressiveText?start="+strconv.FormatInt(start, 10))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxChunkSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MaxChunkSize {
+		return &LogChunk{Text: string(b[:MaxChunkSize]), Next: start + MaxChunkSize, More: true}, nil
+	}
+	next, err := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64)
+	if err != nil {
+		next = start + int64(len(b))
+	}
+	return &LogChunk{Text: string(b), Next: next, More: resp.Header.Get("X-More-Data") == "true"}, nil
+}
+
+// Abort stops a running build
+func (c *Client) Abort(ctx context.Context, job string, number int64) error {
+	resp, err := c.post(ctx, buildPath(job, number)+"/stop", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PendingInputs returns input steps a pipeline build is paused on, empty for other kinds of jobs
+func (c *Client) PendingInputs(ctx context.Context, job string, number int64) ([]InputAction, error) {
+	actions := []InputAction{}
+	err := c.getJSON(ctx, buildPath(job, number)+"/wfapi/pendingInputActions", &actions)
+	if err == ErrNotFound {
+		return []InputAction{}, nil
+	}
+	return actions, err
+}
+
+// SubmitInput proceeds an input step of a build with given parameter values
+func (c *Client) SubmitInput(ctx context.Context, job string, number int64, id string, values []ParameterValue) error {
+	path := inputPath(job, number, id) + "/proceedEmpty"
+	var body io.Reader
+	if len(values) > 0 {
+		data, err := json.Marshal(map[string]interface{}{"parameter": values})
+		if err != nil {
+			return err
+		}
+		path = inputPath(job, number, id) + "/submit"
+		body = strings.NewReader(url.Values{"json": {string(data)}, "proceed": {"Proceed"}}.Encode())
+	}
+	resp, err := c.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// AbortInput aborts an input step of a build, which aborts the build
+func (c *Client) AbortInput(ctx context.Context, job string, number int64, id string) error {
+	resp, err := c.post(ctx, inputPath(job, number, id)+"/abort", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func inputPath(job string, number int64, id string) string {
+	return buildPath(job, number) + "/input/" + url.PathEscape(id)
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}) error {
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	return c.do(ctx, "GET", path, nil)
+}
+
+// post sends a form, with the CSRF crumb when Jenkins issues one.
+// Crumbs expire with the session they were issued for, so a rejected form is sent once more with a new crumb.
+func (c *Client) post(ctx context.Context, path string, body io.Reader) (*http.Response, error) {
+	var form []byte
+	if body != nil {
+		var err error
+		if form, err = ioutil.ReadAll(body); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := c.postForm(ctx, path, form)
+	if err, ok := err.(*statusError); ok && err.Code == http.StatusForbidden {
+		c.resetCrumb()
+		return c.postForm(ctx, path, form)
+	}
+	return resp, err
+}
+
+func (c *Client) postForm(ctx context.Context, path string, form []byte) (*http.Response, error) {
+	cr, err := c.getCrumb(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.request(ctx, "POST", path, bytes.NewReader(form))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cr != nil {
+		req.Header.Set(cr.Field, cr.Value)
+	}
+	return c.send(req)
+}
+
+// resetCrumb forgets the crumb, so that the next form fetches a new one
+func (c *Client) resetCrumb() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.crumb = nil
+}
+
+// getCrumb fetches the CSRF crumb once, it is nil when CSRF protection is off
+func (c *Client) getCrumb(ctx context.Context) (*crumb, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.crumb != nil {
+		return c.crumb, nil
+	}
+	var cr crumb
+	err := c.getJSON(ctx, "/crumbIssuer/api/json", &cr)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.crumb = &cr
+	return c.crumb, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(req)
+}
+
+func (c *Client) request(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.URL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Token)
+	}
+	return req.WithContext(ctx), nil
+}
+
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, &statusError{Method: req.Method, Path: req.URL.Path, Code: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// statusError is an unsuccessful response other than ErrNotFound
+type statusError struct {
+	Method string
+	Path   string
+	Code   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("jenkins: %s %s returned %d", e.Method, e.Path, e.Code)
+}
@@ -0,0 +1,175 @@
+package jenkins_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins/jenkinstest"
+)
+
+func TestJobPath(t *testing.T) {
+	assert.Equal(t, "/job/deploy-web", jenkins.JobPath("deploy-web"))
+	assert.Equal(t, "/job/mobile/job/android%20release", jenkins.JobPath("mobile/android release"))
+}
+
+func TestJobStatus(t *testing.T) {
+	assert.Equal(t, "success", (&jenkins.Job{Color: "blue"}).Status())
+	assert.Equal(t, "failed, building", (&jenkins.Job{Color: "red_anime"}).Status())
+	assert.Equal(t, "unknown", (&jenkins.Job{Color: "purple"}).Status())
+}
+
+func TestParameterDefinition(t *testing.T) {
+	choice := jenkins.ParameterDefinition{Name: "ENV", Type: jenkins.ParamChoice, Choices: []string{"staging", "production"}}
+	assert.Equal(t, "staging", choice.Default())
+	v, err := choice.Parse("production")
+	assert.Nil(t, err)
+	assert.Equal(t, "production", v)
+	_, err = choice.Parse("qa")
+	assert.NotNil(t, err)
+
+	flag := jenkins.ParameterDefinition{Name: "DEBUG", Type: jenkins.ParamBoolean, DefaultParameterValue: &jenkins.ParameterValue{Value: true}}
+	assert.Equal(t, "true", flag.Default())
+	v, err = flag.Parse("No")
+	assert.Nil(t, err)
+	assert.Equal(t, "false", v)
+
+	file := jenkins.ParameterDefinition{Name: "FILE", Type: "FileParameterDefinition"}
+	assert.Equal(t, "", file.Default())
+	_, err = file.Parse("a.txt")
+	assert.NotNil(t, err)
+}
+
+func TestClient(t *testing.T) {
+	server := jenkinstest.NewServer()
+	defer server.Close()
+	server.AddJob("deploy-web")
+	server.AddJob("mobile/android")
+	c := jenkins.New(server.URL, "bot", "token")
+	ctx := context.Background()
+
+	jobs, err := c.Jobs(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, "not built", jobs[0].Status())
+
+	server.SetParameters("mobile/android", jenkins.ParameterDefinition{Name: "BRANCH", Type: jenkins.ParamString, DefaultParameterValue: &jenkins.ParameterValue{Name: "BRANCH", Value: "develop"}})
+	params, err := c.Parameters(ctx, "mobile/android")
+	assert.Nil(t, err)
+	if assert.Len(t, params, 1) {
+		assert.Equal(t, "develop", params[0].Default())
+	}
+	params, err = c.Parameters(ctx, "deploy-web")
+	assert.Nil(t, err)
+	assert.Empty(t, params)
+
+	_, err = c.Job(ctx, "missing")
+	assert.Equal(t, jenkins.ErrNotFound, err)
+	_, err = c.Build(ctx, "deploy-web", 0)
+	assert.Equal(t, jenkins.ErrNotFound, err)
+
+	id, err := c.Trigger(ctx, "mobile/android", map[string]string{"BRANCH": "master"})
+	assert.Nil(t, err)
+	q, err := c.QueueItem(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), q.Executable.Number)
+	assert.Equal(t, map[string]string{"BRANCH": "master"}, server.Params("mobile/android", 1))
+
+	b, err := c.Build(ctx, "mobile/android", 0)
+	assert.Nil(t, err)
+	assert.True(t, b.Building)
+
+	stages, err := c.Stages(ctx, "mobile/android", 1)
+	assert.Nil(t, err)
+	assert.Empty(t, stages)
+	server.SetStages("mobile/android", 1, jenkins.Stage{Name: "Build", Status: jenkins.StageInProgress})
+	stages, err = c.Stages(ctx, "mobile/android", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []jenkins.Stage{{Name: "Build", Status: jenkins.StageInProgress}}, stages)
+
+	server.Finish("mobile/android", 1, jenkins.ResultFailure, "npm ERR! missing script\n")
+	b, err = c.Build(ctx, "mobile/android", 1)
+	assert.Nil(t, err)
+	assert.False(t, b.Building)
+	assert.Equal(t, jenkins.ResultFailure, b.Result)
+
+	text, err := c.ConsoleText(ctx, "mobile/android", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Started by remote API\nnpm ERR! missing script\n", text)
+
+	chunk, err := c.ProgressiveText(ctx, "mobile/android", 1, 8)
+	assert.Nil(t, err)
+	assert.Equal(t, &jenkins.LogChunk{Text: "by remote API\nnpm ERR! missing script\n", Next: int64(len(text))}, chunk)
+
+	server.ExpireCrumb()
+	_, err = c.Trigger(ctx, "deploy-web", nil)
+	assert.Nil(t, err, "forms are sent again with a new crumb")
+	assert.Nil(t, c.Abort(ctx, "deploy-web", 1))
+	b, _ = c.Build(ctx, "deploy-web", 1)
+	assert.Equal(t, jenkins.ResultAborted, b.Result)
+
+	// long console outputs are returned in bounded chunks
+	_, err = c.Trigger(ctx, "deploy-web", nil)
+	assert.Nil(t, err)
+	server.Finish("deploy-web", 2, jenkins.ResultSuccess, strings.Repeat("x", jenkins.MaxChunkSize)+"end\n")
+	chunk, err = c.ProgressiveText(ctx, "deploy-web", 2, 0)
+	assert.Nil(t, err)
+	assert.Len(t, chunk.Text, jenkins.MaxChunkSize)
+	assert.Equal(t, int64(jenkins.MaxChunkSize), chunk.Next)
+	assert.True(t, chunk.More, "the rest of a finished build is still to be read")
+	chunk, err = c.ProgressiveText(ctx, "deploy-web", 2, chunk.Next)
+	assert.Nil(t, err)
+	assert.False(t, chunk.More)
+	assert.True(t, strings.HasSuffix(chunk.Text, "end\n"), chunk.Text)
+}
+
+func TestInputs(t *testing.T) {
+	server := jenkinstest.NewServer()
+	defer server.Close()
+	server.AddJob("deploy-web")
+	c := jenkins.New(server.URL, "bot", "token")
+	ctx := context.Background()
+
+	for k := 0; k < 3; k++ {
+		_, err := c.Trigger(ctx, "deploy-web", nil)
+		assert.Nil(t, err)
+	}
+	inputs, err := c.PendingInputs(ctx, "deploy-web", 1)
+	assert.Nil(t, err)
+	assert.Empty(t, inputs)
+
+	target := jenkins.ParameterDefinition{Name: "TARGET", Type: jenkins.ParamChoice, Choices: []string{"canary", "all"}}
+	dryRun := jenkins.ParameterDefinition{Name: "DRY_RUN", Type: jenkins.ParamBoolean, DefaultParameterValue: &jenkins.ParameterValue{Value: false}}
+	server.SetInput("deploy-web", 1, jenkins.InputAction{ID: "Release", Message: "Release to production?", ProceedText: "Release", Inputs: []jenkins.ParameterDefinition{target, dryRun}})
+	server.SetInput("deploy-web", 2, jenkins.InputAction{ID: "Confirm", Message: "Go on?"})
+	server.SetInput("deploy-web", 3, jenkins.InputAction{ID: "Confirm", Message: "Go on?"})
+
+	inputs, err = c.PendingInputs(ctx, "deploy-web", 1)
+	assert.Nil(t, err)
+	if assert.Len(t, inputs, 1) {
+		assert.Equal(t, "Release to production?", inputs[0].Message)
+		values, err := inputs[0].Defaults()
+		assert.Nil(t, err)
+		assert.Nil(t, c.SubmitInput(ctx, "deploy-web", 1, inputs[0].ID, values))
+	}
+	answer, ok := server.Answer("deploy-web", 1, "Release")
+	assert.True(t, ok)
+	assert.Equal(t, jenkinstest.Answer{Proceeded: true, Values: []jenkins.ParameterValue{{Name: "TARGET", Value: "canary"}, {Name: "DRY_RUN", Value: false}}}, answer)
+	inputs, _ = c.PendingInputs(ctx, "deploy-web", 1)
+	assert.Empty(t, inputs)
+
+	assert.Nil(t, c.SubmitInput(ctx, "deploy-web", 2, "Confirm", nil))
+	answer, _ = server.Answer("deploy-web", 2, "Confirm")
+	assert.Equal(t, jenkinstest.Answer{Proceeded: true}, answer)
+
+	assert.Nil(t, c.AbortInput(ctx, "deploy-web", 3, "Confirm"))
+	b, _ := c.Build(ctx, "deploy-web", 3)
+	assert.Equal(t, jenkins.ResultAborted, b.Result)
+	assert.Equal(t, jenkins.ErrNotFound, c.AbortInput(ctx, "deploy-web", 3, "Confirm"))
+
+	_, err = (&jenkins.InputAction{Inputs: []jenkins.ParameterDefinition{{Name: "TOKEN", Type: jenkins.ParamPassword}}}).Defaults()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,428 @@
+// Package jenkinstest provides a fake Jenkins JSON API for tests
+package jenkinstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+)
+
+// Crumb is the CSRF crumb required on POST requests, until ExpireCrumb is called
+const Crumb = "fake-crumb"
+
+// Server is an in-memory Jenkins. Triggered builds start at once, unless the queue is held, and run until finished with Finish or Abort.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	order  []string
+	queue  map[int64]*jenkins.QueueItem
+	nextID int64
+	// held are queued builds waiting for Release
+	held []*queued
+	hold bool
+	// crumb is the current CSRF crumb
+	crumb string
+}
+
+type job struct {
+	jenkins.Job
+	builds []*build
+	params []jenkins.ParameterDefinition
+}
+
+type queued struct {
+	id     int64
+	job    *job
+	params map[string]string
+}
+
+type build struct {
+	jenkins.Build
+	Params  map[string]string
+	Console string
+	// Stages is nil for builds which are not pipelines
+	Stages []jenkins.Stage
+	// Inputs are the input steps the build is paused on
+	Inputs []jenkins.InputAction
+	// Answers are the inputs submitted or aborted, by ID
+	Answers map[string]Answer
+}
+
+// Answer is the way an input step was answered
+type Answer struct {
+	// Proceeded is false for an aborted input
+	Proceeded bool
+	Values    []jenkins.ParameterValue
+}
+
+// NewServer starts an empty Server
+func NewServer() *Server {
+	s := &Server{jobs: map[string]*job{}, queue: map[int64]*jenkins.QueueItem{}, nextID: 1, crumb: Crumb}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// AddJob creates a buildable job
+func (s *Server) AddJob(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[name] = &job{Job: jenkins.Job{Name: name[strings.LastIndex(name, "/")+1:], FullName: name, Color: "notbuilt", Buildable: true}}
+	s.order = append(s.order, name)
+}
+
+// SetParameters replaces parameter definitions of a job
+func (s *Server) SetParameters(name string, params ...jenkins.ParameterDefinition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[name].params = params
+}
+
+// Hold keeps triggered builds in the queue until Release
+func (s *Server) Hold() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hold = true
+}
+
+// Release starts builds held in the queue and stops holding new ones
+func (s *Server) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hold = false
+	for _, q := range s.held {
+		s.start(q)
+	}
+	s.held = nil
+}
+
+// Cancel removes a held build from the queue
+func (s *Server) Cancel(queueID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, q := range s.held {
+		if q.id == queueID {
+			s.held = append(s.held[:k], s.held[k+1:]...)
+			s.queue[queueID] = &jenkins.QueueItem{ID: queueID, Cancelled: true}
+			return
+		}
+	}
+}
+
+// Finish ends a running build with given result and console output appended
+func (s *Server) Finish(name string, number int64, result, console string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.build(name, number)
+	b.Building = false
+	b.Result = result
+	b.Duration = time.Since(b.StartedAt()).Nanoseconds() / int64(time.Millisecond)
+	b.Console += console
+	complete(s.jobs[name], b)
+}
+
+// SetStages makes a build a pipeline with given stages
+func (s *Server) SetStages(name string, number int64, stages ...jenkins.Stage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.build(name, number).Stages = stages
+}
+
+// SetInput pauses a pipeline build on an input step
+func (s *Server) SetInput(name string, number int64, action jenkins.InputAction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.build(name, number)
+	if b.Stages == nil {
+		b.Stages = []jenkins.Stage{}
+	}
+	b.Inputs = append(b.Inputs, action)
+}
+
+// Answer returns how an input step of a build was answered, ok is false while it was not
+func (s *Server) Answer(name string, number int64, id string) (a Answer, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok = s.build(name, number).Answers[id]
+	return a, ok
+}
+
+// AppendConsole adds output to a build
+func (s *Server) AppendConsole(name string, number int64, console string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.build(name, number).Console += console
+}
+
+// Params returns parameters a build was triggered with
+func (s *Server) Params(name string, number int64) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.build(name, number).Params
+}
+
+// LastBuild returns the last build of a job, nil when never built
+func (s *Server) LastBuild(name string) *jenkins.Build {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := s.jobs[name]
+	if j == nil || len(j.builds) == 0 {
+		return nil
+	}
+	b := j.builds[len(j.builds)-1].Build
+	return &b
+}
+
+func (s *Server) build(name string, number int64) *build {
+	j := s.jobs[name]
+	if j == nil {
+		return nil
+	}
+	if number <= 0 && len(j.builds) > 0 {
+		return j.builds[len(j.builds)-1]
+	}
+	if number <= 0 || number > int64(len(j.builds)) {
+		return nil
+	}
+	return j.builds[number-1]
+}
+
+// complete updates a job once its build ended, Jenkins reporting the highest numbered one as last completed
+func complete(j *job, b *build) {
+	j.Color = color(b.Result)
+	if j.LastCompletedBuild == nil || j.LastCompletedBuild.Number < b.Number {
+		j.LastCompletedBuild = &jenkins.BuildLink{Number: b.Number, URL: b.URL}
+	}
+}
+
+// ExpireCrumb issues a new CSRF crumb, as Jenkins does when the session of the previous one ends
+func (s *Server) ExpireCrumb() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crumb += "-renewed"
+}
+
+func color(result string) string {
+	switch result {
+	case jenkins.ResultSuccess:
+		return "blue"
+	case jenkins.ResultUnstable:
+		return "yellow"
+	case jenkins.ResultAborted:
+		return "aborted"
+	}
+	return "red"
+}
+
+// ServeHTTP implements the subset of the Jenkins API used by jenkins.Client
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method == "POST" && r.Header.Get("Jenkins-Crumb") != s.crumb {
+		http.Error(w, "No valid crumb was included in the request", http.StatusForbidden)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/crumbIssuer/api/json":
+		writeJSON(w, map[string]string{"crumbRequestField": "Jenkins-Crumb", "crumb": s.crumb})
+		return
+	case path == "/api/json":
+		jobs := []jenkins.Job{}
+		for _, name := range s.order {
+			jobs = append(jobs, s.jobs[name].Job)
+		}
+		writeJSON(w, map[string]interface{}{"jobs": jobs})
+		return
+	case strings.HasPrefix(path, "/queue/item/"):
+		id, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(path, "/queue/item/"), "/api/json"), 10, 64)
+		if q, ok := s.queue[id]; ok {
+			writeJSON(w, q)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	name, rest := splitJob(path)
+	j := s.jobs[name]
+	if j == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case rest == "/api/json":
+		writeJSON(w, struct {
+			jenkins.Job
+			Property []map[string]interface{} `json:"property"`
+		}{j.Job, []map[string]interface{}{{"parameterDefinitions": j.params}}})
+	case r.Method == "POST" && (rest == "/build" || rest == "/buildWithParameters"):
+		r.ParseForm()
+		s.trigger(w, j, r.PostForm)
+	default:
+		parts := strings.SplitN(strings.TrimPrefix(rest, "/"), "/", 2)
+		number, _ := strconv.ParseInt(parts[0], 10, 64)
+		b := s.build(name, number)
+		if b == nil || (number == 0 && parts[0] != "lastBuild") || len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		switch {
+		case parts[1] == "api/json":
+			writeJSON(w, b.Build)
+		case parts[1] == "wfapi/describe" && b.Stages != nil:
+			writeJSON(w, map[string]interface{}{"id": strconv.FormatInt(b.Number, 10), "stages": b.Stages})
+		case parts[1] == "wfapi/pendingInputActions" && b.Stages != nil:
+			inputs := b.Inputs
+			if inputs == nil {
+				inputs = []jenkins.InputAction{}
+			}
+			writeJSON(w, inputs)
+		case strings.HasPrefix(parts[1], "input/") && r.Method == "POST":
+			s.answer(w, r, j, b, strings.TrimPrefix(parts[1], "input/"))
+		case parts[1] == "logText/progressiveText":
+			start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+			if start > len(b.Console) {
+				start = len(b.Console)
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("X-Text-Size", strconv.Itoa(len(b.Console)))
+			if b.Building {
+				w.Header().Set("X-More-Data", "true")
+			}
+			w.Write([]byte(b.Console[start:]))
+		case parts[1] == "consoleText":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(b.Console))
+		case parts[1] == "stop" && r.Method == "POST":
+			if b.Building {
+				b.Building, b.Result = false, jenkins.ResultAborted
+				b.Console += "Aborted by user\n"
+				complete(j, b)
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// answer proceeds or aborts an input step, path being <id>/proceedEmpty, <id>/submit or <id>/abort
+func (s *Server) answer(w http.ResponseWriter, r *http.Request, j *job, b *build, path string) {
+	k := strings.LastIndex(path, "/")
+	if k < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	id, _ := url.PathUnescape(path[:k])
+	pending := -1
+	for n, a := range b.Inputs {
+		if a.ID == id {
+			pending = n
+		}
+	}
+	if pending < 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	a := Answer{}
+	switch path[k+1:] {
+	case "proceedEmpty":
+		a.Proceeded = true
+	case "submit":
+		var form struct {
+			Parameter []jenkins.ParameterValue `json:"parameter"`
+		}
+		r.ParseForm()
+		if err := json.Unmarshal([]byte(r.PostForm.Get("json")), &form); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.Proceeded, a.Values = true, form.Parameter
+	case "abort":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	b.Inputs = append(b.Inputs[:pending], b.Inputs[pending+1:]...)
+	if b.Answers == nil {
+		b.Answers = map[string]Answer{}
+	}
+	b.Answers[id] = a
+	if !a.Proceeded && b.Building {
+		b.Building, b.Result = false, jenkins.ResultAborted
+		b.Console += "Rejected by user\n"
+		complete(j, b)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// trigger queues a build and starts it at once unless the queue is held
+func (s *Server) trigger(w http.ResponseWriter, j *job, form url.Values) {
+	q := &queued{id: s.nextID, job: j, params: map[string]string{}}
+	s.nextID++
+	for k := range form {
+		q.params[k] = form.Get(k)
+	}
+	s.queue[q.id] = &jenkins.QueueItem{ID: q.id, Why: "Waiting for next available executor"}
+	if s.hold {
+		s.held = append(s.held, q)
+	} else {
+		s.start(q)
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/queue/item/%d/", s.URL, q.id))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// start runs a queued build
+func (s *Server) start(q *queued) {
+	j := q.job
+	number := int64(len(j.builds) + 1)
+	b := &build{Build: jenkins.Build{
+		Number:            number,
+		URL:               fmt.Sprintf("%s%s/%d/", s.URL, jenkins.JobPath(j.FullName), number),
+		Building:          true,
+		Timestamp:         time.Now().UnixNano() / int64(time.Millisecond),
+		EstimatedDuration: 60000,
+	}, Params: q.params, Console: "Started by remote API\n"}
+	j.builds = append(j.builds, b)
+	j.LastBuild = &jenkins.BuildLink{Number: number, URL: b.URL}
+	j.Color = strings.TrimSuffix(j.Color, "_anime") + "_anime"
+
+	s.queue[q.id] = &jenkins.QueueItem{ID: q.id, Executable: &jenkins.BuildLink{Number: number, URL: b.URL}}
+}
+
+// splitJob splits /job/a/job/b/rest into job full name a/b and /rest
+func splitJob(path string) (string, string) {
+	names := []string{}
+	for strings.HasPrefix(path, "/job/") {
+		path = strings.TrimPrefix(path, "/job/")
+		end := strings.Index(path, "/")
+		if end < 0 {
+			end = len(path)
+		}
+		name, _ := url.PathUnescape(path[:end])
+		names = append(names, name)
+		path = path[end:]
+	}
+	return strings.Join(names, "/"), path
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
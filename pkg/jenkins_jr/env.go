@@ -0,0 +1,45 @@
+package jenkins_jr
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+)
+
+// JenkinsClient is the subset of the Jenkins API used by the bot, implemented by jenkins.Client
+type JenkinsClient interface {
+	// Jobs returns top level jobs
+	Jobs(ctx context.Context) ([]jenkins.Job, error)
+	// Job returns a job by its full name
+	Job(ctx context.Context, name string) (*jenkins.Job, error)
+	// Parameters returns parameter definitions of a job
+	Parameters(ctx context.Context, job string) ([]jenkins.ParameterDefinition, error)
+	// Build returns a build of a job, the last one when number is 0
+	Build(ctx context.Context, job string, number int64) (*jenkins.Build, error)
+	// Stages returns stages of a pipeline build, empty for other kinds of jobs
+	Stages(ctx context.Context, job string, number int64) ([]jenkins.Stage, error)
+	// Trigger queues a build of a job and returns its queue item ID
+	Trigger(ctx context.Context, job string, params map[string]string) (int64, error)
+	// QueueItem returns a queued build
+	QueueItem(ctx context.Context, id int64) (*jenkins.QueueItem, error)
+	// ConsoleText returns the console output of a build, the last one when number is 0
+	ConsoleText(ctx context.Context, job string, number int64) (string, error)
+	// ProgressiveText returns console output of a build from byte offset start
+	ProgressiveText(ctx context.Context, job string, number, start int64) (*jenkins.LogChunk, error)
+	// Abort stops a running build
+	Abort(ctx context.Context, job string, number int64) error
+	// PendingInputs returns input steps a pipeline build is paused on
+	PendingInputs(ctx context.Context, job string, number int64) ([]jenkins.InputAction, error)
+	// SubmitInput proceeds an input step of a build
+	SubmitInput(ctx context.Context, job string, number int64, id string, values []jenkins.ParameterValue) error
+	// AbortInput aborts an input step of a build, and so the build
+	AbortInput(ctx context.Context, job string, number int64, id string) error
+}
+
+// Env holds dependencies shared by the HTTP API and the bot
+type Env struct {
+	DB      *sqlx.DB
+	Jenkins JenkinsClient
+}
@@ -0,0 +1,95 @@
+package mysql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/approval"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+	"github.com/wiskarindra/jenkins_jr/pkg/watch"
+)
+
+// zones are local time zones on both sides of UTC, where a datetime read in another zone than written is off by hours
+var zones = []*time.Location{time.FixedZone("WIB", 7*3600), time.FixedZone("EST", -5*3600)}
+
+// inZones runs fn against a database connected in each of zones
+func inZones(t *testing.T, fn func(db *sqlx.DB)) {
+	local := time.Local
+	defer func() { time.Local = local }()
+	for _, zone := range zones {
+		time.Local = zone
+		fn(mysql.Init())
+	}
+}
+
+func TestApprovalExpiresAt(t *testing.T) {
+	ctx := context.Background()
+	inZones(t, func(db *sqlx.DB) {
+		store := &approval.MySQLStore{DB: db}
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		r := &approval.Request{ChatID: 1, MessageID: 2, Job: "deploy-production", Params: "{}", Title: "deploy-production", RequestedBy: 8, RequesterName: "@user8", Approvers: 1, ExpiresAt: expiresAt}
+		assert.Nil(t, store.Create(ctx, r))
+
+		saved, err := store.Get(ctx, r.ID)
+		assert.Nil(t, err)
+		if assert.NotNil(t, saved) {
+			assert.True(t, expiresAt.Equal(saved.ExpiresAt), "%s read back as %s in %s", expiresAt, saved.ExpiresAt, time.Local)
+			assert.False(t, time.Now().After(saved.ExpiresAt))
+		}
+	})
+}
+
+func TestWebhookAt(t *testing.T) {
+	ctx := context.Background()
+	inZones(t, func(db *sqlx.DB) {
+		store := &subscription.MySQLStore{DB: db}
+		job := "webhook-" + time.Local.String()
+		assert.Nil(t, store.SaveJobState(ctx, &subscription.JobState{Job: job, BuildNumber: 1, Result: "SUCCESS"}))
+		at := time.Now().Truncate(time.Second)
+		assert.Nil(t, store.MarkWebhook(ctx, job, at))
+
+		st, err := store.JobState(ctx, job)
+		assert.Nil(t, err)
+		if assert.NotNil(t, st) && assert.NotNil(t, st.WebhookAt) {
+			assert.True(t, at.Equal(*st.WebhookAt), "%s read back as %s in %s", at, *st.WebhookAt, time.Local)
+			// the fallback poller skips the job for WebhookTTL only
+			assert.True(t, time.Since(*st.WebhookAt) >= 0)
+		}
+	})
+}
+
+func TestWatchExpiresAt(t *testing.T) {
+	ctx := context.Background()
+	inZones(t, func(db *sqlx.DB) {
+		store := &watch.MySQLStore{DB: db}
+		running := &watch.Watch{ChatID: 1, MessageID: 2, Job: "deploy", Title: "deploy", QueueID: 3, ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second)}
+		expired := &watch.Watch{ChatID: 1, MessageID: 3, Job: "deploy", Title: "deploy", QueueID: 4, ExpiresAt: time.Now().Add(-time.Minute).Truncate(time.Second)}
+		assert.Nil(t, store.Create(ctx, running))
+		assert.Nil(t, store.Create(ctx, expired))
+
+		watches, err := store.Active(ctx)
+		assert.Nil(t, err)
+		for _, w := range watches {
+			switch w.ID {
+			case running.ID:
+				assert.True(t, running.ExpiresAt.Equal(w.ExpiresAt), "%s read back as %s in %s", running.ExpiresAt, w.ExpiresAt, time.Local)
+				assert.False(t, time.Now().After(w.ExpiresAt), "watches are not stopped early")
+			case expired.ID:
+				assert.True(t, time.Now().After(w.ExpiresAt), "watches are stopped once expired")
+			}
+		}
+
+		// finished, so that they are not checked again by other tests
+		now := time.Now()
+		for _, w := range []*watch.Watch{running, expired} {
+			result := watch.ResultTimeout
+			w.Result, w.FinishedAt = &result, &now
+			assert.Nil(t, store.Save(ctx, w))
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -23,10 +24,12 @@ func Init() *sqlx.DB {
 
 	env := os.Getenv("ENV")
 	if env == "development" || env == "staging" {
-		fmt.Println(fmt.Sprintf("Connecting to [USERNAME]:[PASSWORD]@(%s:%v)/%s?parseTime=true", dbHost, dbPort, dbName))
+		fmt.Println(fmt.Sprintf("Connecting to [USERNAME]:[PASSWORD]@(%s:%v)/%s?parseTime=true&loc=Local", dbHost, dbPort, dbName))
 	}
 
-	dataSourceName := fmt.Sprintf("%s:%v@(%s:%v)/%s?parseTime=true", dbUsername, dbPassword, dbHost, dbPort, dbName)
+	// datetimes are written in local time, formatted with config.DatabaseDatetimeFormat,
+	// so they are read in local time too, and time.Time arguments are sent in local time
+	dataSourceName := fmt.Sprintf("%s:%v@(%s:%v)/%s?parseTime=true&loc=Local", dbUsername, dbPassword, dbHost, dbPort, dbName)
 	db, _ := sqlx.Open("mysql", dataSourceName)
 	if err := db.Ping(); err != nil {
 		// https://stackoverflow.com/questions/32345124/why-does-sql-open-return-nil-as-error-when-it-should-not
@@ -40,3 +43,16 @@ func Init() *sqlx.DB {
 	db.SetMaxOpenConns(500)
 	return db
 }
+
+// Transaction runs fn inside a transaction, rolling back when fn returns an error
+func Transaction(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,236 @@
+package post
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+	"github.com/wiskarindra/jenkins_jr/pkg/conditional"
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/request"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+// Handler serves post endpoints
+type Handler struct {
+	DB *sqlx.DB
+	// Cache holds public listings for CacheTTL, nil disables caching
+	Cache    *cache.Cache
+	CacheTTL time.Duration
+}
+
+func (h *Handler) fail(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch err {
+	case ErrNotFound:
+		response.Fail(w, http.StatusNotFound, err.Error())
+	case ErrInvalidCursor:
+		response.Fail(w, http.StatusBadRequest, err.Error())
+	case audit.ErrNotCreated, ErrDeleted, ErrNotDeleted:
+		response.Fail(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		log.ErrLog(ctx, err, "post", message)
+		response.Fail(w, http.StatusInternalServerError, message)
+	}
+}
+
+// invalidate drops cached listings affected by a post change, stale ones left behind expire with CacheTTL
+func (h *Handler) invalidate(ctx context.Context, id int64) {
+	if err := Invalidate(ctx, h.DB, h.Cache, id); err != nil {
+		log.ErrLog(ctx, err, "post", "Failed to invalidate cached listings")
+	}
+}
+
+// parseAt reads the at query parameter formatted with config.DateRangeSearchFormat
+func parseAt(r *http.Request) (time.Time, error) {
+	return time.Parse(config.DateRangeSearchFormat, r.URL.Query().Get("at"))
+}
+
+// Version returns a post as it was at the time given by at parameter
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	at, err := parseAt(r)
+	if err != nil {
+		response.Fail(w, http.StatusBadRequest, "at must be formatted as "+config.DateRangeSearchFormat)
+		return
+	}
+
+	p, err := At(ctx, h.DB, request.ID(ps.ByName("id")), at)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to reconstruct post")
+		return
+	}
+	response.OK(w, p, response.Meta{})
+}
+
+// Revert restores a post to its version at the time given by at parameter
+func (h *Handler) Revert(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	at, err := parseAt(r)
+	if err != nil {
+		response.Fail(w, http.StatusBadRequest, "at must be formatted as "+config.DateRangeSearchFormat)
+		return
+	}
+
+	p, err := Revert(ctx, h.DB, request.ID(ps.ByName("id")), at)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to revert post")
+		return
+	}
+	h.invalidate(ctx, p.ID)
+	log.InfoLog(ctx, fmt.Sprintf("reverted post %d to %s", p.ID, at.Format(config.DateRangeSearchFormat)), "post", "revert")
+	response.OK(w, p, response.Meta{})
+}
+
+// Delete moves a post to trash
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	p, err := Trash(ctx, h.DB, request.ID(ps.ByName("id")))
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to delete post")
+		return
+	}
+	h.invalidate(ctx, p.ID)
+	log.InfoLog(ctx, fmt.Sprintf("deleted post %d", p.ID), "post", "delete")
+	response.OK(w, p, response.Meta{})
+}
+
+// Restore brings a post back from trash
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	p, err := Restore(ctx, h.DB, request.ID(ps.ByName("id")))
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to restore post")
+		return
+	}
+	h.invalidate(ctx, p.ID)
+	log.InfoLog(ctx, fmt.Sprintf("restored post %d", p.ID), "post", "restore")
+	response.OK(w, p, response.Meta{})
+}
+
+// Show returns a published post with its images
+func (h *Handler) Show(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	p, err := Find(ctx, h.DB, request.ID(ps.ByName("id")))
+	if err == nil && (p.Deleted || !p.Published) {
+		err = ErrNotFound
+	}
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to get post")
+		return
+	}
+	posts := []Post{*p}
+	if err := LoadImages(ctx, h.DB, posts); err != nil {
+		h.fail(ctx, w, err, "Failed to get post images")
+		return
+	}
+	v, err := Validator(ctx, h.DB, posts)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to get post")
+		return
+	}
+	if conditional.NotModified(w, r, v) {
+		return
+	}
+	response.OK(w, posts[0], response.Meta{})
+}
+
+// List returns a page of published posts, most recently published first.
+// It can be filtered by influencer_id and comma separated categories, and is paginated with the cursor given in meta.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	limit, _ := request.Page(r)
+	l := Listing{
+		Cursor:       q.Get("cursor"),
+		Categories:   request.IDs(q.Get("categories")),
+		InfluencerID: request.ID(q.Get("influencer_id")),
+		Limit:        limit,
+	}
+
+	var page *Page
+	var err error
+	if h.Cache == nil {
+		page, err = ListPublished(ctx, h.DB, l)
+	} else {
+		var v interface{}
+		v, err = h.Cache.Fetch(ctx, l.Key(), h.CacheTTL, func(ctx context.Context) (interface{}, []string, error) {
+			page, err := ListPublished(ctx, h.DB, l)
+			if err != nil {
+				return nil, nil, err
+			}
+			return page, l.Tags(page.Posts), nil
+		})
+		if err == nil {
+			page = v.(*Page)
+		}
+	}
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to list posts")
+		return
+	}
+	if conditional.NotModified(w, r, page.Validator) {
+		return
+	}
+	response.OK(w, page.Posts, response.Meta{Limit: limit, NextCursor: page.NextCursor})
+}
+
+// Like makes the current user like a post
+func (h *Handler) Like(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.like(w, r, ps, true)
+}
+
+// Unlike withdraws the like of the current user on a post
+func (h *Handler) Unlike(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.like(w, r, ps, false)
+}
+
+func (h *Handler) like(w http.ResponseWriter, r *http.Request, ps httprouter.Params, liked bool) {
+	ctx := r.Context()
+	user := currentuser.FromContext(ctx)
+	if user.ID == 0 {
+		response.Fail(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	p, err := Like(ctx, h.DB, request.ID(ps.ByName("id")), user.ID, liked)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to like post")
+		return
+	}
+	// only pages showing the post display its like count
+	if h.Cache != nil {
+		h.Cache.Invalidate(Tag(p.ID))
+	}
+	response.OK(w, p, response.Meta{})
+}
+
+// Trash lists deleted posts, most recently deleted first
+func (h *Handler) Trash(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	limit, offset := request.Page(r)
+
+	posts, err := ListTrash(ctx, h.DB, limit, offset)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to list deleted posts")
+		return
+	}
+	total, err := CountTrash(ctx, h.DB)
+	if err != nil {
+		h.fail(ctx, w, err, "Failed to count deleted posts")
+		return
+	}
+	response.OK(w, posts, response.Meta{Limit: limit, Offset: offset, Total: total})
+}
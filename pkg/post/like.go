@@ -0,0 +1,60 @@
+package post
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+)
+
+// Like sets whether a Bukalapak user likes a published post, keeping like_count in sync.
+// The post updated_at is bumped as well, since like_count is part of its representation and of its exported row.
+// Liking twice, or unliking a post not liked, changes nothing.
+func Like(ctx context.Context, db *sqlx.DB, postID, userID int64, liked bool) (*Post, error) {
+	var p *Post
+	err := mysql.Transaction(ctx, db, func(tx *sqlx.Tx) error {
+		var err error
+		p, err = findForUpdate(ctx, tx, postID)
+		if err != nil {
+			return err
+		}
+		if p.Deleted || !p.Published {
+			return ErrNotFound
+		}
+
+		var current bool
+		err = tx.GetContext(ctx, &current, "SELECT liked FROM post_likes WHERE post_id = ? AND bukalapak_user_id = ? FOR UPDATE", postID, userID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if current == liked {
+			return nil
+		}
+
+		at := time.Now()
+		now := at.Format(config.DatabaseDatetimeFormat)
+		_, err = tx.ExecContext(ctx, "INSERT INTO post_likes (post_id, bukalapak_user_id, liked, created_at, updated_at) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE liked = VALUES(liked), updated_at = VALUES(updated_at)",
+			postID, userID, liked, now, now)
+		if err != nil {
+			return err
+		}
+
+		delta := 1
+		if !liked {
+			delta = -1
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET like_count = GREATEST(like_count + ?, 0), updated_at = ? WHERE id = ?", delta, now, postID); err != nil {
+			return err
+		}
+		p.UpdatedAt = at
+		if p.LikeCount += delta; p.LikeCount < 0 {
+			p.LikeCount = 0
+		}
+		return nil
+	})
+	return p, err
+}
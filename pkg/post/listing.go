@@ -0,0 +1,267 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+	"github.com/wiskarindra/jenkins_jr/pkg/conditional"
+)
+
+// ErrInvalidCursor is returned when a listing cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cache tags of public listings
+const (
+	// TagAllPosts tags listings not filtered by influencer nor category
+	TagAllPosts = "posts"
+)
+
+// Tag returns cache tag of listings showing a post
+func Tag(id int64) string {
+	return "post:" + strconv.FormatInt(id, 10)
+}
+
+// InfluencerTag returns cache tag of listings showing posts of an influencer, or filtered by it
+func InfluencerTag(id int64) string {
+	return "influencer:" + strconv.FormatInt(id, 10)
+}
+
+// CategoryTag returns cache tag of listings filtered by a category
+func CategoryTag(id int64) string {
+	return "category:" + strconv.FormatInt(id, 10)
+}
+
+// Listing is a request for a page of published posts, newest first
+type Listing struct {
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor       string
+	Categories   []int64
+	InfluencerID int64
+	Limit        int
+}
+
+// Page is a page of published posts
+type Page struct {
+	Posts      []Post
+	NextCursor string
+	Validator  conditional.Validator
+}
+
+// normalize sorts and dedups categories so that equivalent listings share their cache key
+func (l Listing) normalize() Listing {
+	seen := map[int64]bool{}
+	categories := []int64{}
+	for _, c := range l.Categories {
+		if !seen[c] {
+			seen[c] = true
+			categories = append(categories, c)
+		}
+	}
+	sort.Slice(categories, func(a, b int) bool { return categories[a] < categories[b] })
+	l.Categories = categories
+	return l
+}
+
+// Key returns cache key of a listing
+func (l Listing) Key() string {
+	l = l.normalize()
+	categories := make([]string, len(l.Categories))
+	for k, c := range l.Categories {
+		categories[k] = strconv.FormatInt(c, 10)
+	}
+	return fmt.Sprintf("posts?cursor=%s&categories=%s&influencer_id=%d&limit=%d", l.Cursor, strings.Join(categories, ","), l.InfluencerID, l.Limit)
+}
+
+// Tags returns cache tags of a listing page: its filters, and the posts and influencers it shows
+func (l Listing) Tags(posts []Post) []string {
+	tags := []string{}
+	if l.InfluencerID > 0 {
+		tags = append(tags, InfluencerTag(l.InfluencerID))
+	}
+	for _, c := range l.Categories {
+		tags = append(tags, CategoryTag(c))
+	}
+	if len(tags) == 0 {
+		tags = append(tags, TagAllPosts)
+	}
+	for _, p := range posts {
+		tags = append(tags, Tag(p.ID))
+		if p.InfluencerID > 0 {
+			tags = append(tags, InfluencerTag(p.InfluencerID))
+		}
+	}
+	return tags
+}
+
+// Tags returns cache tags of listings affected by a change of p, which belongs to given categories
+func Tags(p *Post, categories []int64) []string {
+	tags := []string{TagAllPosts, Tag(p.ID)}
+	if p.InfluencerID > 0 {
+		tags = append(tags, InfluencerTag(p.InfluencerID))
+	}
+	for _, c := range categories {
+		tags = append(tags, CategoryTag(c))
+	}
+	return tags
+}
+
+// EncodeCursor returns the cursor of the page following p
+func EncodeCursor(p Post) string {
+	var at int64
+	if p.LastPublishedAt != nil {
+		at = p.LastPublishedAt.Unix()
+	}
+	return fmt.Sprintf("%d_%d", at, p.ID)
+}
+
+// DecodeCursor returns publication time and ID of the last post of the previous page
+func DecodeCursor(cursor string) (time.Time, int64, error) {
+	parts := strings.Split(cursor, "_")
+	if len(parts) != 2 {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	at, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || id <= 0 {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	// in local time, as publication times are stored
+	return time.Unix(at, 0), id, nil
+}
+
+// ListPublished returns a page of published posts with their images, most recently published first
+func ListPublished(ctx context.Context, db sqlx.QueryerContext, l Listing) (*Page, error) {
+	l = l.normalize()
+	conds := []string{"deleted = false", "published = true"}
+	args := []interface{}{}
+	if l.InfluencerID > 0 {
+		conds = append(conds, "influencer_id = ?")
+		args = append(args, l.InfluencerID)
+	}
+	if len(l.Categories) > 0 {
+		conds = append(conds, "id IN (SELECT post_id FROM post_filters WHERE bukalapak_category_id IN (?) AND deleted_at IS NULL)")
+		args = append(args, l.Categories)
+	}
+	if l.Cursor != "" {
+		at, id, err := DecodeCursor(l.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		t := at.Format(config.DatabaseDatetimeFormat)
+		conds = append(conds, "(last_published_at < ? OR (last_published_at = ? AND id < ?))")
+		args = append(args, t, t, id)
+	}
+	// one more post tells whether there is a next page
+	args = append(args, l.Limit+1)
+
+	query, args, err := sqlx.In("SELECT "+Columns+" FROM posts WHERE "+strings.Join(conds, " AND ")+" ORDER BY last_published_at DESC, id DESC LIMIT ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	posts := []Post{}
+	if err := sqlx.SelectContext(ctx, db, &posts, query, args...); err != nil {
+		return nil, err
+	}
+
+	page := &Page{Posts: posts}
+	if len(posts) > l.Limit {
+		page.Posts = posts[:l.Limit]
+		page.NextCursor = EncodeCursor(page.Posts[l.Limit-1])
+	}
+	if err := LoadImages(ctx, db, page.Posts); err != nil {
+		return nil, err
+	}
+	page.Validator, err = Validator(ctx, db, page.Posts, page.NextCursor)
+	return page, err
+}
+
+// Validator returns the validator of a representation of posts, whose images must be loaded.
+// It changes whenever one of the posts, its images or tags is updated, and when like counts change.
+// extra are other parts of the representation, e.g. pagination.
+func Validator(ctx context.Context, db sqlx.QueryerContext, posts []Post, extra ...interface{}) (conditional.Validator, error) {
+	v := conditional.Validator{}
+	if len(posts) == 0 {
+		v.ETag = conditional.NewETag(extra...)
+		return v, nil
+	}
+
+	ids := make([]int64, len(posts))
+	parts := make([]interface{}, 0, len(posts)+len(extra)+1)
+	for k, p := range posts {
+		ids[k] = p.ID
+		parts = append(parts, fmt.Sprintf("%d:%d", p.ID, p.LikeCount))
+		v.LastModified = latest(v.LastModified, p.UpdatedAt)
+		for _, img := range p.Images {
+			v.LastModified = latest(v.LastModified, img.UpdatedAt)
+		}
+	}
+
+	query, args, err := sqlx.In("SELECT MAX(updated_at) FROM post_tags WHERE post_id IN (?) AND deleted_at IS NULL", ids)
+	if err != nil {
+		return v, err
+	}
+	var tagsUpdatedAt *time.Time
+	if err := sqlx.GetContext(ctx, db, &tagsUpdatedAt, query, args...); err != nil {
+		return v, err
+	}
+	if tagsUpdatedAt != nil {
+		v.LastModified = latest(v.LastModified, *tagsUpdatedAt)
+	}
+
+	parts = append(parts, v.LastModified.UnixNano())
+	v.ETag = conditional.NewETag(append(parts, extra...)...)
+	return v, nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// Invalidate removes cached listings affected by changes of given posts
+func Invalidate(ctx context.Context, db sqlx.QueryerContext, c *cache.Cache, ids ...int64) error {
+	if c == nil || len(ids) == 0 {
+		return nil
+	}
+	posts, err := FindAll(ctx, db, ids)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := sqlx.In("SELECT post_id, bukalapak_category_id FROM post_filters WHERE post_id IN (?)", ids)
+	if err != nil {
+		return err
+	}
+	filters := []struct {
+		PostID     int64 `db:"post_id"`
+		CategoryID int64 `db:"bukalapak_category_id"`
+	}{}
+	if err := sqlx.SelectContext(ctx, db, &filters, query, args...); err != nil {
+		return err
+	}
+	categories := map[int64][]int64{}
+	for _, f := range filters {
+		categories[f.PostID] = append(categories[f.PostID], f.CategoryID)
+	}
+
+	tags := []string{}
+	for k := range posts {
+		tags = append(tags, Tags(&posts[k], categories[posts[k].ID])...)
+	}
+	c.Invalidate(tags...)
+	return nil
+}
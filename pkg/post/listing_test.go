@@ -0,0 +1,73 @@
+package post_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+)
+
+func TestListingKey(t *testing.T) {
+	a := post.Listing{Categories: []int64{21, 2, 21}, Limit: 10}
+	b := post.Listing{Categories: []int64{2, 21}, Limit: 10}
+	assert.Equal(t, a.Key(), b.Key())
+	assert.Equal(t, "posts?cursor=&categories=2,21&influencer_id=0&limit=10", a.Key())
+	assert.NotEqual(t, a.Key(), post.Listing{Categories: []int64{2, 21}, Limit: 20}.Key())
+}
+
+func TestCursor(t *testing.T) {
+	at := time.Date(2018, 8, 10, 7, 45, 20, 0, time.UTC)
+	cursor := post.EncodeCursor(post.Post{ID: 42, LastPublishedAt: &at})
+
+	decodedAt, id, err := post.DecodeCursor(cursor)
+	assert.Nil(t, err)
+	assert.True(t, at.Equal(decodedAt))
+	assert.Equal(t, int64(42), id)
+
+	for _, invalid := range []string{"42", "a_42", "1533887120_x", "1533887120_0"} {
+		_, _, err := post.DecodeCursor(invalid)
+		assert.Equal(t, post.ErrInvalidCursor, err)
+	}
+}
+
+func TestListPublishedAndInvalidate(t *testing.T) {
+	db := mysql.Init()
+	ctx := context.Background()
+	ids := []int64{createPost(t, db), createPost(t, db), createPost(t, db)}
+	for k, id := range ids {
+		db.MustExec("UPDATE posts SET last_published_at = ? WHERE id = ?", time.Date(2030, 1, 1, 0, 0, k, 0, time.UTC), id)
+	}
+
+	l := post.Listing{Categories: []int64{2}, Limit: 2}
+	first, err := post.ListPublished(ctx, db, l)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{ids[2], ids[1]}, []int64{first.Posts[0].ID, first.Posts[1].ID})
+	assert.Len(t, first.Posts[0].Images, 1)
+	assert.NotEmpty(t, first.NextCursor)
+
+	l.Cursor = first.NextCursor
+	second, err := post.ListPublished(ctx, db, l)
+	assert.Nil(t, err)
+	assert.Equal(t, ids[0], second.Posts[0].ID)
+
+	c := cache.New(10)
+	c.Set(l.Key(), second, time.Minute, l.Tags(second.Posts)...)
+	other := post.Listing{Categories: []int64{999}, Limit: 2}
+	c.Set(other.Key(), &post.Page{}, time.Minute, other.Tags(nil)...)
+
+	assert.Nil(t, post.Invalidate(ctx, db, c, ids[0]))
+	_, ok := c.Get(l.Key())
+	assert.False(t, ok)
+	_, ok = c.Get(other.Key())
+	assert.True(t, ok, "listings of other categories are kept")
+
+	for _, id := range ids {
+		_, err := post.Trash(ctx, db, id)
+		assert.Nil(t, err)
+	}
+}
@@ -0,0 +1,149 @@
+package post
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+)
+
+// Columns lists posts columns in Post field order
+const Columns = "id, COALESCE(title, '') AS title, COALESCE(description, '') AS description, COALESCE(influencer_name, '') AS influencer_name, influencer_id, published, first_published_at, last_published_at, like_count, deleted, deleted_at, score, created_at, updated_at"
+
+// ErrNotFound is returned when post does not exist
+var ErrNotFound = errors.New("post not found")
+
+// Post is an inspiration post
+type Post struct {
+	ID               int64      `db:"id" json:"id"`
+	Title            string     `db:"title" json:"title"`
+	Description      string     `db:"description" json:"description"`
+	InfluencerName   string     `db:"influencer_name" json:"influencer_name"`
+	InfluencerID     int64      `db:"influencer_id" json:"influencer_id"`
+	Published        bool       `db:"published" json:"published"`
+	FirstPublishedAt *time.Time `db:"first_published_at" json:"first_published_at"`
+	LastPublishedAt  *time.Time `db:"last_published_at" json:"last_published_at"`
+	LikeCount        int        `db:"like_count" json:"like_count"`
+	Deleted          bool       `db:"deleted" json:"deleted"`
+	DeletedAt        *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Score            int        `db:"score" json:"score"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
+
+	Images []Image `db:"-" json:"images,omitempty"`
+}
+
+// Image is an image of a post
+type Image struct {
+	ID        int64     `db:"id" json:"id"`
+	PostID    int64     `db:"post_id" json:"post_id"`
+	URL       string    `db:"url" json:"url"`
+	Width     int       `db:"width" json:"width"`
+	Height    int       `db:"height" json:"height"`
+	Position  int       `db:"position" json:"position"`
+	CreatedAt time.Time `db:"created_at" json:"-"`
+	UpdatedAt time.Time `db:"updated_at" json:"-"`
+}
+
+// Find returns post with given ID, deleted or not
+func Find(ctx context.Context, db sqlx.QueryerContext, id int64) (*Post, error) {
+	var p Post
+	err := sqlx.GetContext(ctx, db, &p, "SELECT "+Columns+" FROM posts WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &p, err
+}
+
+// FindAll returns posts with given IDs, in the same order, skipping missing ones
+func FindAll(ctx context.Context, db sqlx.QueryerContext, ids []int64) ([]Post, error) {
+	if len(ids) == 0 {
+		return []Post{}, nil
+	}
+	query, args, err := sqlx.In("SELECT "+Columns+" FROM posts WHERE id IN (?)", ids)
+	if err != nil {
+		return nil, err
+	}
+	found := []Post{}
+	if err := sqlx.SelectContext(ctx, db, &found, query, args...); err != nil {
+		return nil, err
+	}
+
+	byID := map[int64]Post{}
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	posts := make([]Post, 0, len(found))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
+
+// findForUpdate returns post with given ID, locking its row until the end of tx
+func findForUpdate(ctx context.Context, tx *sqlx.Tx, id int64) (*Post, error) {
+	var p Post
+	err := tx.GetContext(ctx, &p, "SELECT "+Columns+" FROM posts WHERE id = ? FOR UPDATE", id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &p, err
+}
+
+// Update saves editable attributes of a post
+func Update(ctx context.Context, tx *sqlx.Tx, p *Post) error {
+	p.UpdatedAt = time.Now()
+	_, err := tx.ExecContext(ctx, "UPDATE posts SET title = ?, description = ?, influencer_name = ?, influencer_id = ?, published = ?, first_published_at = ?, last_published_at = ?, score = ?, updated_at = ? WHERE id = ?",
+		p.Title, p.Description, p.InfluencerName, p.InfluencerID, p.Published, p.FirstPublishedAt, p.LastPublishedAt, p.Score, p.UpdatedAt.Format(config.DatabaseDatetimeFormat), p.ID)
+	return err
+}
+
+// ListPublishedByInfluencer returns published posts of an influencer, newest first
+func ListPublishedByInfluencer(ctx context.Context, db sqlx.QueryerContext, influencerID int64, limit, offset int) ([]Post, error) {
+	posts := []Post{}
+	err := sqlx.SelectContext(ctx, db, &posts, "SELECT "+Columns+" FROM posts USE INDEX (index_posts_on_influencer_id_and_deleted_and_published) WHERE influencer_id = ? AND deleted = false AND published = true ORDER BY last_published_at DESC, id DESC LIMIT ? OFFSET ?", influencerID, limit, offset)
+	return posts, err
+}
+
+// CountPublishedByInfluencer returns number of published posts of an influencer
+func CountPublishedByInfluencer(ctx context.Context, db sqlx.QueryerContext, influencerID int64) (int, error) {
+	var count int
+	err := sqlx.GetContext(ctx, db, &count, "SELECT COUNT(*) FROM posts USE INDEX (index_posts_on_influencer_id_and_deleted_and_published) WHERE influencer_id = ? AND deleted = false AND published = true", influencerID)
+	return count, err
+}
+
+// LoadImages fills Images of given posts, ordered by position
+func LoadImages(ctx context.Context, db sqlx.QueryerContext, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	query, args, err := sqlx.In("SELECT id, post_id, COALESCE(url, '') AS url, COALESCE(width, 0) AS width, COALESCE(height, 0) AS height, position, created_at, updated_at FROM post_images WHERE post_id IN (?) AND deleted_at IS NULL ORDER BY position, id", ids)
+	if err != nil {
+		return err
+	}
+
+	images := []Image{}
+	if err := sqlx.SelectContext(ctx, db, &images, query, args...); err != nil {
+		return err
+	}
+
+	byPost := map[int64][]Image{}
+	for _, img := range images {
+		byPost[img.PostID] = append(byPost[img.PostID], img)
+	}
+	for i := range posts {
+		posts[i].Images = byPost[posts[i].ID]
+	}
+	return nil
+}
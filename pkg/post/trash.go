@@ -0,0 +1,191 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+)
+
+var (
+	// ErrDeleted is returned when trashing a post already in trash
+	ErrDeleted = errors.New("post is already deleted")
+	// ErrNotDeleted is returned when restoring a post not in trash
+	ErrNotDeleted = errors.New("post is not deleted")
+)
+
+// children lists tables whose rows are soft deleted along with their post
+var children = []string{"post_images", "post_tags", "post_filters"}
+
+// childRecords maps children tables whose changes are audited to their record type
+var childRecords = map[string]string{"post_images": audit.RecordPostImage, "post_tags": audit.RecordPostTag}
+
+// childDeletion is the audited state of a child row trashed or restored along with its post
+type childDeletion struct {
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+// dependents lists tables whose rows are permanently removed along with their post
+var dependents = []string{"post_images", "post_tags", "post_likes", "post_filters"}
+
+// Trash soft deletes a post with its images, tags and filters
+func Trash(ctx context.Context, db *sqlx.DB, id int64) (*Post, error) {
+	var trashed *Post
+	err := mysql.Transaction(ctx, db, func(tx *sqlx.Tx) error {
+		current, err := findForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.Deleted {
+			return ErrDeleted
+		}
+
+		now := time.Now()
+		deletedAt := now.Format(config.DatabaseDatetimeFormat)
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET deleted = true, deleted_at = ?, updated_at = ? WHERE id = ?", deletedAt, deletedAt, id); err != nil {
+			return err
+		}
+		for _, table := range children {
+			ids, err := childIDs(ctx, tx, table, "post_id = ? AND deleted_at IS NULL", id)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE "+table+" SET deleted_at = ?, updated_at = ? WHERE post_id = ? AND deleted_at IS NULL", deletedAt, deletedAt, id); err != nil {
+				return err
+			}
+			if err := recordChildren(ctx, tx, table, ids, nil, &now); err != nil {
+				return err
+			}
+		}
+
+		p := *current
+		p.Deleted, p.DeletedAt, p.UpdatedAt = true, &now, now
+		trashed = &p
+		return audit.Record(ctx, tx, audit.RecordPost, id, current, &p)
+	})
+	return trashed, err
+}
+
+// Restore revives a deleted post with the images, tags and filters deleted along with it
+func Restore(ctx context.Context, db *sqlx.DB, id int64) (*Post, error) {
+	var restored *Post
+	err := mysql.Transaction(ctx, db, func(tx *sqlx.Tx) error {
+		current, err := findForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !current.Deleted {
+			return ErrNotDeleted
+		}
+
+		now := time.Now()
+		updatedAt := now.Format(config.DatabaseDatetimeFormat)
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET deleted = false, deleted_at = NULL, updated_at = ? WHERE id = ?", updatedAt, id); err != nil {
+			return err
+		}
+		// children deleted on their own before the post stay deleted
+		if current.DeletedAt != nil {
+			deletedAt := current.DeletedAt.Format(config.DatabaseDatetimeFormat)
+			for _, table := range children {
+				ids, err := childIDs(ctx, tx, table, "post_id = ? AND deleted_at = ?", id, deletedAt)
+				if err != nil {
+					return err
+				}
+				if _, err := tx.ExecContext(ctx, "UPDATE "+table+" SET deleted_at = NULL, updated_at = ? WHERE post_id = ? AND deleted_at = ?", updatedAt, id, deletedAt); err != nil {
+					return err
+				}
+				if err := recordChildren(ctx, tx, table, ids, current.DeletedAt, nil); err != nil {
+					return err
+				}
+			}
+		}
+
+		p := *current
+		p.Deleted, p.DeletedAt, p.UpdatedAt = false, nil, now
+		restored = &p
+		return audit.Record(ctx, tx, audit.RecordPost, id, current, &p)
+	})
+	return restored, err
+}
+
+// childIDs locks and returns IDs of rows of an audited children table matching where, none for other tables
+func childIDs(ctx context.Context, tx *sqlx.Tx, table, where string, args ...interface{}) ([]int64, error) {
+	ids := []int64{}
+	if _, ok := childRecords[table]; !ok {
+		return ids, nil
+	}
+	err := tx.SelectContext(ctx, &ids, "SELECT id FROM "+table+" WHERE "+where+" FOR UPDATE", args...)
+	return ids, err
+}
+
+// recordChildren audits the deleted_at change of given rows of a children table
+func recordChildren(ctx context.Context, tx *sqlx.Tx, table string, ids []int64, before, after *time.Time) error {
+	for _, id := range ids {
+		if err := audit.Record(ctx, tx, childRecords[table], id, &childDeletion{before}, &childDeletion{after}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTrash returns deleted posts, most recently deleted first
+func ListTrash(ctx context.Context, db sqlx.QueryerContext, limit, offset int) ([]Post, error) {
+	posts := []Post{}
+	err := sqlx.SelectContext(ctx, db, &posts, "SELECT "+Columns+" FROM posts WHERE deleted = true ORDER BY deleted_at DESC, id DESC LIMIT ? OFFSET ?", limit, offset)
+	return posts, err
+}
+
+// CountTrash returns number of deleted posts
+func CountTrash(ctx context.Context, db sqlx.QueryerContext) (int, error) {
+	var count int
+	err := sqlx.GetContext(ctx, db, &count, "SELECT COUNT(*) FROM posts WHERE deleted = true")
+	return count, err
+}
+
+// Purge permanently removes posts deleted more than retention ago, with all their related rows.
+// Posts are removed by batches of batchSize, each in its own transaction. It returns number of purged posts.
+func Purge(ctx context.Context, db *sqlx.DB, retention time.Duration, batchSize int) (int, error) {
+	cutoff := time.Now().Add(-retention).Format(config.DatabaseDatetimeFormat)
+	purged := 0
+	for {
+		n := 0
+		err := mysql.Transaction(ctx, db, func(tx *sqlx.Tx) error {
+			// selected in the transaction and locked, so that a post restored meanwhile keeps its related rows
+			ids := []int64{}
+			if err := tx.SelectContext(ctx, &ids, "SELECT id FROM posts WHERE deleted = true AND deleted_at < ? ORDER BY id LIMIT ? FOR UPDATE", cutoff, batchSize); err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			for _, table := range dependents {
+				if err := execIn(ctx, tx, "DELETE FROM "+table+" WHERE post_id IN (?)", ids); err != nil {
+					return err
+				}
+			}
+			n = len(ids)
+			return execIn(ctx, tx, "DELETE FROM posts WHERE id IN (?) AND deleted = true", ids)
+		})
+		if err != nil {
+			return purged, err
+		}
+		if n == 0 {
+			return purged, nil
+		}
+		purged += n
+	}
+}
+
+func execIn(ctx context.Context, tx *sqlx.Tx, query string, ids []int64) error {
+	query, args, err := sqlx.In(query, ids)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	return err
+}
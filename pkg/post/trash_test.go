@@ -0,0 +1,181 @@
+package post_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/export"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+)
+
+func init() {
+	gotenv.MustLoad(os.Getenv("GOPATH") + "/src/github.com/wiskarindra/jenkins_jr/.env")
+	os.Setenv("ENV", "test")
+	os.Setenv("DATABASE_NAME", os.Getenv("DATABASE_TEST_NAME"))
+	os.Setenv("DATABASE_PORT", os.Getenv("DATABASE_TEST_PORT"))
+	os.Setenv("DATABASE_USERNAME", os.Getenv("DATABASE_TEST_USERNAME"))
+	os.Setenv("DATABASE_PASSWORD", os.Getenv("DATABASE_TEST_PASSWORD"))
+	os.Setenv("DATABASE_HOST", os.Getenv("DATABASE_TEST_HOST"))
+}
+
+// createPost inserts a published post with one image, tag, like and filter
+func createPost(t *testing.T, db *sqlx.DB) int64 {
+	res := db.MustExec("INSERT INTO posts (title, published, created_at, updated_at) VALUES ('Gaya Kasual', true, NOW(), NOW())")
+	id, err := res.LastInsertId()
+	assert.Nil(t, err)
+
+	db.MustExec("INSERT INTO post_images (post_id, url, width, height, created_at, updated_at) VALUES (?, 'https://s0.bukalapak.com/a.jpg', 1080, 1350, NOW(), NOW())", id)
+	db.MustExec("INSERT INTO post_tags (post_id, name, created_at, updated_at) VALUES (?, 'Kemeja', NOW(), NOW())", id)
+	db.MustExec("INSERT INTO post_likes (post_id, bukalapak_user_id) VALUES (?, 1)", id)
+	db.MustExec("INSERT INTO post_filters (post_id, bukalapak_category_id) VALUES (?, 2)", id)
+	return id
+}
+
+func count(db *sqlx.DB, query string, id int64) int {
+	var n int
+	db.Get(&n, query, id)
+	return n
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	db := mysql.Init()
+	ctx := context.Background()
+	id := createPost(t, db)
+	// updated_at of children moves with deleted_at, so that incremental exports include them
+	stale := func() {
+		db.MustExec("UPDATE post_images SET updated_at = NOW() - INTERVAL 1 DAY WHERE post_id = ?", id)
+		db.MustExec("UPDATE post_tags SET updated_at = NOW() - INTERVAL 1 DAY WHERE post_id = ?", id)
+	}
+	const touched = "SELECT COUNT(*) FROM %s WHERE post_id = ? AND updated_at > NOW() - INTERVAL 1 HOUR"
+	// images and tags trashed and restored along with the post are audited
+	const audited = "SELECT COUNT(*) FROM action_log_histories h JOIN %s c ON c.id = h.record_id WHERE h.record_type = '%s' AND c.post_id = ?"
+
+	stale()
+	p, err := post.Trash(ctx, db, id)
+	assert.Nil(t, err)
+	assert.True(t, p.Deleted)
+	assert.NotNil(t, p.DeletedAt)
+	assert.Equal(t, 0, count(db, "SELECT COUNT(*) FROM post_images WHERE post_id = ? AND deleted_at IS NULL", id))
+	assert.Equal(t, 0, count(db, "SELECT COUNT(*) FROM post_tags WHERE post_id = ? AND deleted_at IS NULL", id))
+	assert.Equal(t, 1, count(db, fmt.Sprintf(touched, "post_images"), id))
+	assert.Equal(t, 1, count(db, fmt.Sprintf(touched, "post_tags"), id))
+	assert.Equal(t, 1, count(db, fmt.Sprintf(audited, "post_images", audit.RecordPostImage), id))
+	assert.Equal(t, 1, count(db, fmt.Sprintf(audited, "post_tags", audit.RecordPostTag), id))
+
+	_, err = post.Trash(ctx, db, id)
+	assert.Equal(t, post.ErrDeleted, err)
+
+	stale()
+	p, err = post.Restore(ctx, db, id)
+	assert.Nil(t, err)
+	assert.False(t, p.Deleted)
+	assert.Equal(t, 1, count(db, "SELECT COUNT(*) FROM post_images WHERE post_id = ? AND deleted_at IS NULL", id))
+	assert.Equal(t, 1, count(db, fmt.Sprintf(touched, "post_images"), id))
+	assert.Equal(t, 1, count(db, fmt.Sprintf(touched, "post_tags"), id))
+	assert.Equal(t, 1, count(db, "SELECT COUNT(*) FROM post_filters WHERE post_id = ? AND deleted_at IS NULL", id))
+	assert.Equal(t, 2, count(db, fmt.Sprintf(audited, "post_images", audit.RecordPostImage), id))
+	assert.Equal(t, 2, count(db, fmt.Sprintf(audited, "post_tags", audit.RecordPostTag), id))
+
+	_, err = post.Restore(ctx, db, id)
+	assert.Equal(t, post.ErrNotDeleted, err)
+}
+
+// exported returns rows of post id found in an exported jsonl file
+func exported(t *testing.T, f export.File, id int64) []map[string]interface{} {
+	file, err := os.Open(f.Path)
+	assert.Nil(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	assert.Nil(t, err)
+
+	rows := []map[string]interface{}{}
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		row := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &row))
+		if row["post_id"] == float64(id) {
+			rows = append(rows, row)
+		}
+	}
+	assert.Nil(t, scanner.Err())
+	return rows
+}
+
+func TestTrashAndRestoreExport(t *testing.T) {
+	db := mysql.Init()
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "export")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	exporter := export.Exporter{DB: db, Dir: dir, Format: export.FormatJSONL, Tables: export.Tables[1:3]}
+	// the snapshot time is the exclusive upper bound, at second precision
+	run := func() []export.File {
+		time.Sleep(1100 * time.Millisecond)
+		files, err := exporter.Run(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, files, 2)
+		return files
+	}
+
+	id := createPost(t, db)
+	run()
+
+	_, err = post.Trash(ctx, db, id)
+	assert.Nil(t, err)
+	// images trashed without their updated_at bumped are exported by their deleted_at
+	db.MustExec("UPDATE post_images SET updated_at = NOW() - INTERVAL 1 DAY WHERE post_id = ?", id)
+	for _, f := range run() {
+		rows := exported(t, f, id)
+		assert.Len(t, rows, 1, f.Table)
+		if len(rows) == 1 {
+			assert.NotNil(t, rows[0]["deleted_at"], f.Table)
+		}
+	}
+
+	_, err = post.Restore(ctx, db, id)
+	assert.Nil(t, err)
+	for _, f := range run() {
+		rows := exported(t, f, id)
+		assert.Len(t, rows, 1, f.Table)
+		if len(rows) == 1 {
+			assert.Nil(t, rows[0]["deleted_at"], f.Table)
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	db := mysql.Init()
+	ctx := context.Background()
+	old, recent := createPost(t, db), createPost(t, db)
+
+	_, err := post.Trash(ctx, db, old)
+	assert.Nil(t, err)
+	_, err = post.Trash(ctx, db, recent)
+	assert.Nil(t, err)
+	db.MustExec("UPDATE posts SET deleted_at = ? WHERE id = ?", time.Now().AddDate(0, 0, -31), old)
+
+	_, err = post.Purge(ctx, db, 30*24*time.Hour, 1)
+	assert.Nil(t, err)
+
+	_, err = post.Find(ctx, db, old)
+	assert.Equal(t, post.ErrNotFound, err)
+	for _, table := range []string{"post_images", "post_tags", "post_likes", "post_filters"} {
+		assert.Equal(t, 0, count(db, "SELECT COUNT(*) FROM "+table+" WHERE post_id = ?", old), table)
+	}
+
+	_, err = post.Find(ctx, db, recent)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,60 @@
+package post
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/audit"
+	"github.com/wiskarindra/jenkins_jr/pkg/mysql"
+)
+
+// At reconstructs a post as it was at given time by undoing its recorded changes
+func At(ctx context.Context, db sqlx.QueryerContext, id int64, at time.Time) (*Post, error) {
+	p, err := Find(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := audit.Since(ctx, db, audit.RecordPost, id, at)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit.Rewind(p, histories); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Revert applies editable attributes of the version of a post at given time.
+// Engagement and lifecycle attributes (likes, deletion) are kept as they are.
+func Revert(ctx context.Context, db *sqlx.DB, id int64, at time.Time) (*Post, error) {
+	var reverted *Post
+	err := mysql.Transaction(ctx, db, func(tx *sqlx.Tx) error {
+		current, err := findForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		version, err := At(ctx, tx, id, at)
+		if err != nil {
+			return err
+		}
+
+		p := *current
+		p.Title = version.Title
+		p.Description = version.Description
+		p.InfluencerName = version.InfluencerName
+		p.InfluencerID = version.InfluencerID
+		p.Published = version.Published
+		p.FirstPublishedAt = version.FirstPublishedAt
+		p.LastPublishedAt = version.LastPublishedAt
+		p.Score = version.Score
+
+		if err := Update(ctx, tx, &p); err != nil {
+			return err
+		}
+		reverted = &p
+		return audit.Record(ctx, tx, audit.RecordPost, p.ID, current, &p)
+	})
+	return reverted, err
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/auth"
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+// Limiter throttles requests of each user, client or IP address
+type Limiter struct {
+	Store Store
+	// TrustProxy takes client IP addresses from X-Forwarded-For, set it only behind a load balancer overwriting it
+	TrustProxy bool
+}
+
+// Handle wraps h to allow each requester limit on the route named name.
+// Users and clients must be authenticated beforehand to be told apart from their IP address.
+// Requests are let through when the store fails.
+func (l *Limiter) Handle(name string, limit Limit, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if l.allow(w, r, name+":"+l.Key(r), limit) {
+			h(w, r, ps)
+		}
+	}
+}
+
+// HandleIP wraps h to allow each IP address limit on the routes named name, whoever the requester is.
+// It goes before authentication, so that requests with made up tokens are throttled before reaching the auth server.
+func (l *Limiter) HandleIP(name string, limit Limit, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if l.allow(w, r, name+":ip:"+l.ip(r), limit) {
+			h(w, r, ps)
+		}
+	}
+}
+
+// allow takes a token of key, answering 429 when there is none.
+// Requests are let through when the store fails.
+func (l *Limiter) allow(w http.ResponseWriter, r *http.Request, key string, limit Limit) bool {
+	ctx := r.Context()
+	result, err := l.Store.Take(ctx, key, limit)
+	if err != nil {
+		log.ErrLog(ctx, err, "ratelimit", "Failed to take rate limit token")
+		return true
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", seconds(result.RetryAfter))
+		response.Fail(w, http.StatusTooManyRequests, "Too many requests, retry in "+seconds(result.RetryAfter)+" seconds")
+		return false
+	}
+	return true
+}
+
+// Key identifies the requester by user ID, then OAuth2 client ID, then IP address
+func (l *Limiter) Key(r *http.Request) string {
+	if user := currentuser.FromContext(r.Context()); user.ID > 0 {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	if t := auth.FromContext(r.Context()); t != nil && t.ClientID != "" {
+		return "client:" + t.ClientID
+	}
+	return "ip:" + l.ip(r)
+}
+
+func (l *Limiter) ip(r *http.Request) string {
+	if l.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds formats d as a whole number of seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
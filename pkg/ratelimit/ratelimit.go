@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+)
+
+// Limit allows Requests per Period, with bursts up to Requests
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses limits formatted as requests/period, e.g. 60/1m
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("ratelimit: limit %q is not formatted as requests/period", s)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid number of requests in %q", s)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid period in %q", s)
+	}
+	return Limit{Requests: requests, Period: period}, nil
+}
+
+// LimitFromEnv returns the limit of environment variable key, or fallback when missing or invalid.
+// Invalid values are logged.
+func LimitFromEnv(key string, fallback Limit) Limit {
+	v := config.String(key, "")
+	if v == "" {
+		return fallback
+	}
+	l, err := ParseLimit(v)
+	if err != nil {
+		log.ErrLog(context.Background(), err, "ratelimit", fmt.Sprintf("Invalid %s, using %s", key, fallback))
+		return fallback
+	}
+	return l
+}
+
+// String formats limit as ParseLimit expects it
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+	// Limit is the capacity of the bucket
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets. MemoryStore serves a single process, a shared backend can implement it for many.
+type Store interface {
+	// Take removes a token from the bucket of key, created full on first use
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is a token bucket, refilled continuously at Requests per Period
+type bucket struct {
+	tokens float64
+	last   time.Time
+	period time.Duration
+}
+
+// take refills b up to now, then removes a token if any
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	capacity := float64(limit.Requests)
+	rate := capacity / float64(limit.Period)
+
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))*rate)
+	b.last, b.period = now, limit.Period
+
+	r := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) / rate))
+	}
+	r.Remaining = int(b.tokens)
+	r.Reset = time.Duration(math.Ceil((capacity - b.tokens) / rate))
+	return r
+}
@@ -0,0 +1,153 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/auth"
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+	"github.com/wiskarindra/jenkins_jr/pkg/ratelimit"
+)
+
+func init() {
+	os.Setenv("ENV", "test")
+}
+
+func TestParseLimit(t *testing.T) {
+	l, err := ratelimit.ParseLimit("60/1m")
+	assert.Nil(t, err)
+	assert.Equal(t, ratelimit.Limit{Requests: 60, Period: time.Minute}, l)
+	assert.Equal(t, "60/1m0s", l.String())
+
+	for _, invalid := range []string{"", "60", "0/1m", "a/1m", "60/soon", "60/-1s"} {
+		_, err := ratelimit.ParseLimit(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+
+	os.Setenv("RATE_LIMIT_TEST", "10/1s")
+	defer os.Unsetenv("RATE_LIMIT_TEST")
+	assert.Equal(t, ratelimit.Limit{Requests: 10, Period: time.Second}, ratelimit.LimitFromEnv("RATE_LIMIT_TEST", l))
+	assert.Equal(t, l, ratelimit.LimitFromEnv("RATE_LIMIT_MISSING", l))
+
+	os.Setenv("RATE_LIMIT_TEST", "10 per second")
+	assert.Equal(t, l, ratelimit.LimitFromEnv("RATE_LIMIT_TEST", l), "invalid values fall back")
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+	limit := ratelimit.Limit{Requests: 3, Period: 300 * time.Millisecond}
+
+	for k := 2; k >= 0; k-- {
+		r, err := s.Take(ctx, "a", limit)
+		assert.Nil(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3, r.Limit)
+		assert.Equal(t, k, r.Remaining)
+	}
+
+	r, _ := s.Take(ctx, "a", limit)
+	assert.False(t, r.Allowed)
+	assert.True(t, r.RetryAfter > 0 && r.RetryAfter <= 100*time.Millisecond, r.RetryAfter.String())
+	assert.True(t, r.Reset > 200*time.Millisecond && r.Reset <= 300*time.Millisecond, r.Reset.String())
+
+	r, _ = s.Take(ctx, "b", limit)
+	assert.True(t, r.Allowed, "buckets are kept per key")
+
+	time.Sleep(110 * time.Millisecond)
+	r, _ = s.Take(ctx, "a", limit)
+	assert.True(t, r.Allowed, "tokens are refilled over time")
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is down")
+}
+
+func TestLimiter(t *testing.T) {
+	l := &ratelimit.Limiter{Store: ratelimit.NewMemoryStore()}
+	h := l.Handle("likes", ratelimit.Limit{Requests: 2, Period: time.Minute}, func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h(w, r, nil)
+		return w
+	}
+
+	r := httptest.NewRequest("POST", "/posts/1/like", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	w := serve(r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+
+	serve(r)
+	w = serve(r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	user := r.WithContext(currentuser.NewContext(r.Context(), &currentuser.CurrentUser{ID: 42}))
+	assert.Equal(t, http.StatusNoContent, serve(user).Code, "users are limited apart from their IP address")
+
+	other := httptest.NewRequest("POST", "/posts/1/like", nil)
+	other.RemoteAddr = "10.0.0.2:5000"
+	other.Header.Set("X-Forwarded-For", "10.0.0.1")
+	assert.Equal(t, http.StatusNoContent, serve(other).Code, "X-Forwarded-For is ignored unless proxy is trusted")
+
+	failing := &ratelimit.Limiter{Store: failingStore{}}
+	h = failing.Handle("likes", ratelimit.Limit{Requests: 1, Period: time.Minute}, func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	assert.Equal(t, http.StatusNoContent, serve(r).Code, "requests are let through when store fails")
+}
+
+func TestHandleIP(t *testing.T) {
+	l := &ratelimit.Limiter{Store: ratelimit.NewMemoryStore()}
+	h := l.HandleIP("auth", ratelimit.Limit{Requests: 1, Period: time.Minute}, func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	serve := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		h(w, r, nil)
+		return w.Code
+	}
+
+	r := httptest.NewRequest("GET", "/posts", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	assert.Equal(t, http.StatusNoContent, serve(r))
+
+	user := r.WithContext(currentuser.NewContext(r.Context(), &currentuser.CurrentUser{ID: 42}))
+	assert.Equal(t, http.StatusTooManyRequests, serve(user), "requests are limited by IP address whoever makes them")
+
+	other := httptest.NewRequest("GET", "/posts", nil)
+	other.RemoteAddr = "10.0.0.2:5000"
+	assert.Equal(t, http.StatusNoContent, serve(other))
+}
+
+func TestKey(t *testing.T) {
+	l := &ratelimit.Limiter{TrustProxy: true}
+	r := httptest.NewRequest("GET", "/posts", nil)
+	r.RemoteAddr = "10.0.0.2:5000"
+	assert.Equal(t, "ip:10.0.0.2", l.Key(r))
+
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	assert.Equal(t, "ip:203.0.113.7", l.Key(r))
+
+	r = r.WithContext(auth.NewContext(r.Context(), &auth.Token{ClientID: "android"}))
+	assert.Equal(t, "client:android", l.Key(r))
+
+	r = r.WithContext(currentuser.NewContext(r.Context(), &currentuser.CurrentUser{ID: 42}))
+	assert.Equal(t, "user:42", l.Key(r))
+}
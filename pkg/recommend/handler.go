@@ -0,0 +1,61 @@
+package recommend
+
+import (
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/currentuser"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+	"github.com/wiskarindra/jenkins_jr/pkg/request"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+// Handler serves related posts endpoint
+type Handler struct {
+	DB          *sqlx.DB
+	Recommender *Recommender
+}
+
+// related is a recommended post with the signals it shares with the viewed post
+type related struct {
+	post.Post
+	Reasons []string `json:"reasons"`
+}
+
+// Related returns posts similar to the given one, leaving out posts the current user already liked
+func (h *Handler) Related(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	limit, _ := request.Page(r)
+
+	recommendations, err := h.Recommender.Related(ctx, request.ID(ps.ByName("id")), currentuser.FromContext(ctx).ID, limit)
+	if err != nil {
+		log.ErrLog(ctx, err, "recommend", "Failed to find related posts")
+		response.Fail(w, http.StatusInternalServerError, "Failed to find related posts")
+		return
+	}
+
+	ids := make([]int64, len(recommendations))
+	reasons := map[int64][]string{}
+	for k, rec := range recommendations {
+		ids[k] = rec.PostID
+		reasons[rec.PostID] = rec.Reasons
+	}
+	posts, err := post.FindAll(ctx, h.DB, ids)
+	if err == nil {
+		err = post.LoadImages(ctx, h.DB, posts)
+	}
+	if err != nil {
+		log.ErrLog(ctx, err, "recommend", "Failed to load related posts")
+		response.Fail(w, http.StatusInternalServerError, "Failed to load related posts")
+		return
+	}
+
+	result := make([]related, len(posts))
+	for k, p := range posts {
+		result[k] = related{Post: p, Reasons: reasons[p.ID]}
+	}
+	response.OK(w, result, response.Meta{Limit: limit})
+}
@@ -0,0 +1,74 @@
+package recommend
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MySQLStore reads relatedness signals from post_filters, posts, post_tags and post_likes
+type MySQLStore struct {
+	DB *sqlx.DB
+}
+
+// signalQueries select (post_id, strength) of published posts related to the post given as first argument.
+// Every query takes the post ID and the limit as arguments.
+var signalQueries = map[string]string{
+	SignalCategory: `SELECT f2.post_id, COUNT(*) AS strength
+		FROM post_filters f1
+		JOIN post_filters f2 ON f2.bukalapak_category_id = f1.bukalapak_category_id AND f2.post_id <> f1.post_id AND f2.deleted_at IS NULL
+		JOIN posts p ON p.id = f2.post_id AND p.deleted = false AND p.published = true
+		WHERE f1.post_id = ? AND f1.deleted_at IS NULL
+		GROUP BY f2.post_id ORDER BY strength DESC LIMIT ?`,
+	SignalInfluencer: `SELECT p.id AS post_id, 1 AS strength
+		FROM posts current
+		JOIN posts p ON p.influencer_id = current.influencer_id AND p.id <> current.id AND p.deleted = false AND p.published = true
+		WHERE current.id = ? AND current.influencer_id <> 0
+		ORDER BY p.last_published_at DESC LIMIT ?`,
+	SignalTag: `SELECT t2.post_id, COUNT(DISTINCT t2.reference_type, t2.reference_id) AS strength
+		FROM post_tags t1
+		JOIN post_tags t2 ON t2.reference_type = t1.reference_type AND t2.reference_id = t1.reference_id AND t2.post_id <> t1.post_id AND t2.deleted_at IS NULL
+		JOIN posts p ON p.id = t2.post_id AND p.deleted = false AND p.published = true
+		WHERE t1.post_id = ? AND t1.reference_id IS NOT NULL AND t1.deleted_at IS NULL
+		GROUP BY t2.post_id ORDER BY strength DESC LIMIT ?`,
+	SignalCoLike: `SELECT l2.post_id, COUNT(*) AS strength
+		FROM post_likes l1
+		JOIN post_likes l2 ON l2.bukalapak_user_id = l1.bukalapak_user_id AND l2.post_id <> l1.post_id AND l2.liked = true
+		JOIN posts p ON p.id = l2.post_id AND p.deleted = false AND p.published = true
+		WHERE l1.post_id = ? AND l1.liked = true
+		GROUP BY l2.post_id ORDER BY strength DESC LIMIT ?`,
+}
+
+// Signals implements Store
+func (s *MySQLStore) Signals(ctx context.Context, postID int64, limit int) (Signals, error) {
+	signals := Signals{}
+	for name, query := range signalQueries {
+		rows := []struct {
+			PostID   int64   `db:"post_id"`
+			Strength float64 `db:"strength"`
+		}{}
+		if err := s.DB.SelectContext(ctx, &rows, query, postID, limit); err != nil {
+			return nil, err
+		}
+
+		signals[name] = map[int64]float64{}
+		for _, r := range rows {
+			signals[name][r.PostID] = r.Strength
+		}
+	}
+	return signals, nil
+}
+
+// LikedBy implements Store
+func (s *MySQLStore) LikedBy(ctx context.Context, userID int64) (map[int64]bool, error) {
+	ids := []int64{}
+	if err := s.DB.SelectContext(ctx, &ids, "SELECT post_id FROM post_likes WHERE bukalapak_user_id = ? AND liked = true", userID); err != nil {
+		return nil, err
+	}
+
+	liked := map[int64]bool{}
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked, nil
+}
@@ -0,0 +1,189 @@
+package recommend
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+)
+
+// Signal names, also used as recommendation reasons
+const (
+	SignalCategory   = "category"
+	SignalInfluencer = "influencer"
+	SignalTag        = "tag"
+	SignalCoLike     = "co_like"
+)
+
+// Weights sets how much each signal counts toward relatedness
+type Weights map[string]float64
+
+// DefaultWeights favors posts liked by the same users, then shared tagged products
+var DefaultWeights = Weights{
+	SignalCategory:   1,
+	SignalInfluencer: 1,
+	SignalTag:        2,
+	SignalCoLike:     3,
+}
+
+// WeightsFromEnv returns DefaultWeights overridden by RECOMMEND_WEIGHT_<SIGNAL> environment variables
+func WeightsFromEnv() Weights {
+	w := Weights{}
+	for signal, fallback := range DefaultWeights {
+		w[signal] = config.Float("RECOMMEND_WEIGHT_"+strings.ToUpper(signal), fallback)
+	}
+	return w
+}
+
+// Signals maps each signal to the strength of candidate posts, by post ID
+type Signals map[string]map[int64]float64
+
+// Store fetches relatedness signals
+type Store interface {
+	// Signals returns published posts related to given post, excluding itself
+	Signals(ctx context.Context, postID int64, limit int) (Signals, error)
+	// LikedBy returns IDs of posts liked by given Bukalapak user
+	LikedBy(ctx context.Context, userID int64) (map[int64]bool, error)
+}
+
+// Recommendation is a post related to another one
+type Recommendation struct {
+	PostID  int64    `json:"post_id"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// Rank combines signals, each normalized to its strongest candidate, into recommendations sorted by score
+func Rank(signals Signals, weights Weights) []Recommendation {
+	byPost := map[int64]*Recommendation{}
+	names := make([]string, 0, len(signals))
+	for name := range signals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		candidates := signals[name]
+		max := 0.0
+		for _, strength := range candidates {
+			if strength > max {
+				max = strength
+			}
+		}
+		if max == 0 || weights[name] == 0 {
+			continue
+		}
+		for id, strength := range candidates {
+			if strength <= 0 {
+				continue
+			}
+			r, ok := byPost[id]
+			if !ok {
+				r = &Recommendation{PostID: id, Reasons: []string{}}
+				byPost[id] = r
+			}
+			r.Score += weights[name] * strength / max
+			r.Reasons = append(r.Reasons, name)
+		}
+	}
+
+	ranked := make([]Recommendation, 0, len(byPost))
+	for _, r := range byPost {
+		ranked = append(ranked, *r)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].PostID > ranked[j].PostID
+	})
+	return ranked
+}
+
+// Key returns the cache key of ranked candidates of a post
+func Key(postID int64) string {
+	return "related:" + strconv.FormatInt(postID, 10)
+}
+
+// Recommender finds posts related to a given post, caching ranked candidates per post.
+// Cached candidates are tagged with the post and every candidate, so that changes of any of them,
+// invalidated with post.Invalidate, drop them.
+type Recommender struct {
+	Store   Store
+	Weights Weights
+	// Cache holds ranked candidates for TTL, nil disables caching
+	Cache *cache.Cache
+	TTL   time.Duration
+	// Candidates is the number of candidates fetched per signal
+	Candidates int
+}
+
+// New returns Recommender with weights from environment, caching in c
+func New(store Store, c *cache.Cache) *Recommender {
+	return &Recommender{
+		Store:      store,
+		Weights:    WeightsFromEnv(),
+		Cache:      c,
+		TTL:        config.Duration("RECOMMEND_CACHE_TTL", 10*time.Minute),
+		Candidates: 100,
+	}
+}
+
+// Related returns up to limit posts related to postID, excluding posts already liked by userID
+func (r *Recommender) Related(ctx context.Context, postID, userID int64, limit int) ([]Recommendation, error) {
+	ranked, err := r.ranked(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	liked := map[int64]bool{}
+	if userID > 0 {
+		if liked, err = r.Store.LikedBy(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := []Recommendation{}
+	for _, rec := range ranked {
+		if len(result) == limit {
+			break
+		}
+		if rec.PostID == postID || liked[rec.PostID] {
+			continue
+		}
+		result = append(result, rec)
+	}
+	return result, nil
+}
+
+func (r *Recommender) ranked(ctx context.Context, postID int64) ([]Recommendation, error) {
+	load := func(ctx context.Context) (interface{}, []string, error) {
+		signals, err := r.Store.Signals(ctx, postID, r.Candidates)
+		if err != nil {
+			return nil, nil, err
+		}
+		ranked := Rank(signals, r.Weights)
+		tags := []string{post.Tag(postID)}
+		for _, rec := range ranked {
+			tags = append(tags, post.Tag(rec.PostID))
+		}
+		return ranked, tags, nil
+	}
+
+	var v interface{}
+	var err error
+	if r.Cache == nil {
+		v, _, err = load(ctx)
+	} else {
+		v, err = r.Cache.Fetch(ctx, Key(postID), r.TTL, load)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.([]Recommendation), nil
+}
@@ -0,0 +1,85 @@
+package recommend_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/cache"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+	"github.com/wiskarindra/jenkins_jr/pkg/recommend"
+)
+
+type store struct {
+	signals recommend.Signals
+	liked   map[int64]bool
+	calls   int
+}
+
+func (s *store) Signals(_ context.Context, _ int64, _ int) (recommend.Signals, error) {
+	s.calls++
+	return s.signals, nil
+}
+
+func (s *store) LikedBy(_ context.Context, _ int64) (map[int64]bool, error) {
+	return s.liked, nil
+}
+
+var signals = recommend.Signals{
+	recommend.SignalCategory:   {2: 2, 3: 1},
+	recommend.SignalInfluencer: {4: 1},
+	recommend.SignalTag:        {3: 4, 5: 2},
+	recommend.SignalCoLike:     {1: 5, 5: 10},
+}
+
+func TestRank(t *testing.T) {
+	ranked := recommend.Rank(signals, recommend.DefaultWeights)
+
+	assert.Len(t, ranked, 5)
+	assert.Equal(t, int64(5), ranked[0].PostID)
+	assert.Equal(t, 4.0, ranked[0].Score)
+	assert.Equal(t, []string{recommend.SignalCoLike, recommend.SignalTag}, ranked[0].Reasons)
+	assert.Equal(t, int64(3), ranked[1].PostID)
+	assert.Equal(t, 2.5, ranked[1].Score)
+}
+
+func TestRankIgnoresZeroWeight(t *testing.T) {
+	ranked := recommend.Rank(signals, recommend.Weights{recommend.SignalInfluencer: 1})
+
+	assert.Len(t, ranked, 1)
+	assert.Equal(t, int64(4), ranked[0].PostID)
+}
+
+func TestRelated(t *testing.T) {
+	s := &store{signals: signals, liked: map[int64]bool{3: true}}
+	c := cache.New(10)
+	r := recommend.New(s, c)
+	r.Weights = recommend.DefaultWeights
+
+	related, err := r.Related(context.Background(), 1, 99, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{5, 4}, []int64{related[0].PostID, related[1].PostID})
+
+	related, _ = r.Related(context.Background(), 1, 0, 10)
+	assert.Len(t, related, 4)
+	assert.Equal(t, 1, s.calls, "ranked candidates should be cached per post")
+
+	// changes of the post or of a candidate drop cached candidates
+	c.Invalidate(post.Tag(1))
+	r.Related(context.Background(), 1, 0, 10)
+	assert.Equal(t, 2, s.calls)
+	c.Invalidate(post.Tag(5))
+	r.Related(context.Background(), 1, 0, 10)
+	assert.Equal(t, 3, s.calls)
+	c.Invalidate(post.Tag(6))
+	r.Related(context.Background(), 1, 0, 10)
+	assert.Equal(t, 3, s.calls)
+
+	r.TTL = -time.Second
+	c.Purge()
+	r.Related(context.Background(), 1, 0, 10)
+	r.Related(context.Background(), 1, 0, 10)
+	assert.Equal(t, 5, s.calls)
+}
@@ -0,0 +1,53 @@
+package request
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultLimit is used when limit parameter is missing or invalid
+	DefaultLimit = 10
+	// MaxLimit caps limit parameter
+	MaxLimit = 50
+)
+
+// Page returns limit and offset query parameters
+func Page(r *http.Request) (limit, offset int) {
+	q := r.URL.Query()
+
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	offset, err = strconv.Atoi(q.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// ID parses a positive integer identifier, returning 0 when invalid
+func ID(s string) int64 {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
+
+// IDs parses a comma separated list of identifiers, skipping invalid ones
+func IDs(s string) []int64 {
+	ids := []int64{}
+	for _, part := range strings.Split(s, ",") {
+		if id := ID(strings.TrimSpace(part)); id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
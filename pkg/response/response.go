@@ -0,0 +1,59 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Meta contains response metadata
+type Meta struct {
+	HTTPStatus int `json:"http_status"`
+	Limit      int `json:"limit,omitempty"`
+	Offset     int `json:"offset,omitempty"`
+	Total      int `json:"total,omitempty"`
+	// Truncated is set when only the first Total matches of a search can be listed
+	Truncated bool `json:"truncated,omitempty"`
+	// NextCursor is set on cursor paginated listings, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Error is a single error entry of a failed response
+type Error struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+// Body is the envelope of every JSON response
+type Body struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []Error     `json:"errors,omitempty"`
+	Meta   Meta        `json:"meta"`
+}
+
+// Write writes given body as JSON with its meta HTTP status
+func Write(w http.ResponseWriter, body Body) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(body.Meta.HTTPStatus)
+	json.NewEncoder(w).Encode(body)
+}
+
+// OK writes data with 200 status
+func OK(w http.ResponseWriter, data interface{}, meta Meta) {
+	meta.HTTPStatus = http.StatusOK
+	Write(w, Body{Data: data, Meta: meta})
+}
+
+// Created writes data with 201 status
+func Created(w http.ResponseWriter, data interface{}) {
+	Write(w, Body{Data: data, Meta: Meta{HTTPStatus: http.StatusCreated}})
+}
+
+// Accepted writes data with 202 status, for requests handled after responding
+func Accepted(w http.ResponseWriter, data interface{}) {
+	Write(w, Body{Data: data, Meta: Meta{HTTPStatus: http.StatusAccepted}})
+}
+
+// Fail writes an error message with given status
+func Fail(w http.ResponseWriter, status int, message string) {
+	Write(w, Body{Errors: []Error{{Message: message, Code: status}}, Meta: Meta{HTTPStatus: status}})
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/post"
+	"github.com/wiskarindra/jenkins_jr/pkg/request"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+)
+
+// Handler serves post search endpoint
+type Handler struct {
+	DB       *sqlx.DB
+	Searcher Searcher
+}
+
+// hit is a found post with its relevance and highlighted fields
+type hit struct {
+	post.Post
+	Relevance  float64           `json:"relevance"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Posts searches published posts by title, description, influencer and tag names
+func (h *Handler) Posts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		response.Fail(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	limit, offset := request.Page(r)
+
+	page, err := h.Searcher.Search(ctx, Query{Text: q, Limit: limit, Offset: offset})
+	if err != nil {
+		log.ErrLog(ctx, err, "search", "Failed to search posts")
+		response.Fail(w, http.StatusInternalServerError, "Failed to search posts")
+		return
+	}
+
+	results := page.Results
+	ids := make([]int64, len(results))
+	for k, res := range results {
+		ids[k] = res.PostID
+	}
+	posts, err := post.FindAll(ctx, h.DB, ids)
+	if err == nil {
+		err = post.LoadImages(ctx, h.DB, posts)
+	}
+	if err != nil {
+		log.ErrLog(ctx, err, "search", "Failed to load found posts")
+		response.Fail(w, http.StatusInternalServerError, "Failed to load found posts")
+		return
+	}
+
+	byID := map[int64]Result{}
+	for _, res := range results {
+		byID[res.PostID] = res
+	}
+	hits := make([]hit, len(posts))
+	for k, p := range posts {
+		hits[k] = hit{Post: p, Relevance: byID[p.ID].Relevance, Highlights: byID[p.ID].Highlights}
+	}
+	response.OK(w, hits, response.Meta{Limit: limit, Offset: offset, Total: page.Total, Truncated: page.Truncated})
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	highlightOpen  = "<em>"
+	highlightClose = "</em>"
+	// snippetLength is the maximum length, in runes, of highlighted text around the first match
+	snippetLength = 160
+)
+
+// Highlight HTML escapes text and wraps words matching any of terms in <em> tags.
+// Long texts are cut to a snippet around the first match. It returns empty string when nothing matches.
+func Highlight(text string, terms []string) string {
+	want := map[string]bool{}
+	for _, t := range terms {
+		want[t] = true
+	}
+
+	type span struct{ start, end int }
+	matches := []span{}
+	start := -1
+	for i, r := range text + " " {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			if want[Normalize(text[start:i])] {
+				matches = append(matches, span{start, i})
+			}
+			start = -1
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	from, to := snippet(text, matches[0].start)
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range matches {
+		if m.start < from || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString(highlightOpen + html.EscapeString(text[m.start:m.end]) + highlightClose)
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// snippet returns byte bounds of at most snippetLength runes of text, starting a little before offset
+func snippet(text string, offset int) (int, int) {
+	if utf8.RuneCountInString(text) <= snippetLength {
+		return 0, len(text)
+	}
+
+	from := offset
+	for back := 0; from > 0 && back < snippetLength/4; back++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	// start on a word boundary
+	if from > 0 {
+		if i := strings.IndexFunc(text[from:offset], unicode.IsSpace); i >= 0 {
+			from += i + 1
+		}
+	}
+
+	to := from
+	for n := 0; to < len(text) && n < snippetLength; n++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+	return from, to
+}
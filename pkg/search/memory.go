@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// fieldWeights boosts matches on short, meaningful fields
+var fieldWeights = map[string]float64{
+	"title":           3,
+	"influencer_name": 2,
+	"tags":            2,
+	"description":     1,
+}
+
+// MemoryIndex is an in-memory inverted index Searcher, meant for tests and development
+type MemoryIndex struct {
+	ScoreWeight float64
+
+	mu       sync.RWMutex
+	docs     map[int64]Document
+	postings map[string]map[int64]float64
+}
+
+// NewMemoryIndex returns an empty MemoryIndex
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		ScoreWeight: DefaultScoreWeight,
+		docs:        map[int64]Document{},
+		postings:    map[string]map[int64]float64{},
+	}
+}
+
+// Index implements Searcher
+func (m *MemoryIndex) Index(_ context.Context, doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(doc.PostID)
+	m.docs[doc.PostID] = doc
+
+	fields := map[string][]string{
+		"title":           Tokenize(doc.Title),
+		"description":     Tokenize(doc.Description),
+		"influencer_name": Tokenize(doc.InfluencerName),
+	}
+	for _, tag := range doc.Tags {
+		fields["tags"] = append(fields["tags"], Tokenize(tag)...)
+	}
+	for field, terms := range fields {
+		for _, term := range terms {
+			if m.postings[term] == nil {
+				m.postings[term] = map[int64]float64{}
+			}
+			m.postings[term][doc.PostID] += fieldWeights[field]
+		}
+	}
+	return nil
+}
+
+// Remove implements Searcher
+func (m *MemoryIndex) Remove(_ context.Context, postID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(postID)
+	return nil
+}
+
+func (m *MemoryIndex) remove(postID int64) {
+	if _, ok := m.docs[postID]; !ok {
+		return
+	}
+	delete(m.docs, postID)
+	for term, posting := range m.postings {
+		delete(posting, postID)
+		if len(posting) == 0 {
+			delete(m.postings, term)
+		}
+	}
+}
+
+// Search implements Searcher, scoring documents by weighted term frequency times inverse document frequency
+func (m *MemoryIndex) Search(_ context.Context, q Query) (*Page, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	terms := Terms(q.Text)
+	relevance := map[int64]float64{}
+	for _, term := range terms {
+		posting := m.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		idf := math.Log(1 + float64(len(m.docs))/float64(len(posting)))
+		for id, tf := range posting {
+			relevance[id] += tf * idf
+		}
+	}
+
+	candidates := make([]candidate, 0, len(relevance))
+	for id, r := range relevance {
+		candidates = append(candidates, candidate{doc: m.docs[id], relevance: r})
+	}
+	return rank(candidates, terms, m.ScoreWeight, q.Limit, q.Offset), nil
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxCandidates caps rows fetched from MySQL before blending with posts.score, the response telling when more matched
+const maxCandidates = 200
+
+// MySQLSearcher searches published posts with MySQL FULLTEXT indexes on posts and post_tags.
+// MySQL keeps the indexes up to date, so Index and Remove do nothing.
+type MySQLSearcher struct {
+	DB          *sqlx.DB
+	ScoreWeight float64
+}
+
+// NewMySQLSearcher returns MySQLSearcher with default score weight
+func NewMySQLSearcher(db *sqlx.DB) *MySQLSearcher {
+	return &MySQLSearcher{DB: db, ScoreWeight: DefaultScoreWeight}
+}
+
+// Index implements Searcher
+func (s *MySQLSearcher) Index(_ context.Context, _ Document) error {
+	return nil
+}
+
+// Remove implements Searcher
+func (s *MySQLSearcher) Remove(_ context.Context, _ int64) error {
+	return nil
+}
+
+type row struct {
+	ID             int64   `db:"id"`
+	Title          string  `db:"title"`
+	Description    string  `db:"description"`
+	InfluencerName string  `db:"influencer_name"`
+	Score          int     `db:"score"`
+	Relevance      float64 `db:"relevance"`
+}
+
+// Search implements Searcher
+func (s *MySQLSearcher) Search(ctx context.Context, q Query) (*Page, error) {
+	terms := Terms(q.Text)
+	if len(terms) == 0 {
+		return &Page{Results: []Result{}}, nil
+	}
+	// the FULLTEXT indexes hold whole words while terms are stemmed, so terms match as prefixes
+	against := strings.Join(terms, "* ") + "*"
+
+	rows := []row{}
+	err := s.DB.SelectContext(ctx, &rows, `SELECT p.id, COALESCE(p.title, '') AS title, COALESCE(p.description, '') AS description, COALESCE(p.influencer_name, '') AS influencer_name, p.score,
+			MATCH (p.title, p.description, p.influencer_name) AGAINST (? IN BOOLEAN MODE) + COALESCE(t.relevance, 0) AS relevance
+		FROM posts p
+		LEFT JOIN (
+			SELECT post_id, SUM(MATCH (name) AGAINST (? IN BOOLEAN MODE)) AS relevance
+			FROM post_tags
+			WHERE deleted_at IS NULL AND MATCH (name) AGAINST (? IN BOOLEAN MODE)
+			GROUP BY post_id
+		) t ON t.post_id = p.id
+		WHERE p.deleted = false AND p.published = true
+			AND (MATCH (p.title, p.description, p.influencer_name) AGAINST (? IN BOOLEAN MODE) OR t.post_id IS NOT NULL)
+		ORDER BY relevance DESC
+		LIMIT ?`, against, against, against, against, maxCandidates+1)
+	if err != nil {
+		return nil, err
+	}
+	// one more row than ranked tells whether the candidates were cut off
+	truncated := len(rows) > maxCandidates
+	if truncated {
+		rows = rows[:maxCandidates]
+	}
+
+	tags, err := s.tags(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]candidate, len(rows))
+	for k, r := range rows {
+		candidates[k] = candidate{
+			doc:       Document{PostID: r.ID, Title: r.Title, Description: r.Description, InfluencerName: r.InfluencerName, Tags: tags[r.ID], Score: r.Score},
+			relevance: r.Relevance,
+		}
+	}
+	page := rank(candidates, terms, s.ScoreWeight, q.Limit, q.Offset)
+	page.Truncated = truncated
+	return page, nil
+}
+
+func (s *MySQLSearcher) tags(ctx context.Context, rows []row) (map[int64][]string, error) {
+	tags := map[int64][]string{}
+	if len(rows) == 0 {
+		return tags, nil
+	}
+
+	ids := make([]int64, len(rows))
+	for k, r := range rows {
+		ids[k] = r.ID
+	}
+	query, args, err := sqlx.In("SELECT post_id, COALESCE(name, '') AS name FROM post_tags WHERE post_id IN (?) AND deleted_at IS NULL", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []struct {
+		PostID int64  `db:"post_id"`
+		Name   string `db:"name"`
+	}{}
+	if err := s.DB.SelectContext(ctx, &result, query, args...); err != nil {
+		return nil, err
+	}
+	for _, t := range result {
+		tags[t.PostID] = append(tags[t.PostID], t.Name)
+	}
+	return tags, nil
+}
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+// DefaultScoreWeight is the share of posts.score in the final ranking
+const DefaultScoreWeight = 0.2
+
+// Document is the searchable content of a post
+type Document struct {
+	PostID         int64
+	Title          string
+	Description    string
+	InfluencerName string
+	Tags           []string
+	Score          int
+}
+
+// Query is a search request
+type Query struct {
+	Text   string
+	Limit  int
+	Offset int
+}
+
+// Result is a post matching a query
+type Result struct {
+	PostID     int64             `json:"post_id"`
+	Relevance  float64           `json:"relevance"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Page is a page of results out of Total ranked posts
+type Page struct {
+	Results []Result
+	Total   int
+	// Truncated is set when more posts matched than the searcher ranks, those past Total cannot be reached
+	Truncated bool
+}
+
+// Searcher finds published posts matching a query, most relevant first
+type Searcher interface {
+	// Index adds or replaces the document of a post
+	Index(ctx context.Context, doc Document) error
+	// Remove drops the document of a post
+	Remove(ctx context.Context, postID int64) error
+	Search(ctx context.Context, q Query) (*Page, error)
+}
+
+// candidate is a matching document with its raw text relevance
+type candidate struct {
+	doc       Document
+	relevance float64
+}
+
+// rank blends text relevance with posts.score, both normalized to the best candidate,
+// then returns the requested page with highlights
+func rank(candidates []candidate, terms []string, weight float64, limit, offset int) *Page {
+	maxRelevance, maxScore := 0.0, 0.0
+	for _, c := range candidates {
+		maxRelevance = math.Max(maxRelevance, c.relevance)
+		maxScore = math.Max(maxScore, float64(c.doc.Score))
+	}
+
+	results := make([]Result, len(candidates))
+	for k, c := range candidates {
+		relevance := 0.0
+		if maxRelevance > 0 {
+			relevance = c.relevance / maxRelevance
+		}
+		score := 0.0
+		if maxScore > 0 && c.doc.Score > 0 {
+			score = math.Log1p(float64(c.doc.Score)) / math.Log1p(maxScore)
+		}
+		results[k] = Result{PostID: c.doc.PostID, Relevance: (1-weight)*relevance + weight*score}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Relevance != results[j].Relevance {
+			return results[i].Relevance > results[j].Relevance
+		}
+		return results[i].PostID > results[j].PostID
+	})
+
+	page := &Page{Results: []Result{}, Total: len(results)}
+	if offset >= len(results) {
+		return page
+	}
+	results = results[offset:]
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+
+	docs := map[int64]Document{}
+	for _, c := range candidates {
+		docs[c.doc.PostID] = c.doc
+	}
+	for k := range results {
+		results[k].Highlights = highlights(docs[results[k].PostID], terms)
+	}
+	page.Results = results
+	return page
+}
+
+func highlights(doc Document, terms []string) map[string]string {
+	h := map[string]string{}
+	fields := map[string]string{"title": doc.Title, "description": doc.Description, "influencer_name": doc.InfluencerName}
+	for field, text := range fields {
+		if s := Highlight(text, terms); s != "" {
+			h[field] = s
+		}
+	}
+	for _, tag := range doc.Tags {
+		if s := Highlight(tag, terms); s != "" {
+			h["tags"] = s
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,75 @@
+package search_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/search"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"baju", "baju", "pesta", "murah"}, search.Tokenize("Baju-baju untuk pesta yang murah!"))
+	assert.Equal(t, []string{"tas", "kulit", "kece"}, search.Tokenize("Tasnya dari kulit, kece lah"))
+	assert.Equal(t, []string{"sepatu", "ilmu"}, search.Tokenize("sepatunya kamu ilmu"))
+	assert.Equal(t, []string{"seragam", "sekolah", "masalah"}, search.Tokenize("Seragam sekolah tanpa masalah"))
+	assert.Empty(t, search.Tokenize("dan yang di"))
+}
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "Inspirasi <em>baju</em> &amp; <em>bajunya</em> pesta", search.Highlight("Inspirasi baju & bajunya pesta", []string{"baju"}))
+	assert.Equal(t, "", search.Highlight("Inspirasi sepatu", []string{"baju"}))
+
+	long := "Lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua " +
+		"Ut enim ad minim veniam quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat batik " +
+		"Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur"
+	h := search.Highlight(long, []string{"batik"})
+	assert.Contains(t, h, "<em>batik</em>")
+	assert.True(t, len([]rune(h)) < len([]rune(long)))
+	assert.Equal(t, "…", string([]rune(h)[0]))
+}
+
+func TestMemoryIndex(t *testing.T) {
+	ctx := context.Background()
+	idx := search.NewMemoryIndex()
+
+	idx.Index(ctx, search.Document{PostID: 1, Title: "Gaya batik ke kantor", Description: "Padu padan kemeja batik", Score: 10})
+	idx.Index(ctx, search.Document{PostID: 2, Title: "Outfit liburan", Description: "Kain batik untuk pantai", Score: 500})
+	idx.Index(ctx, search.Document{PostID: 3, Title: "Sneakers putih", InfluencerName: "Raisa", Tags: []string{"Kemeja Batik Pria"}})
+	idx.Index(ctx, search.Document{PostID: 4, Title: "Jaket kulit"})
+
+	page, err := idx.Search(ctx, search.Query{Text: "batiknya"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, page.Total)
+	assert.False(t, page.Truncated)
+	results := page.Results
+	assert.Len(t, results, 3)
+	assert.Equal(t, int64(1), results[0].PostID)
+	assert.Equal(t, "Gaya <em>batik</em> ke kantor", results[0].Highlights["title"])
+	for _, r := range results {
+		if r.PostID == 3 {
+			assert.Equal(t, "Kemeja <em>Batik</em> Pria", r.Highlights["tags"])
+		}
+	}
+
+	idx.ScoreWeight = 0.9
+	page, _ = idx.Search(ctx, search.Query{Text: "batik"})
+	results = page.Results
+	assert.Equal(t, int64(2), results[0].PostID)
+
+	page, _ = idx.Search(ctx, search.Query{Text: "batik", Limit: 1, Offset: 1})
+	assert.Len(t, page.Results, 1)
+	assert.Equal(t, 3, page.Total)
+
+	idx.Remove(ctx, 2)
+	page, _ = idx.Search(ctx, search.Query{Text: "batik"})
+	results = page.Results
+	assert.Len(t, results, 2)
+
+	idx.Index(ctx, search.Document{PostID: 1, Title: "Gaya kasual"})
+	page, _ = idx.Search(ctx, search.Query{Text: "batik"})
+	results = page.Results
+	assert.Len(t, results, 1)
+	assert.Equal(t, int64(3), results[0].PostID)
+}
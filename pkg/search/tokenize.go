@@ -0,0 +1,80 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopwords are common Indonesian (and a few English) words carrying no meaning on their own
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		ada adalah agar akan aku anda antara apa apakah atau bagai bagaimana bahwa baik banyak
+		bagi beberapa begitu belum bisa buat bukan cara dalam dan dapat dari demikian dengan di
+		dia dimana ini itu ialah jadi jika juga ka kalau kami kamu karena ke kepada ketika kita
+		lagi lain lebih maka masih mau mereka meski nya oleh pada para per perlu pun saat saja
+		sambil sampai sang saya se sebagai sebuah secara sedang sehingga sejak selalu seperti
+		serta si sudah supaya tak tanpa tapi telah tentang tersebut tetapi tidak untuk usai yaitu
+		yakni yang
+		deh dong kah lah nih sih yuk
+		a an and for in of on or the to with`) {
+		stopwords[w] = true
+	}
+}
+
+// suffixes are Indonesian particles and possessive pronouns attached to words.
+// The "lah" particle is left out, as it ends too many base words like "sekolah" or "masalah".
+var suffixes = []string{"nya", "kah", "pun", "ku", "mu"}
+
+// minStemLength keeps short words like "ilmu" or "tamu" from being stripped
+const minStemLength = 3
+
+// Tokenize splits text into lowercase stemmed terms, dropping stopwords.
+// Reduplicated words such as "baju-baju" yield their base word once per occurrence.
+func Tokenize(text string) []string {
+	tokens := []string{}
+	for _, word := range words(text) {
+		if term := Normalize(word); term != "" {
+			tokens = append(tokens, term)
+		}
+	}
+	return tokens
+}
+
+// Normalize returns the search term of a single word, empty for stopwords
+func Normalize(word string) string {
+	word = strings.ToLower(word)
+	if stopwords[word] {
+		return ""
+	}
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= minStemLength {
+			word = strings.TrimSuffix(word, suffix)
+			break
+		}
+	}
+	if stopwords[word] {
+		return ""
+	}
+	return word
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Terms returns unique terms of text, in order of appearance
+func Terms(text string) []string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, t := range Tokenize(text) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,231 @@
+package subscription
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps subscriptions in process memory, they are lost on restart
+type MemoryStore struct {
+	mu            sync.Mutex
+	subscriptions []Subscription
+	quietHours    map[int64]QuietHours
+	states        map[string]JobState
+	notifications []Notification
+	inputs        []InputPrompt
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{quietHours: map[int64]QuietHours{}, states: map[string]JobState{}}
+}
+
+// Subscribe implements Store
+func (s *MemoryStore) Subscribe(_ context.Context, sub *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, old := range s.subscriptions {
+		if old.ChatID == sub.ChatID && old.Pattern == sub.Pattern {
+			s.subscriptions[k].Events, s.subscriptions[k].UpdatedAt = sub.Events, now
+			return nil
+		}
+	}
+	saved := *sub
+	saved.ID = int64(len(s.subscriptions) + 1)
+	saved.CreatedAt, saved.UpdatedAt = now, now
+	s.subscriptions = append(s.subscriptions, saved)
+	return nil
+}
+
+// Unsubscribe implements Store
+func (s *MemoryStore) Unsubscribe(_ context.Context, chatID int64, pattern string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, sub := range s.subscriptions {
+		if sub.ChatID == chatID && sub.Pattern == pattern {
+			s.subscriptions = append(s.subscriptions[:k], s.subscriptions[k+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Subscriptions implements Store
+func (s *MemoryStore) Subscriptions(_ context.Context, chatID int64) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subs := []Subscription{}
+	for _, sub := range s.subscriptions {
+		if chatID == 0 || sub.ChatID == chatID {
+			subs = append(subs, sub)
+		}
+	}
+	if chatID != 0 {
+		sort.Slice(subs, func(i, j int) bool { return subs[i].Pattern < subs[j].Pattern })
+	}
+	return subs, nil
+}
+
+// QuietHours implements Store
+func (s *MemoryStore) QuietHours(_ context.Context, chatID int64) (*QuietHours, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.quietHours[chatID]
+	if !ok {
+		return nil, nil
+	}
+	return &q, nil
+}
+
+// SetQuietHours implements Store
+func (s *MemoryStore) SetQuietHours(_ context.Context, q *QuietHours) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quietHours[q.ChatID] = *q
+	return nil
+}
+
+// RemoveQuietHours implements Store
+func (s *MemoryStore) RemoveQuietHours(_ context.Context, chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.quietHours, chatID)
+	return nil
+}
+
+// JobState implements Store
+func (s *MemoryStore) JobState(_ context.Context, job string) (*JobState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[job]
+	if !ok {
+		return nil, nil
+	}
+	return &st, nil
+}
+
+// SaveJobState implements Store
+func (s *MemoryStore) SaveJobState(_ context.Context, st *JobState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *st
+	saved.WebhookAt = s.states[st.Job].WebhookAt
+	s.states[st.Job] = saved
+	return nil
+}
+
+// MarkWebhook implements Store
+func (s *MemoryStore) MarkWebhook(_ context.Context, job string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.states[job]; ok {
+		st.WebhookAt = &at
+		s.states[job] = st
+	}
+	return nil
+}
+
+// AddNotification implements Store
+func (s *MemoryStore) AddNotification(_ context.Context, n *Notification) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, old := range s.notifications {
+		if old.ChatID == n.ChatID && old.Job == n.Job && old.BuildNumber == n.BuildNumber {
+			return false, nil
+		}
+	}
+	saved := *n
+	saved.ID = int64(len(s.notifications) + 1)
+	saved.CreatedAt, saved.UpdatedAt = time.Now(), time.Now()
+	s.notifications = append(s.notifications, saved)
+	return true, nil
+}
+
+// PendingNotifications implements Store
+func (s *MemoryStore) PendingNotifications(_ context.Context) ([]Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := []Notification{}
+	for _, n := range s.notifications {
+		if n.SentAt == nil {
+			pending = append(pending, n)
+		}
+	}
+	return pending, nil
+}
+
+// MarkSent implements Store
+func (s *MemoryStore) MarkSent(_ context.Context, ids ...int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, id := range ids {
+		s.notifications[id-1].SentAt = &now
+	}
+	return nil
+}
+
+// AddInputPrompt implements Store
+func (s *MemoryStore) AddInputPrompt(_ context.Context, p *InputPrompt) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, old := range s.inputs {
+		if old.ChatID == p.ChatID && old.Job == p.Job && old.BuildNumber == p.BuildNumber && old.InputID == p.InputID {
+			return false, nil
+		}
+	}
+	now := time.Now()
+	p.ID = int64(len(s.inputs) + 1)
+	p.MessageID, p.Status, p.DecidedBy, p.DecidedAt = 0, InputPending, "", nil
+	p.CreatedAt, p.UpdatedAt = now, now
+	s.inputs = append(s.inputs, *p)
+	return true, nil
+}
+
+// UnsentInputPrompts implements Store
+func (s *MemoryStore) UnsentInputPrompts(_ context.Context) ([]InputPrompt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unsent := []InputPrompt{}
+	for _, p := range s.inputs {
+		if p.MessageID == 0 && p.Status == InputPending {
+			unsent = append(unsent, p)
+		}
+	}
+	return unsent, nil
+}
+
+// MarkInputSent implements Store
+func (s *MemoryStore) MarkInputSent(_ context.Context, id, messageID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputs[id-1].MessageID, s.inputs[id-1].UpdatedAt = messageID, time.Now()
+	return nil
+}
+
+// InputPrompt implements Store
+func (s *MemoryStore) InputPrompt(_ context.Context, id int64) (*InputPrompt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || id > int64(len(s.inputs)) {
+		return nil, nil
+	}
+	p := s.inputs[id-1]
+	return &p, nil
+}
+
+// DecideInput implements Store
+func (s *MemoryStore) DecideInput(_ context.Context, p *InputPrompt) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.ID < 1 || p.ID > int64(len(s.inputs)) || s.inputs[p.ID-1].Status != InputPending {
+		return false, nil
+	}
+	now := time.Now()
+	saved := &s.inputs[p.ID-1]
+	saved.Status, saved.DecidedBy, saved.DecidedAt, saved.UpdatedAt = p.Status, p.DecidedBy, &now, now
+	return true, nil
+}
@@ -0,0 +1,191 @@
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+)
+
+// MySQLStore keeps subscriptions in the subscriptions, quiet_hours, job_states, notifications and input_prompts tables
+type MySQLStore struct {
+	DB *sqlx.DB
+}
+
+func now() string {
+	return time.Now().Format(config.DatabaseDatetimeFormat)
+}
+
+// Subscribe implements Store
+func (s *MySQLStore) Subscribe(ctx context.Context, sub *Subscription) error {
+	n := now()
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO subscriptions (chat_id, pattern, events, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE events = VALUES(events), updated_at = VALUES(updated_at)",
+		sub.ChatID, sub.Pattern, sub.Events, sub.CreatedBy, n, n)
+	return err
+}
+
+// Unsubscribe implements Store
+func (s *MySQLStore) Unsubscribe(ctx context.Context, chatID int64, pattern string) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM subscriptions WHERE chat_id = ? AND pattern = ?", chatID, pattern)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// Subscriptions implements Store
+func (s *MySQLStore) Subscriptions(ctx context.Context, chatID int64) ([]Subscription, error) {
+	subs := []Subscription{}
+	if chatID == 0 {
+		err := s.DB.SelectContext(ctx, &subs, "SELECT * FROM subscriptions ORDER BY id")
+		return subs, err
+	}
+	err := s.DB.SelectContext(ctx, &subs, "SELECT * FROM subscriptions WHERE chat_id = ? ORDER BY pattern", chatID)
+	return subs, err
+}
+
+// QuietHours implements Store
+func (s *MySQLStore) QuietHours(ctx context.Context, chatID int64) (*QuietHours, error) {
+	var q QuietHours
+	err := s.DB.GetContext(ctx, &q, "SELECT * FROM quiet_hours WHERE chat_id = ?", chatID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// SetQuietHours implements Store
+func (s *MySQLStore) SetQuietHours(ctx context.Context, q *QuietHours) error {
+	n := now()
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO quiet_hours (chat_id, starts_at, ends_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE starts_at = VALUES(starts_at), ends_at = VALUES(ends_at), updated_at = VALUES(updated_at)",
+		q.ChatID, q.StartsAt, q.EndsAt, n, n)
+	return err
+}
+
+// RemoveQuietHours implements Store
+func (s *MySQLStore) RemoveQuietHours(ctx context.Context, chatID int64) error {
+	_, err := s.DB.ExecContext(ctx, "DELETE FROM quiet_hours WHERE chat_id = ?", chatID)
+	return err
+}
+
+// JobState implements Store
+func (s *MySQLStore) JobState(ctx context.Context, job string) (*JobState, error) {
+	var st JobState
+	err := s.DB.GetContext(ctx, &st, "SELECT * FROM job_states WHERE job = ?", job)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// SaveJobState implements Store
+func (s *MySQLStore) SaveJobState(ctx context.Context, st *JobState) error {
+	n := now()
+	_, err := s.DB.ExecContext(ctx, "INSERT INTO job_states (job, build_number, result, created_at, updated_at) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE build_number = VALUES(build_number), result = VALUES(result), updated_at = VALUES(updated_at)",
+		st.Job, st.BuildNumber, st.Result, n, n)
+	return err
+}
+
+// MarkWebhook implements Store
+func (s *MySQLStore) MarkWebhook(ctx context.Context, job string, at time.Time) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE job_states SET webhook_at = ? WHERE job = ?", at.Format(config.DatabaseDatetimeFormat), job)
+	return err
+}
+
+// AddNotification implements Store
+func (s *MySQLStore) AddNotification(ctx context.Context, n *Notification) (bool, error) {
+	t := now()
+	res, err := s.DB.ExecContext(ctx, "INSERT IGNORE INTO notifications (chat_id, job, build_number, event, text, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		n.ChatID, n.Job, n.BuildNumber, n.Event, n.Text, t, t)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// PendingNotifications implements Store
+func (s *MySQLStore) PendingNotifications(ctx context.Context) ([]Notification, error) {
+	notifications := []Notification{}
+	err := s.DB.SelectContext(ctx, &notifications, "SELECT * FROM notifications WHERE sent_at IS NULL ORDER BY id")
+	return notifications, err
+}
+
+// MarkSent implements Store
+func (s *MySQLStore) MarkSent(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	t := now()
+	query, args, err := sqlx.In("UPDATE notifications SET sent_at = ?, updated_at = ? WHERE id IN (?)", t, t, ids)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// AddInputPrompt implements Store
+func (s *MySQLStore) AddInputPrompt(ctx context.Context, p *InputPrompt) (bool, error) {
+	t := now()
+	res, err := s.DB.ExecContext(ctx, "INSERT IGNORE INTO input_prompts (chat_id, message_id, job, build_number, input_id, text, status, decided_by, created_at, updated_at) VALUES (?, 0, ?, ?, ?, ?, ?, '', ?, ?)",
+		p.ChatID, p.Job, p.BuildNumber, p.InputID, p.Text, InputPending, t, t)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	p.MessageID, p.Status = 0, InputPending
+	p.ID, err = res.LastInsertId()
+	return true, err
+}
+
+// UnsentInputPrompts implements Store
+func (s *MySQLStore) UnsentInputPrompts(ctx context.Context) ([]InputPrompt, error) {
+	prompts := []InputPrompt{}
+	err := s.DB.SelectContext(ctx, &prompts, "SELECT * FROM input_prompts WHERE message_id = 0 AND status = ? ORDER BY id", InputPending)
+	return prompts, err
+}
+
+// MarkInputSent implements Store
+func (s *MySQLStore) MarkInputSent(ctx context.Context, id, messageID int64) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE input_prompts SET message_id = ?, updated_at = ? WHERE id = ?", messageID, now(), id)
+	return err
+}
+
+// InputPrompt implements Store
+func (s *MySQLStore) InputPrompt(ctx context.Context, id int64) (*InputPrompt, error) {
+	var p InputPrompt
+	err := s.DB.GetContext(ctx, &p, "SELECT * FROM input_prompts WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DecideInput implements Store
+func (s *MySQLStore) DecideInput(ctx context.Context, p *InputPrompt) (bool, error) {
+	t := now()
+	res, err := s.DB.ExecContext(ctx, "UPDATE input_prompts SET status = ?, decided_by = ?, decided_at = ?, updated_at = ? WHERE id = ? AND status = ?",
+		p.Status, p.DecidedBy, t, t, p.ID, InputPending)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
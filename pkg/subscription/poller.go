@@ -0,0 +1,485 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/analyzer"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins_jr"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/resource"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+// maxBuilds caps the builds of a job checked at once, older ones being skipped after a long downtime
+const maxBuilds = 20
+
+// Poller detects status changes of subscribed jobs and alerts their chats
+type Poller struct {
+	Store   Store
+	Jenkins jenkins_jr.JenkinsClient
+	API     *telegram.Bot
+	// Poll is the time between two checks of the subscribed jobs
+	Poll time.Duration
+	// Location is the time zone of quiet hours
+	Location *time.Location
+	// WebhookTTL is how long a job is left to webhooks after its last one, before being polled again
+	WebhookTTL time.Duration
+
+	// mu serializes polls and webhooks, so that an alert is not sent twice
+	mu sync.Mutex
+}
+
+// NewPoller returns Poller checking jobs every minute, with quiet hours in local time.
+// Jobs are polled again 5 minutes after their last webhook, so that a lost webhook delays alerts by a few polls only.
+func NewPoller(store Store, client jenkins_jr.JenkinsClient, api *telegram.Bot) *Poller {
+	return &Poller{Store: store, Jenkins: client, API: api, Poll: time.Minute, Location: time.Local, WebhookTTL: 5 * time.Minute}
+}
+
+// Run checks subscribed jobs and sends alerts every Poll until ctx is done
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.Poll)
+	defer ticker.Stop()
+	for {
+		p.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check detects new builds of subscribed jobs and builds waiting for input, then sends alerts of chats out of their quiet hours
+func (p *Poller) Check(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ctx = resource.NewContext(ctx, "poll-"+strconv.FormatInt(time.Now().UnixNano(), 10), "bot/poll", time.Now())
+	if err := p.Detect(ctx); err != nil {
+		log.ErrLog(ctx, err, "subscription", "Failed to detect builds")
+	}
+	if err := p.Deliver(ctx); err != nil {
+		log.ErrLog(ctx, err, "subscription", "Failed to deliver notifications")
+	}
+}
+
+// Receive alerts subscribers of a build completed according to a webhook, without waiting for the next poll.
+// The job is then left to webhooks for WebhookTTL.
+func (p *Poller) Receive(ctx context.Context, job string, number int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	subs, err := p.Store.Subscriptions(ctx, 0)
+	if err != nil {
+		return err
+	}
+	subscribed := false
+	for _, s := range subs {
+		subscribed = subscribed || s.Matches(job)
+	}
+	if !subscribed {
+		return nil
+	}
+	if err := p.Observe(ctx, subs, job, number); err != nil {
+		return err
+	}
+	if err := p.Store.MarkWebhook(ctx, job, time.Now()); err != nil {
+		return err
+	}
+	return p.Deliver(ctx)
+}
+
+// Detect saves notifications of builds completed since the previous check, skipping jobs recently notified by webhooks,
+// and prompts of input steps running builds are paused on
+func (p *Poller) Detect(ctx context.Context) error {
+	subs, err := p.Store.Subscriptions(ctx, 0)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	jobs, err := p.jobs(ctx, subs)
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		st, err := p.Store.JobState(ctx, j.FullName)
+		if err != nil {
+			return err
+		}
+		// webhooks only tell of completed builds, inputs are always polled
+		if err := p.prompt(ctx, subs, j, st); err != nil {
+			log.ErrLog(ctx, err, "subscription", "Failed to check inputs of "+j.FullName)
+		}
+		if st != nil && st.WebhookAt != nil && time.Since(*st.WebhookAt) < p.WebhookTTL {
+			continue
+		}
+		var last int64
+		if j.LastCompletedBuild != nil {
+			last = j.LastCompletedBuild.Number
+		}
+		if err := p.Observe(ctx, subs, j.FullName, last); err != nil {
+			log.ErrLog(ctx, err, "subscription", "Failed to check builds of "+j.FullName)
+		}
+	}
+	return nil
+}
+
+// prompt saves a prompt of every input step the running builds of a job are paused on, for every chat subscribed to the job
+func (p *Poller) prompt(ctx context.Context, subs []Subscription, j jenkins.Job, st *JobState) error {
+	if j.LastBuild == nil {
+		return nil
+	}
+	// builds up to the job state, or else up to the last completed build, are not running anymore
+	from := int64(1)
+	if st != nil {
+		from = st.BuildNumber + 1
+	} else if j.LastCompletedBuild != nil {
+		from = j.LastCompletedBuild.Number + 1
+	}
+	if j.LastBuild.Number-from >= maxBuilds {
+		from = j.LastBuild.Number - maxBuilds + 1
+	}
+
+	chats := []int64{}
+	seen := map[int64]bool{}
+	for _, s := range subs {
+		if s.Matches(j.FullName) && !seen[s.ChatID] {
+			chats = append(chats, s.ChatID)
+			seen[s.ChatID] = true
+		}
+	}
+	for number := from; number <= j.LastBuild.Number; number++ {
+		actions, err := p.Jenkins.PendingInputs(ctx, j.FullName, number)
+		if err != nil {
+			return err
+		}
+		if len(actions) == 0 {
+			continue
+		}
+		b, err := p.Jenkins.Build(ctx, j.FullName, number)
+		if err != nil {
+			return err
+		}
+		for k := range actions {
+			text := InputText(j.FullName, b, &actions[k])
+			for _, chatID := range chats {
+				in := &InputPrompt{ChatID: chatID, Job: j.FullName, BuildNumber: number, InputID: actions[k].ID, Text: text}
+				if _, err := p.Store.AddInputPrompt(ctx, in); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jobs returns top level jobs matched by subscriptions, and jobs in folders subscribed to by full name
+func (p *Poller) jobs(ctx context.Context, subs []Subscription) ([]jenkins.Job, error) {
+	all, err := p.Jenkins.Jobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs := []jenkins.Job{}
+	seen := map[string]bool{}
+	for _, j := range all {
+		for _, s := range subs {
+			if s.Matches(j.FullName) {
+				jobs = append(jobs, j)
+				seen[j.FullName] = true
+				break
+			}
+		}
+	}
+	for _, s := range subs {
+		if seen[s.Pattern] || strings.ContainsAny(s.Pattern, `*?[\`) {
+			continue
+		}
+		seen[s.Pattern] = true
+		j, err := p.Jenkins.Job(ctx, s.Pattern)
+		if err == jenkins.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, nil
+}
+
+// Observe notifies subscribers of a job of its builds completed up to last.
+// The first time a job is observed, its history is recorded without notifying.
+func (p *Poller) Observe(ctx context.Context, subs []Subscription, job string, last int64) error {
+	st, err := p.Store.JobState(ctx, job)
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		st = &JobState{Job: job, BuildNumber: last}
+		if last > 0 {
+			b, err := p.Jenkins.Build(ctx, job, last)
+			if err != nil {
+				return err
+			}
+			st.Result = result(st.Result, b.Result)
+		}
+		return p.Store.SaveJobState(ctx, st)
+	}
+	if last <= st.BuildNumber {
+		return nil
+	}
+
+	from := st.BuildNumber + 1
+	if last-from >= maxBuilds {
+		from = last - maxBuilds + 1
+	}
+	for number := from; number <= last; number++ {
+		b, err := p.Jenkins.Build(ctx, job, number)
+		if err == jenkins.ErrNotFound {
+			// deleted, e.g. by the job build discarder
+			st.BuildNumber = number
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if b.Building {
+			// an older build still running while a newer one completed, checked again next time
+			break
+		}
+		if err := p.notify(ctx, subs, job, b, st.Result); err != nil {
+			return err
+		}
+		st.BuildNumber, st.Result = number, result(st.Result, b.Result)
+	}
+	return p.Store.SaveJobState(ctx, st)
+}
+
+// result returns the result a job is in after a build, aborted and not built ones leaving it unchanged
+func result(previous, build string) string {
+	switch build {
+	case jenkins.ResultSuccess, jenkins.ResultUnstable, jenkins.ResultFailure:
+		return build
+	}
+	return previous
+}
+
+// notify saves a notification of a build for every chat subscribed to its event, once per chat.
+// Notifications of failed and unstable builds tell what their console output shows of the failure.
+func (p *Poller) notify(ctx context.Context, subs []Subscription, job string, b *jenkins.Build, previous string) error {
+	event := Event(previous, b.Result)
+	chats := map[int64]string{}
+	order := []int64{}
+	for _, s := range subs {
+		if !s.Matches(job) {
+			continue
+		}
+		current, ok := chats[s.ChatID]
+		switch {
+		case event != "" && s.Wants(event):
+			current = event
+		case s.Wants(EventAll) && !ok:
+			current = EventAll
+		}
+		if current == "" {
+			continue
+		}
+		if !ok {
+			order = append(order, s.ChatID)
+		}
+		chats[s.ChatID] = current
+	}
+
+	if len(order) == 0 {
+		return nil
+	}
+	summary, err := analyzer.Build(ctx, p.Jenkins, job, b)
+	if err != nil {
+		// alerted anyway, without a summary
+		log.ErrLog(ctx, err, "subscription", fmt.Sprintf("Failed to analyze %s #%d", job, b.Number))
+	}
+	for _, chatID := range order {
+		text := Text(job, b, chats[chatID])
+		if summary != nil {
+			text += "\n" + summary.String()
+		}
+		n := &Notification{ChatID: chatID, Job: job, BuildNumber: b.Number, Event: chats[chatID], Text: text}
+		if _, err := p.Store.AddNotification(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Text is the alert of an event of a build
+func Text(job string, b *jenkins.Build, event string) string {
+	elapsed := b.Elapsed().Round(time.Second)
+	switch event {
+	case EventFailure:
+		return fmt.Sprintf("%s #%d failed after %s\n%s", job, b.Number, elapsed, b.URL)
+	case EventUnstable:
+		return fmt.Sprintf("%s #%d is unstable\n%s", job, b.Number, b.URL)
+	case EventFixed:
+		return fmt.Sprintf("%s #%d is fixed\n%s", job, b.Number, b.URL)
+	}
+	return fmt.Sprintf("%s #%d finished with %s in %s\n%s", job, b.Number, b.Result, elapsed, b.URL)
+}
+
+// InputText is the prompt of an input step of a build
+func InputText(job string, b *jenkins.Build, a *jenkins.InputAction) string {
+	text := fmt.Sprintf("%s #%d is waiting for input: %s", job, b.Number, a.Message)
+	values, err := a.Defaults()
+	switch {
+	case err != nil:
+		text += "\nIt can only proceed on Jenkins, " + err.Error()
+	case len(values) > 0:
+		pairs := make([]string, len(values))
+		for k, v := range values {
+			pairs[k] = fmt.Sprintf("%s=%v", v.Name, v.Value)
+		}
+		text += "\nProceeding submits " + strings.Join(pairs, ", ")
+	}
+	return text + "\n" + b.URL + "input/"
+}
+
+// InputButtons are the buttons of a prompt, without Proceed when the input needs values which cannot be given from a chat
+func InputButtons(id int64, a *jenkins.InputAction) [][]telegram.InlineKeyboardButton {
+	data := InputCallback + ":" + strconv.FormatInt(id, 10)
+	buttons := []telegram.InlineKeyboardButton{}
+	if _, err := a.Defaults(); err == nil {
+		proceed := a.ProceedText
+		if proceed == "" {
+			proceed = "Proceed"
+		}
+		buttons = append(buttons, telegram.InlineKeyboardButton{Text: proceed, CallbackData: data + ":proceed"})
+	}
+	buttons = append(buttons, telegram.InlineKeyboardButton{Text: "Abort", CallbackData: data + ":abort"})
+	return [][]telegram.InlineKeyboardButton{buttons}
+}
+
+// FindInput returns the action of an input step, nil when the build is not paused on it
+func FindInput(actions []jenkins.InputAction, id string) *jenkins.InputAction {
+	for k, a := range actions {
+		if a.ID == id {
+			return &actions[k]
+		}
+	}
+	return nil
+}
+
+// Deliver sends pending notifications of chats out of their quiet hours, those of the same chat in as few messages as possible,
+// then their input prompts
+func (p *Poller) Deliver(ctx context.Context) error {
+	pending, err := p.Store.PendingNotifications(ctx)
+	if err != nil {
+		return err
+	}
+	byChat := map[int64][]Notification{}
+	order := []int64{}
+	for _, n := range pending {
+		if _, ok := byChat[n.ChatID]; !ok {
+			order = append(order, n.ChatID)
+		}
+		byChat[n.ChatID] = append(byChat[n.ChatID], n)
+	}
+
+	now := time.Now().In(p.Location)
+	for _, chatID := range order {
+		q, err := p.Store.QuietHours(ctx, chatID)
+		if err != nil {
+			return err
+		}
+		if q != nil && q.Contains(now) {
+			continue
+		}
+		for _, batch := range batches(byChat[chatID]) {
+			if err := p.send(ctx, chatID, batch); err != nil {
+				log.ErrLog(ctx, err, "subscription", fmt.Sprintf("Failed to notify chat %d", chatID))
+				break
+			}
+		}
+	}
+	return p.deliverInputs(ctx, now)
+}
+
+// deliverInputs sends prompts of chats out of their quiet hours, those of inputs answered meanwhile being dropped
+func (p *Poller) deliverInputs(ctx context.Context, now time.Time) error {
+	prompts, err := p.Store.UnsentInputPrompts(ctx)
+	if err != nil {
+		return err
+	}
+	pending := map[string][]jenkins.InputAction{}
+	for k := range prompts {
+		in := &prompts[k]
+		q, err := p.Store.QuietHours(ctx, in.ChatID)
+		if err != nil {
+			return err
+		}
+		if q != nil && q.Contains(now) {
+			continue
+		}
+		build := fmt.Sprintf("%s #%d", in.Job, in.BuildNumber)
+		actions, ok := pending[build]
+		if !ok {
+			if actions, err = p.Jenkins.PendingInputs(ctx, in.Job, in.BuildNumber); err != nil {
+				log.ErrLog(ctx, err, "subscription", "Failed to check inputs of "+build)
+				continue
+			}
+			pending[build] = actions
+		}
+		a := FindInput(actions, in.InputID)
+		if a == nil {
+			in.Status = InputGone
+			if _, err := p.Store.DecideInput(ctx, in); err != nil {
+				return err
+			}
+			continue
+		}
+		m, err := p.API.SendKeyboard(ctx, in.ChatID, in.Text, InputButtons(in.ID, a))
+		if err != nil {
+			log.ErrLog(ctx, err, "subscription", fmt.Sprintf("Failed to prompt chat %d", in.ChatID))
+			continue
+		}
+		if err := p.Store.MarkInputSent(ctx, in.ID, m.MessageID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Poller) send(ctx context.Context, chatID int64, batch []Notification) error {
+	texts := make([]string, len(batch))
+	ids := make([]int64, len(batch))
+	for k, n := range batch {
+		texts[k], ids[k] = n.Text, n.ID
+	}
+	if _, err := p.API.SendMessage(ctx, chatID, strings.Join(texts, "\n\n")); err != nil {
+		return err
+	}
+	return p.Store.MarkSent(ctx, ids...)
+}
+
+// batches splits notifications into groups whose texts fit in a message
+func batches(notifications []Notification) [][]Notification {
+	batches := [][]Notification{}
+	var batch []Notification
+	length := 0
+	for _, n := range notifications {
+		if len(batch) > 0 && length+2+len(n.Text) > telegram.MaxMessageLength {
+			batches = append(batches, batch)
+			batch, length = nil, 0
+		}
+		if len(batch) > 0 {
+			length += 2
+		}
+		batch = append(batch, n)
+		length += len(n.Text)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
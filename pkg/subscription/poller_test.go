@@ -0,0 +1,204 @@
+package subscription_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins/jenkinstest"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram/telegramtest"
+)
+
+func init() {
+	os.Setenv("ENV", "test")
+}
+
+// run triggers a build of job and finishes it with result
+func run(t *testing.T, j *jenkinstest.Server, job, result string) {
+	_, err := jenkins.New(j.URL, "bot", "token").Trigger(context.Background(), job, nil)
+	assert.Nil(t, err)
+	j.Finish(job, j.LastBuild(job).Number, result, "")
+}
+
+func TestPoller(t *testing.T) {
+	j := jenkinstest.NewServer()
+	defer j.Close()
+	tg := telegramtest.NewServer("123:token")
+	defer tg.Close()
+	ctx := context.Background()
+
+	j.AddJob("deploy-web")
+	j.AddJob("deploy-api")
+	j.AddJob("lint")
+	j.AddJob("mobile/android")
+	run(t, j, "deploy-web", jenkins.ResultFailure)
+
+	store := subscription.NewMemoryStore()
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 1, Pattern: "deploy-*", Events: "failure,fixed"})
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 1, Pattern: "deploy-web", Events: "all"})
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 2, Pattern: "mobile/android", Events: "unstable"})
+	p := subscription.NewPoller(store, jenkins.New(j.URL, "bot", "token"), tg.Bot())
+
+	// builds before the first check are not alerted
+	p.Check(ctx)
+	assert.Empty(t, tg.Sent())
+
+	run(t, j, "deploy-web", jenkins.ResultFailure)
+	run(t, j, "deploy-web", jenkins.ResultSuccess)
+	run(t, j, "deploy-api", jenkins.ResultFailure)
+	run(t, j, "lint", jenkins.ResultFailure)
+	run(t, j, "mobile/android", jenkins.ResultUnstable)
+	p.Check(ctx)
+
+	sent := tg.Sent()
+	if assert.Len(t, sent, 2) {
+		// a single message per chat, each build alerted once
+		assert.Equal(t, int64(1), sent[0].ChatID)
+		texts := strings.Split(sent[0].Text, "\n\n")
+		if assert.Len(t, texts, 3) {
+			assert.True(t, strings.HasPrefix(texts[0], "deploy-web #2 finished with FAILURE in "), texts[0])
+			assert.True(t, strings.HasPrefix(texts[1], "deploy-web #3 is fixed\n"), texts[1])
+			assert.True(t, strings.HasPrefix(texts[2], "deploy-api #1 failed after "), texts[2])
+		}
+		assert.Equal(t, int64(2), sent[1].ChatID)
+		assert.True(t, strings.HasPrefix(sent[1].Text, "mobile/android #1 is unstable\n"), sent[1].Text)
+	}
+
+	// nothing new, nothing sent again
+	p.Check(ctx)
+	assert.Len(t, tg.Sent(), 2)
+
+	// alerts wait for the end of quiet hours
+	now := time.Now()
+	store.SetQuietHours(ctx, &subscription.QuietHours{ChatID: 1, StartsAt: now.Hour() * 60, EndsAt: (now.Hour() + 1) % 24 * 60})
+	run(t, j, "deploy-api", jenkins.ResultFailure)
+	run(t, j, "deploy-api", jenkins.ResultSuccess)
+	p.Check(ctx)
+	assert.Len(t, tg.Sent(), 2)
+
+	store.RemoveQuietHours(ctx, 1)
+	p.Check(ctx)
+	sent = tg.Sent()
+	if assert.Len(t, sent, 3) {
+		assert.True(t, strings.HasPrefix(sent[2].Text, "deploy-api #3 is fixed\n"), sent[2].Text)
+	}
+}
+
+func TestPollerRunningBuild(t *testing.T) {
+	j := jenkinstest.NewServer()
+	defer j.Close()
+	tg := telegramtest.NewServer("123:token")
+	defer tg.Close()
+	ctx := context.Background()
+	client := jenkins.New(j.URL, "bot", "token")
+
+	j.AddJob("deploy")
+	store := subscription.NewMemoryStore()
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 1, Pattern: "deploy", Events: "all"})
+	p := subscription.NewPoller(store, client, tg.Bot())
+	p.Check(ctx)
+
+	// #1 is still running when #2 completes, both are alerted once #1 ends
+	client.Trigger(ctx, "deploy", nil)
+	client.Trigger(ctx, "deploy", nil)
+	j.Finish("deploy", 2, jenkins.ResultSuccess, "")
+	p.Check(ctx)
+	assert.Empty(t, tg.Sent())
+
+	j.Finish("deploy", 1, jenkins.ResultFailure, "--- FAIL: TestDeploy (0.01s)\nFAIL\tgithub.com/a/deploy\t0.01s\n")
+	p.Check(ctx)
+	sent := tg.Sent()
+	if assert.Len(t, sent, 1) {
+		texts := strings.Split(sent[0].Text, "\n\n")
+		assert.Len(t, texts, 2)
+		assert.True(t, strings.HasPrefix(texts[0], "deploy #1 finished with FAILURE"), texts[0])
+		// failures are summarized
+		assert.True(t, strings.HasSuffix(texts[0], "\nProbable cause: Go test failure\n> FAIL\tgithub.com/a/deploy\t0.01s\nFailing tests: TestDeploy"), texts[0])
+		assert.True(t, strings.HasPrefix(texts[1], "deploy #2 finished with SUCCESS"), texts[1])
+	}
+}
+
+func TestPollerWebhook(t *testing.T) {
+	j := jenkinstest.NewServer()
+	defer j.Close()
+	tg := telegramtest.NewServer("123:token")
+	defer tg.Close()
+	ctx := context.Background()
+
+	j.AddJob("deploy")
+	j.AddJob("lint")
+	store := subscription.NewMemoryStore()
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 1, Pattern: "deploy", Events: "all"})
+	p := subscription.NewPoller(store, jenkins.New(j.URL, "bot", "token"), tg.Bot())
+	p.Check(ctx)
+
+	// jobs without subscribers are ignored
+	run(t, j, "lint", jenkins.ResultFailure)
+	assert.Nil(t, p.Receive(ctx, "lint", 1))
+	assert.Empty(t, tg.Sent())
+
+	run(t, j, "deploy", jenkins.ResultSuccess)
+	assert.Nil(t, p.Receive(ctx, "deploy", 1))
+	assert.Len(t, tg.Sent(), 1)
+
+	// left to webhooks, the job is not polled
+	run(t, j, "deploy", jenkins.ResultFailure)
+	p.Check(ctx)
+	assert.Len(t, tg.Sent(), 1)
+
+	// until they stop for WebhookTTL
+	p.WebhookTTL = 0
+	p.Check(ctx)
+	sent := tg.Sent()
+	if assert.Len(t, sent, 2) {
+		assert.True(t, strings.HasPrefix(sent[1].Text, "deploy #2 finished with FAILURE"), sent[1].Text)
+	}
+}
+
+func TestPollerInputs(t *testing.T) {
+	j := jenkinstest.NewServer()
+	defer j.Close()
+	tg := telegramtest.NewServer("123:token")
+	defer tg.Close()
+	ctx := context.Background()
+	client := jenkins.New(j.URL, "bot", "token")
+
+	j.AddJob("deploy")
+	store := subscription.NewMemoryStore()
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 1, Pattern: "deploy", Events: "failure"})
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 1, Pattern: "deploy*", Events: "fixed"})
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 2, Pattern: "deploy", Events: "failure"})
+	now := time.Now()
+	store.SetQuietHours(ctx, &subscription.QuietHours{ChatID: 2, StartsAt: now.Hour() * 60, EndsAt: (now.Hour() + 1) % 24 * 60})
+	p := subscription.NewPoller(store, client, tg.Bot())
+
+	client.Trigger(ctx, "deploy", nil)
+	token := jenkins.ParameterDefinition{Name: "TOKEN", Type: jenkins.ParamPassword}
+	j.SetInput("deploy", 1, jenkins.InputAction{ID: "Release", Message: "Release?", Inputs: []jenkins.ParameterDefinition{token}})
+
+	// every subscribed chat is prompted once, whatever its events
+	p.Check(ctx)
+	p.Check(ctx)
+	sent := tg.Sent()
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, int64(1), sent[0].ChatID)
+		assert.Equal(t, "deploy #1 is waiting for input: Release?\nIt can only proceed on Jenkins, TOKEN is a password, which cannot be given here\n"+j.URL+"/job/deploy/1/input/", sent[0].Text)
+		// passwords cannot be given from a chat
+		assert.Equal(t, []string{"input:1:abort"}, sent[0].Buttons())
+	}
+
+	// the input was answered on Jenkins before the end of quiet hours
+	assert.Nil(t, client.AbortInput(ctx, "deploy", 1, "Release"))
+	store.RemoveQuietHours(ctx, 2)
+	p.Check(ctx)
+	assert.Len(t, tg.Sent(), 1)
+	in, err := store.InputPrompt(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, subscription.InputGone, in.Status)
+}
@@ -0,0 +1,261 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+)
+
+// Events a chat can subscribe to
+const (
+	// EventFailure is a build failing after one which did not
+	EventFailure = "failure"
+	// EventFixed is a build succeeding after a failed or unstable one
+	EventFixed = "fixed"
+	// EventUnstable is a build unstable after one which was not
+	EventUnstable = "unstable"
+	// EventAll is every completed build
+	EventAll = "all"
+)
+
+// Events lists events in the order they are shown
+var Events = []string{EventFailure, EventFixed, EventUnstable, EventAll}
+
+// DefaultEvents are subscribed to when none are given
+var DefaultEvents = []string{EventFailure, EventFixed}
+
+// Subscription alerts a chat of builds of jobs whose full name matches Pattern, e.g. deploy-* or mobile/*
+type Subscription struct {
+	ID      int64  `db:"id"`
+	ChatID  int64  `db:"chat_id"`
+	Pattern string `db:"pattern"`
+	// Events are comma separated
+	Events string `db:"events"`
+	// CreatedBy is the Telegram user who subscribed
+	CreatedBy int64     `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Matches tells whether a job is covered by the subscription
+func (s *Subscription) Matches(job string) bool {
+	ok, _ := path.Match(s.Pattern, job)
+	return ok
+}
+
+// Wants tells whether the subscription includes an event
+func (s *Subscription) Wants(event string) bool {
+	for _, e := range strings.Split(s.Events, ",") {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseEvents parses comma separated events, returning them in the order of Events
+func ParseEvents(s string) ([]string, error) {
+	given := map[string]bool{}
+	for _, e := range strings.Split(strings.ToLower(s), ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		known := false
+		for _, k := range Events {
+			known = known || k == e
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event %s, expected %s", e, strings.Join(Events, ", "))
+		}
+		given[e] = true
+	}
+	events := []string{}
+	for _, e := range Events {
+		if given[e] {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no events given, expected %s", strings.Join(Events, ", "))
+	}
+	return events, nil
+}
+
+// ValidPattern checks the syntax of a job pattern
+func ValidPattern(pattern string) error {
+	if pattern == "" || len(pattern) > 255 {
+		return fmt.Errorf("job pattern must have 1 to 255 characters")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid job pattern %s", pattern)
+	}
+	return nil
+}
+
+// Event returns the event of a completed build given the result of the previous one, empty when the status did not change
+func Event(previous, result string) string {
+	switch {
+	case result == jenkins.ResultFailure && previous != jenkins.ResultFailure:
+		return EventFailure
+	case result == jenkins.ResultUnstable && previous != jenkins.ResultUnstable:
+		return EventUnstable
+	case result == jenkins.ResultSuccess && (previous == jenkins.ResultFailure || previous == jenkins.ResultUnstable):
+		return EventFixed
+	}
+	return ""
+}
+
+// QuietHours are daily hours during which alerts of a chat wait, possibly spanning midnight
+type QuietHours struct {
+	ID     int64 `db:"id"`
+	ChatID int64 `db:"chat_id"`
+	// StartsAt and EndsAt are minutes since midnight
+	StartsAt  int       `db:"starts_at"`
+	EndsAt    int       `db:"ends_at"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// ParseQuietHours parses hours formatted as HH:MM-HH:MM
+func ParseQuietHours(s string) (starts, ends int, err error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("quiet hours must be formatted as HH:MM-HH:MM, e.g. 22:00-07:00")
+	}
+	if starts, err = parseClock(parts[0]); err != nil {
+		return 0, 0, err
+	}
+	if ends, err = parseClock(parts[1]); err != nil {
+		return 0, 0, err
+	}
+	if starts == ends {
+		return 0, 0, fmt.Errorf("quiet hours must not start and end at the same time")
+	}
+	return starts, ends, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %s, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains tells whether t, in the time zone of the quiet hours, is quiet
+func (q *QuietHours) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if q.StartsAt < q.EndsAt {
+		return minute >= q.StartsAt && minute < q.EndsAt
+	}
+	return minute >= q.StartsAt || minute < q.EndsAt
+}
+
+func (q *QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.StartsAt/60, q.StartsAt%60, q.EndsAt/60, q.EndsAt%60)
+}
+
+// JobState is the last completed build of a job seen by the poller
+type JobState struct {
+	ID          int64  `db:"id"`
+	Job         string `db:"job"`
+	BuildNumber int64  `db:"build_number"`
+	// Result is the last success, unstable or failure, aborted builds leaving it unchanged
+	Result    string    `db:"result"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+	// WebhookAt is when Jenkins last notified a build of the job, nil when it never did
+	WebhookAt *time.Time `db:"webhook_at"`
+}
+
+// Notification is an alert of a build sent, or waiting to be sent, to a chat.
+// A chat is notified at most once of a build.
+type Notification struct {
+	ID          int64      `db:"id"`
+	ChatID      int64      `db:"chat_id"`
+	Job         string     `db:"job"`
+	BuildNumber int64      `db:"build_number"`
+	Event       string     `db:"event"`
+	Text        string     `db:"text"`
+	SentAt      *time.Time `db:"sent_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at"`
+}
+
+// Statuses of input prompts
+const (
+	InputPending   = "pending"
+	InputProceeded = "proceeded"
+	InputAborted   = "aborted"
+	// InputGone is an input answered on Jenkins, or whose build ended, before anyone pressed a button
+	InputGone = "gone"
+)
+
+// InputCallback is the name of the callback of the buttons of input prompts
+const InputCallback = "input"
+
+// InputPrompt asks a chat to proceed or abort an input step a build is paused on.
+// A chat is prompted at most once of an input.
+type InputPrompt struct {
+	ID     int64 `db:"id"`
+	ChatID int64 `db:"chat_id"`
+	// MessageID is the prompt sent to the chat, 0 until it is sent
+	MessageID   int64  `db:"message_id"`
+	Job         string `db:"job"`
+	BuildNumber int64  `db:"build_number"`
+	InputID     string `db:"input_id"`
+	Text        string `db:"text"`
+	Status      string `db:"status"`
+	// DecidedBy is the name of the user who pressed a button
+	DecidedBy string     `db:"decided_by"`
+	DecidedAt *time.Time `db:"decided_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+}
+
+// Store keeps subscriptions and the poller state
+type Store interface {
+	// Subscribe creates the subscription of a chat to a pattern, or replaces its events
+	Subscribe(ctx context.Context, s *Subscription) error
+	// Unsubscribe removes the subscription of a chat to a pattern, returning false when there was none
+	Unsubscribe(ctx context.Context, chatID int64, pattern string) (bool, error)
+	// Subscriptions returns subscriptions of a chat, or of all chats when chatID is 0
+	Subscriptions(ctx context.Context, chatID int64) ([]Subscription, error)
+
+	// QuietHours returns quiet hours of a chat, nil when it has none
+	QuietHours(ctx context.Context, chatID int64) (*QuietHours, error)
+	// SetQuietHours creates or replaces quiet hours of a chat
+	SetQuietHours(ctx context.Context, q *QuietHours) error
+	// RemoveQuietHours removes quiet hours of a chat
+	RemoveQuietHours(ctx context.Context, chatID int64) error
+
+	// JobState returns the state of a job, nil when it was never polled
+	JobState(ctx context.Context, job string) (*JobState, error)
+	// SaveJobState creates or updates the build number and result of a job
+	SaveJobState(ctx context.Context, st *JobState) error
+	// MarkWebhook records that Jenkins notified a build of a job, when the job has a state
+	MarkWebhook(ctx context.Context, job string, at time.Time) error
+
+	// AddNotification saves a notification to send, returning false when the chat was already notified of the build
+	AddNotification(ctx context.Context, n *Notification) (bool, error)
+	// PendingNotifications returns notifications not sent yet, oldest first
+	PendingNotifications(ctx context.Context) ([]Notification, error)
+	// MarkSent records that notifications were sent
+	MarkSent(ctx context.Context, ids ...int64) error
+
+	// AddInputPrompt saves a pending prompt to send, returning false when the chat was already prompted of the input
+	AddInputPrompt(ctx context.Context, p *InputPrompt) (bool, error)
+	// UnsentInputPrompts returns pending prompts not sent yet, oldest first
+	UnsentInputPrompts(ctx context.Context) ([]InputPrompt, error)
+	// MarkInputSent records the message a prompt was sent as
+	MarkInputSent(ctx context.Context, id, messageID int64) error
+	// InputPrompt returns a prompt, nil when it does not exist
+	InputPrompt(ctx context.Context, id int64) (*InputPrompt, error)
+	// DecideInput saves the status of a pending prompt and who decided it, returning false when it was already decided
+	DecideInput(ctx context.Context, p *InputPrompt) (bool, error)
+}
@@ -0,0 +1,76 @@
+package subscription_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+)
+
+func TestParseEvents(t *testing.T) {
+	events, err := subscription.ParseEvents("all, FAILURE,fixed,failure")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"failure", "fixed", "all"}, events)
+
+	_, err = subscription.ParseEvents("failure,broken")
+	assert.EqualError(t, err, "unknown event broken, expected failure, fixed, unstable, all")
+	_, err = subscription.ParseEvents(",")
+	assert.NotNil(t, err)
+}
+
+func TestSubscription(t *testing.T) {
+	s := subscription.Subscription{Pattern: "deploy-*", Events: "failure,fixed"}
+	assert.True(t, s.Matches("deploy-web"))
+	assert.False(t, s.Matches("mobile/deploy-web"))
+	assert.True(t, s.Wants(subscription.EventFixed))
+	assert.False(t, s.Wants(subscription.EventAll))
+
+	assert.Nil(t, subscription.ValidPattern("mobile/*"))
+	assert.NotNil(t, subscription.ValidPattern("deploy-["))
+	assert.NotNil(t, subscription.ValidPattern(""))
+}
+
+func TestEvent(t *testing.T) {
+	cases := []struct{ previous, result, event string }{
+		{"", jenkins.ResultFailure, subscription.EventFailure},
+		{jenkins.ResultSuccess, jenkins.ResultFailure, subscription.EventFailure},
+		{jenkins.ResultFailure, jenkins.ResultFailure, ""},
+		{jenkins.ResultSuccess, jenkins.ResultUnstable, subscription.EventUnstable},
+		{jenkins.ResultUnstable, jenkins.ResultFailure, subscription.EventFailure},
+		{jenkins.ResultFailure, jenkins.ResultSuccess, subscription.EventFixed},
+		{jenkins.ResultUnstable, jenkins.ResultSuccess, subscription.EventFixed},
+		{jenkins.ResultSuccess, jenkins.ResultSuccess, ""},
+		{jenkins.ResultFailure, jenkins.ResultAborted, ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.event, subscription.Event(c.previous, c.result), c.previous+" then "+c.result)
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	starts, ends, err := subscription.ParseQuietHours("22:00-07:30")
+	assert.Nil(t, err)
+	q := subscription.QuietHours{StartsAt: starts, EndsAt: ends}
+	assert.Equal(t, "22:00-07:30", q.String())
+
+	at := func(clock string) time.Time {
+		t, _ := time.Parse("15:04", clock)
+		return t
+	}
+	assert.True(t, q.Contains(at("23:59")))
+	assert.True(t, q.Contains(at("07:29")))
+	assert.False(t, q.Contains(at("07:30")))
+	assert.False(t, q.Contains(at("12:00")))
+
+	q = subscription.QuietHours{StartsAt: 12 * 60, EndsAt: 13 * 60}
+	assert.True(t, q.Contains(at("12:30")))
+	assert.False(t, q.Contains(at("13:00")))
+
+	for _, invalid := range []string{"22:00", "25:00-07:00", "22:00-22:00", "10pm-7am"} {
+		_, _, err := subscription.ParseQuietHours(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
@@ -0,0 +1,229 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the Telegram Bot API
+const DefaultURL = "https://api.telegram.org"
+
+// MaxMessageLength is the longest text accepted by sendMessage
+const MaxMessageLength = 4096
+
+// User is a Telegram user or bot
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// Chat is a private chat, group or channel
+type Chat struct {
+	ID    int64  `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title,omitempty"`
+}
+
+// Message is a chat message
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text,omitempty"`
+}
+
+// InlineKeyboardButton is a button shown below a message, sending its CallbackData back when pressed
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// InlineKeyboardMarkup is the keyboard of a message, as rows of buttons
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// CallbackQuery is a press on an inline keyboard button
+type CallbackQuery struct {
+	ID   string `json:"id"`
+	From User   `json:"from"`
+	// Message is the message with the button, nil when too old
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// Update is an incoming update
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+// Error is an unsuccessful Bot API response
+type Error struct {
+	Code        int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("telegram: %d %s", e.Code, e.Description)
+}
+
+type result struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+// Bot calls the Telegram Bot API
+type Bot struct {
+	Token string
+	URL   string
+	HTTP  *http.Client
+}
+
+// New returns Bot authenticated with token, calling the API at url or DefaultURL when empty
+func New(token, url string) *Bot {
+	if url == "" {
+		url = DefaultURL
+	}
+	// long polling requests last up to their timeout
+	return &Bot{Token: token, URL: strings.TrimSuffix(url, "/"), HTTP: &http.Client{Timeout: 90 * time.Second}}
+}
+
+// Call calls a Bot API method with JSON params, decoding its result into v when not nil
+func (b *Bot) Call(ctx context.Context, method string, params, v interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return b.post(ctx, method, "application/json", bytes.NewReader(body), v)
+}
+
+func (b *Bot) post(ctx context.Context, method, contentType string, body io.Reader, v interface{}) error {
+	req, err := http.NewRequest("POST", b.URL+"/bot"+b.Token+"/"+method, body)
+	if err != nil {
+		return transportError(method, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := b.HTTP.Do(req.WithContext(ctx))
+	if err != nil {
+		return transportError(method, err)
+	}
+	defer resp.Body.Close()
+
+	var r result
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("telegram: %s returned %d: %v", method, resp.StatusCode, err)
+	}
+	if !r.OK {
+		return &Error{Code: r.ErrorCode, Description: r.Description}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, v)
+}
+
+// transportError drops the URL of requests from their errors, as it contains the bot token
+func transportError(method string, err error) error {
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	return fmt.Errorf("telegram: %s: %v", method, err)
+}
+
+// GetMe returns the bot user
+func (b *Bot) GetMe(ctx context.Context) (*User, error) {
+	var u User
+	if err := b.Call(ctx, "getMe", struct{}{}, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUpdates long polls updates following offset for up to timeout
+func (b *Bot) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	params := map[string]interface{}{"offset": offset, "timeout": int(timeout.Seconds())}
+	updates := []Update{}
+	err := b.Call(ctx, "getUpdates", params, &updates)
+	return updates, err
+}
+
+// SendMessage sends a plain text message to a chat
+func (b *Bot) SendMessage(ctx context.Context, chatID int64, text string) (*Message, error) {
+	var m Message
+	if err := b.Call(ctx, "sendMessage", map[string]interface{}{"chat_id": chatID, "text": text}, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SendKeyboard sends a plain text message with inline keyboard buttons to a chat
+func (b *Bot) SendKeyboard(ctx context.Context, chatID int64, text string, keyboard [][]InlineKeyboardButton) (*Message, error) {
+	params := map[string]interface{}{"chat_id": chatID, "text": text, "reply_markup": InlineKeyboardMarkup{InlineKeyboard: keyboard}}
+	var m Message
+	if err := b.Call(ctx, "sendMessage", params, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// EditMessageText replaces the text of a message sent by the bot, removing its keyboard
+func (b *Bot) EditMessageText(ctx context.Context, chatID, messageID int64, text string) error {
+	return b.Call(ctx, "editMessageText", map[string]interface{}{"chat_id": chatID, "message_id": messageID, "text": text}, nil)
+}
+
+// EditMessageKeyboard replaces the text of a message sent by the bot and its keyboard
+func (b *Bot) EditMessageKeyboard(ctx context.Context, chatID, messageID int64, text string, keyboard [][]InlineKeyboardButton) error {
+	params := map[string]interface{}{"chat_id": chatID, "message_id": messageID, "text": text, "reply_markup": InlineKeyboardMarkup{InlineKeyboard: keyboard}}
+	return b.Call(ctx, "editMessageText", params, nil)
+}
+
+// AnswerCallbackQuery stops the progress shown on a pressed button, text being shown to the user when not empty
+func (b *Bot) AnswerCallbackQuery(ctx context.Context, id, text string) error {
+	params := map[string]interface{}{"callback_query_id": id}
+	if text != "" {
+		params["text"] = text
+	}
+	return b.Call(ctx, "answerCallbackQuery", params, nil)
+}
+
+// SendDocument uploads a file to a chat, with an optional caption
+func (b *Bot) SendDocument(ctx context.Context, chatID int64, filename string, data []byte, caption string) (*Message, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("chat_id", strconv.FormatInt(chatID, 10))
+	if caption != "" {
+		form.WriteField("caption", caption)
+	}
+	file, err := form.CreateFormFile("document", filename)
+	if err != nil {
+		return nil, err
+	}
+	file.Write(data)
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	var m Message
+	if err := b.post(ctx, "sendDocument", form.FormDataContentType(), &body, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,23 @@
+package telegram_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+func TestTransportError(t *testing.T) {
+	server := httptest.NewServer(nil)
+	server.Close()
+
+	_, err := telegram.New("123:secret", server.URL).GetMe(context.Background())
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "telegram: getMe: "), err.Error())
+		assert.NotContains(t, err.Error(), "secret", "errors do not leak the token")
+	}
+}
@@ -0,0 +1,263 @@
+// Package telegramtest provides a fake Telegram Bot API for tests
+package telegramtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+// BotUser is the bot user returned by getMe
+var BotUser = telegram.User{ID: 1, IsBot: true, FirstName: "Jenkins Jr", Username: "jenkins_jr_bot"}
+
+// Sent is a call made by the bot
+type Sent struct {
+	Method string
+	ChatID int64
+	// MessageID is the message sent by sendMessage, or edited by editMessageText
+	MessageID int64
+	Text      string
+	Params    map[string]interface{}
+	// Filename and Document are the file uploaded by sendDocument
+	Filename string
+	Document []byte
+}
+
+// Buttons returns callback data of the inline keyboard buttons of a sent message
+func (s Sent) Buttons() []string {
+	markup, _ := s.Params["reply_markup"].(map[string]interface{})
+	rows, _ := markup["inline_keyboard"].([]interface{})
+	data := []string{}
+	for _, row := range rows {
+		buttons, _ := row.([]interface{})
+		for _, b := range buttons {
+			button, _ := b.(map[string]interface{})
+			d, _ := button["callback_data"].(string)
+			data = append(data, d)
+		}
+	}
+	return data
+}
+
+// Server is an in-memory Bot API. Users talk to the bot with Send, and the bot calls are recorded.
+type Server struct {
+	*httptest.Server
+	Token string
+
+	mu        sync.Mutex
+	updates   []telegram.Update
+	sent      []Sent
+	nextID    int64
+	messageID int64
+	// changed is closed and replaced whenever updates or sent change
+	changed chan struct{}
+}
+
+// NewServer starts a Server accepting given bot token
+func NewServer(token string) *Server {
+	s := &Server{Token: token, nextID: 1, messageID: 1, changed: make(chan struct{})}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Bot returns a telegram.Bot calling this server
+func (s *Server) Bot() *telegram.Bot {
+	return telegram.New(s.Token, s.URL)
+}
+
+// Send makes a user send text to the bot in a chat, users being named @user<ID>
+func (s *Server) Send(chatID, userID int64, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updates = append(s.updates, telegram.Update{UpdateID: s.nextID, Message: &telegram.Message{
+		MessageID: s.newMessageID(),
+		From:      &telegram.User{ID: userID, FirstName: "User", Username: "user" + strconv.FormatInt(userID, 10)},
+		Chat:      telegram.Chat{ID: chatID, Type: "private"},
+		Date:      time.Now().Unix(),
+		Text:      text,
+	}})
+	s.nextID++
+	s.notify()
+}
+
+// Press makes a user press a button of a message sent by the bot
+func (s *Server) Press(chatID, userID, messageID int64, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := &telegram.CallbackQuery{
+		ID:      "query-" + strconv.FormatInt(s.nextID, 10),
+		From:    telegram.User{ID: userID, FirstName: "User", Username: "user" + strconv.FormatInt(userID, 10)},
+		Message: &telegram.Message{MessageID: messageID, From: &BotUser, Chat: telegram.Chat{ID: chatID, Type: "private"}},
+		Data:    data,
+	}
+	if messageID == 0 {
+		// Telegram leaves out messages too old to be edited
+		q.Message = nil
+	}
+	s.updates = append(s.updates, telegram.Update{UpdateID: s.nextID, CallbackQuery: q})
+	s.nextID++
+	s.notify()
+}
+
+// Sent returns calls made by the bot so far
+func (s *Server) Sent() []Sent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Sent{}, s.sent...)
+}
+
+// Wait blocks until the bot made n calls or timeout elapsed, returning calls made so far
+func (s *Server) Wait(n int, timeout time.Duration) []Sent {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		sent, changed := append([]Sent{}, s.sent...), s.changed
+		s.mu.Unlock()
+		if len(sent) >= n {
+			return sent
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return sent
+		}
+	}
+}
+
+func (s *Server) newMessageID() int64 {
+	s.messageID++
+	return s.messageID
+}
+
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// ServeHTTP implements the subset of the Bot API used by telegram.Bot
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := "/bot" + s.Token + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		reply(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, prefix)
+
+	params := map[string]interface{}{}
+	var filename string
+	var document []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.ParseMultipartForm(50 << 20)
+		for k := range r.MultipartForm.Value {
+			params[k] = r.MultipartForm.Value[k][0]
+		}
+		// numbers are sent as strings in forms, record them as JSON would decode them
+		chatID, _ := strconv.ParseFloat(r.FormValue("chat_id"), 64)
+		params["chat_id"] = chatID
+		if file, header, err := r.FormFile("document"); err == nil {
+			filename = header.Filename
+			document, _ = ioutil.ReadAll(file)
+			file.Close()
+		}
+	} else {
+		json.NewDecoder(r.Body).Decode(&params)
+	}
+
+	switch method {
+	case "getMe":
+		reply(w, http.StatusOK, BotUser, "")
+	case "getUpdates":
+		reply(w, http.StatusOK, s.poll(r, params), "")
+	case "sendMessage":
+		m := s.record(method, params, "", nil)
+		reply(w, http.StatusOK, m, "")
+	case "sendDocument":
+		if document == nil {
+			reply(w, http.StatusBadRequest, nil, "Bad Request: there is no document in the request")
+			return
+		}
+		params["caption"], _ = params["caption"].(string)
+		m := s.record(method, params, filename, document)
+		reply(w, http.StatusOK, m, "")
+	case "editMessageText", "answerCallbackQuery":
+		s.record(method, params, "", nil)
+		reply(w, http.StatusOK, true, "")
+	default:
+		reply(w, http.StatusNotFound, nil, "Not Found: method not found")
+	}
+}
+
+// poll returns updates from offset, waiting for some up to the requested timeout
+func (s *Server) poll(r *http.Request, params map[string]interface{}) []telegram.Update {
+	offset := int64(number(params["offset"]))
+	deadline := time.After(time.Duration(number(params["timeout"])) * time.Second)
+	for {
+		s.mu.Lock()
+		updates := []telegram.Update{}
+		for _, u := range s.updates {
+			if u.UpdateID >= offset {
+				updates = append(updates, u)
+			}
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		if len(updates) > 0 {
+			return updates
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return updates
+		case <-r.Context().Done():
+			return updates
+		}
+	}
+}
+
+// record stores a call of the bot, returning the message it sent
+func (s *Server) record(method string, params map[string]interface{}, filename string, document []byte) telegram.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent := Sent{Method: method, ChatID: int64(number(params["chat_id"])), MessageID: int64(number(params["message_id"])), Params: params, Filename: filename, Document: document}
+	sent.Text, _ = params["text"].(string)
+	if method == "sendDocument" {
+		sent.Text, _ = params["caption"].(string)
+	}
+	if method == "sendMessage" || method == "sendDocument" {
+		sent.MessageID = s.newMessageID()
+	}
+	s.sent = append(s.sent, sent)
+	s.notify()
+	return telegram.Message{
+		MessageID: sent.MessageID,
+		From:      &BotUser,
+		Chat:      telegram.Chat{ID: sent.ChatID, Type: "private"},
+		Date:      time.Now().Unix(),
+		Text:      sent.Text,
+	}
+}
+
+func number(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+func reply(w http.ResponseWriter, status int, result interface{}, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	body := map[string]interface{}{"ok": status == http.StatusOK}
+	if status == http.StatusOK {
+		body["result"] = result
+	} else {
+		body["error_code"] = status
+		body["description"] = description
+	}
+	json.NewEncoder(w).Encode(body)
+}
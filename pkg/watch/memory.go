@@ -0,0 +1,53 @@
+package watch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps watches in process memory, they are lost on restart
+type MemoryStore struct {
+	mu      sync.Mutex
+	watches []*Watch
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Create implements Store
+func (s *MemoryStore) Create(_ context.Context, w *Watch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	w.ID = int64(len(s.watches) + 1)
+	w.CreatedAt, w.UpdatedAt = now, now
+	saved := *w
+	s.watches = append(s.watches, &saved)
+	return nil
+}
+
+// Active implements Store
+func (s *MemoryStore) Active(_ context.Context) ([]Watch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	watches := []Watch{}
+	for _, w := range s.watches {
+		if w.FinishedAt == nil {
+			watches = append(watches, *w)
+		}
+	}
+	return watches, nil
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(_ context.Context, w *Watch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := s.watches[w.ID-1]
+	w.UpdatedAt = time.Now()
+	saved.BuildNumber, saved.Text, saved.Result, saved.FinishedAt, saved.UpdatedAt = w.BuildNumber, w.Text, w.Result, w.FinishedAt, w.UpdatedAt
+	return nil
+}
@@ -0,0 +1,87 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/wiskarindra/jenkins_jr/config"
+)
+
+// Results of a watch which are not build results
+const (
+	ResultCancelled = "CANCELLED"
+	ResultTimeout   = "TIMEOUT"
+	ResultLost      = "LOST"
+)
+
+// Watch is a build followed by editing a chat message
+type Watch struct {
+	ID        int64  `db:"id"`
+	ChatID    int64  `db:"chat_id"`
+	MessageID int64  `db:"message_id"`
+	Job       string `db:"job"`
+	// Title describes the build in the message, e.g. the job with its parameters
+	Title   string `db:"title"`
+	QueueID int64  `db:"queue_id"`
+	// BuildNumber is 0 while the build is queued
+	BuildNumber int64 `db:"build_number"`
+	// Text is the message text as last edited
+	Text string `db:"text"`
+	// Result is the build result, or one of the watch results, once finished
+	Result     *string    `db:"result"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	FinishedAt *time.Time `db:"finished_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at"`
+}
+
+// Store keeps watches so that they survive restarts
+type Store interface {
+	// Create saves a new watch, setting its ID
+	Create(ctx context.Context, w *Watch) error
+	// Active returns unfinished watches, oldest first
+	Active(ctx context.Context) ([]Watch, error)
+	// Save updates the build number, text and result of a watch
+	Save(ctx context.Context, w *Watch) error
+}
+
+// MySQLStore keeps watches in the build_watches table
+type MySQLStore struct {
+	DB *sqlx.DB
+}
+
+// Create implements Store
+func (s *MySQLStore) Create(ctx context.Context, w *Watch) error {
+	now := time.Now()
+	w.CreatedAt, w.UpdatedAt = now, now
+	res, err := s.DB.ExecContext(ctx, "INSERT INTO build_watches (chat_id, message_id, job, title, queue_id, build_number, text, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		w.ChatID, w.MessageID, w.Job, w.Title, w.QueueID, w.BuildNumber, w.Text,
+		w.ExpiresAt.Format(config.DatabaseDatetimeFormat), now.Format(config.DatabaseDatetimeFormat), now.Format(config.DatabaseDatetimeFormat))
+	if err != nil {
+		return err
+	}
+	w.ID, err = res.LastInsertId()
+	return err
+}
+
+// Active implements Store
+func (s *MySQLStore) Active(ctx context.Context) ([]Watch, error) {
+	watches := []Watch{}
+	err := s.DB.SelectContext(ctx, &watches, "SELECT * FROM build_watches WHERE finished_at IS NULL ORDER BY id")
+	return watches, err
+}
+
+// Save implements Store
+func (s *MySQLStore) Save(ctx context.Context, w *Watch) error {
+	w.UpdatedAt = time.Now()
+	var finishedAt *string
+	if w.FinishedAt != nil {
+		f := w.FinishedAt.Format(config.DatabaseDatetimeFormat)
+		finishedAt = &f
+	}
+	_, err := s.DB.ExecContext(ctx, "UPDATE build_watches SET build_number = ?, text = ?, result = ?, finished_at = ?, updated_at = ? WHERE id = ?",
+		w.BuildNumber, w.Text, w.Result, finishedAt, w.UpdatedAt.Format(config.DatabaseDatetimeFormat), w.ID)
+	return err
+}
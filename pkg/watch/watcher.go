@@ -0,0 +1,253 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/analyzer"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins_jr"
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/resource"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram"
+)
+
+// Watcher follows triggered builds, editing a chat message with their progress until they end
+type Watcher struct {
+	Store   Store
+	Jenkins jenkins_jr.JenkinsClient
+	API     *telegram.Bot
+	// Poll is the time between two checks of the active watches
+	Poll time.Duration
+	// Timeout is how long a build is watched at most
+	Timeout time.Duration
+
+	// checking serializes checks, so that a watch is never edited twice for the same change
+	checking sync.Mutex
+	// mu guards started
+	mu sync.Mutex
+	// started are the times the first step of watches ended, zero while Start steps them.
+	// Checks skip watches they loaded before, whose copy is stale.
+	started map[int64]time.Time
+}
+
+// New returns Watcher checking builds every 10 seconds for up to 2 hours
+func New(store Store, client jenkins_jr.JenkinsClient, api *telegram.Bot) *Watcher {
+	return &Watcher{Store: store, Jenkins: client, API: api, Poll: 10 * time.Second, Timeout: 2 * time.Hour, started: map[int64]time.Time{}}
+}
+
+// check is the state of a watched build
+type check struct {
+	text string
+	// result is set once the build ended, or cannot be followed anymore
+	result string
+	// final is posted as a new message when the build ended, so that the chat is notified
+	final string
+}
+
+// Start watches a triggered build in the message with given ID, showing its status at once.
+// It does not wait for running checks, which may be slow when Jenkins is.
+func (w *Watcher) Start(ctx context.Context, chatID, messageID int64, job, title string, queueID int64) error {
+	wa := &Watch{ChatID: chatID, MessageID: messageID, Job: job, Title: title, QueueID: queueID, ExpiresAt: time.Now().Add(w.Timeout)}
+	if err := w.Store.Create(ctx, wa); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.started[wa.ID] = time.Time{}
+	w.mu.Unlock()
+
+	w.step(ctx, wa)
+
+	w.mu.Lock()
+	w.started[wa.ID] = time.Now()
+	w.mu.Unlock()
+	return nil
+}
+
+// stale tells whether a watch loaded at given time is being stepped by Start or was stepped since
+func (w *Watcher) stale(id int64, loaded time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ended, ok := w.started[id]
+	return ok && (ended.IsZero() || ended.After(loaded))
+}
+
+// forget drops the watches started before loaded, as checks loading them from then on get their latest state
+func (w *Watcher) forget(loaded time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, ended := range w.started {
+		if !ended.IsZero() && ended.Before(loaded) {
+			delete(w.started, id)
+		}
+	}
+}
+
+// Run checks active watches every Poll until ctx is done, starting with those left by a previous run
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.Poll)
+	defer ticker.Stop()
+	for {
+		w.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check updates every active watch once
+func (w *Watcher) Check(ctx context.Context) {
+	w.CheckJob(ctx, "")
+}
+
+// CheckJob updates active watches of a job once, or of all jobs when job is empty
+func (w *Watcher) CheckJob(ctx context.Context, job string) {
+	w.checking.Lock()
+	defer w.checking.Unlock()
+	ctx = resource.NewContext(ctx, "watch-"+strconv.FormatInt(time.Now().UnixNano(), 10), "bot/watch", time.Now())
+
+	loaded := time.Now()
+	w.forget(loaded)
+	watches, err := w.Store.Active(ctx)
+	if err != nil {
+		log.ErrLog(ctx, err, "watch", "Failed to load watches")
+		return
+	}
+	for k := range watches {
+		if (job == "" || watches[k].Job == job) && !w.stale(watches[k].ID, loaded) {
+			w.step(ctx, &watches[k])
+		}
+	}
+}
+
+// step checks a build, edits its message when the status changed and finishes the watch once the build ended
+func (w *Watcher) step(ctx context.Context, wa *Watch) {
+	number := wa.BuildNumber
+	c, err := w.check(ctx, wa)
+	expired := time.Now().After(wa.ExpiresAt)
+	if err != nil && !expired {
+		log.ErrLog(ctx, err, "watch", fmt.Sprintf("Failed to check build of watch %d", wa.ID))
+		return
+	}
+	if c.result == "" && expired {
+		c = w.timeout(wa)
+	}
+
+	changed := c.text != wa.Text
+	wa.Text = c.text
+	if c.result != "" {
+		now := time.Now()
+		wa.Result, wa.FinishedAt = &c.result, &now
+	}
+	if changed {
+		if err := w.API.EditMessageText(ctx, wa.ChatID, wa.MessageID, wa.Text); err != nil {
+			log.ErrLog(ctx, err, "watch", fmt.Sprintf("Failed to edit message of watch %d", wa.ID))
+		}
+	}
+	if changed || c.result != "" || number != wa.BuildNumber {
+		// saved before posting the final status, which would be posted again if saving failed
+		if err := w.Store.Save(ctx, wa); err != nil {
+			log.ErrLog(ctx, err, "watch", fmt.Sprintf("Failed to save watch %d", wa.ID))
+			return
+		}
+	}
+	if c.final != "" {
+		if _, err := w.API.SendMessage(ctx, wa.ChatID, c.final); err != nil {
+			log.ErrLog(ctx, err, "watch", fmt.Sprintf("Failed to post result of watch %d", wa.ID))
+		}
+	}
+}
+
+// check fetches the state of a watched build, setting its number once it left the queue
+func (w *Watcher) check(ctx context.Context, wa *Watch) (check, error) {
+	if wa.BuildNumber == 0 {
+		q, err := w.Jenkins.QueueItem(ctx, wa.QueueID)
+		if err == jenkins.ErrNotFound {
+			return check{text: fmt.Sprintf("Lost track of %s, queue item %d is gone", wa.Title, wa.QueueID), result: ResultLost}, nil
+		}
+		if err != nil {
+			return check{}, err
+		}
+		if q.Cancelled {
+			return check{text: "Cancelled in queue: " + wa.Title, result: ResultCancelled}, nil
+		}
+		if q.Executable == nil {
+			text := "Queued: " + wa.Title
+			if q.Why != "" {
+				text += "\n" + q.Why
+			}
+			return check{text: text}, nil
+		}
+		wa.BuildNumber = q.Executable.Number
+	}
+
+	b, err := w.Jenkins.Build(ctx, wa.Job, wa.BuildNumber)
+	if err != nil {
+		return check{}, err
+	}
+	if !b.Building {
+		elapsed := b.Elapsed().Round(time.Second)
+		final := fmt.Sprintf("%s #%d finished with %s in %s\n%s", wa.Job, b.Number, b.Result, elapsed, b.URL)
+		summary, err := analyzer.Build(ctx, w.Jenkins, wa.Job, b)
+		if err != nil {
+			log.ErrLog(ctx, err, "watch", fmt.Sprintf("Failed to analyze %s #%d", wa.Job, b.Number))
+		}
+		if summary != nil {
+			final += "\n" + summary.String()
+		}
+		return check{
+			text:   fmt.Sprintf("%s #%d: %s\nFinished in %s\n%s", b.Result, b.Number, wa.Title, elapsed, b.URL),
+			result: b.Result,
+			final:  final,
+		}, nil
+	}
+
+	stages, err := w.Jenkins.Stages(ctx, wa.Job, wa.BuildNumber)
+	if err != nil {
+		return check{}, err
+	}
+	progress := fmt.Sprintf("%s elapsed of about %s", b.Elapsed().Round(time.Second), (time.Duration(b.EstimatedDuration) * time.Millisecond).Round(time.Second))
+	if stage := Current(stages); stage != "" {
+		progress = stage + ", " + progress
+	}
+	return check{text: fmt.Sprintf("Building #%d: %s\n%s\n%s", b.Number, wa.Title, progress, b.URL)}, nil
+}
+
+// timeout stops a watch which lasted longer than Timeout
+func (w *Watcher) timeout(wa *Watch) check {
+	if wa.BuildNumber == 0 {
+		return check{text: fmt.Sprintf("Stopped watching: %s\nStill queued after %s", wa.Title, w.Timeout), result: ResultTimeout}
+	}
+	return check{
+		text:   fmt.Sprintf("Stopped watching #%d: %s\nStill running after %s, see /status %s %d", wa.BuildNumber, wa.Title, w.Timeout, wa.Job, wa.BuildNumber),
+		result: ResultTimeout,
+	}
+}
+
+// Current describes the running stage of a pipeline, e.g. "Stage Test (2/4)", empty when there is none
+func Current(stages []jenkins.Stage) string {
+	current := -1
+	for k, s := range stages {
+		if s.Status == jenkins.StageNotExecuted {
+			continue
+		}
+		current = k
+		if s.Status == jenkins.StageInProgress || s.Status == jenkins.StagePendingInput {
+			break
+		}
+	}
+	if current < 0 {
+		return ""
+	}
+	s := stages[current]
+	text := fmt.Sprintf("Stage %s (%d/%d)", s.Name, current+1, len(stages))
+	if s.Status == jenkins.StagePendingInput {
+		text += " waiting for input"
+	}
+	return text
+}
@@ -0,0 +1,190 @@
+package watch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins/jenkinstest"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram/telegramtest"
+	"github.com/wiskarindra/jenkins_jr/pkg/watch"
+)
+
+const (
+	chatID    = 100
+	messageID = 42
+)
+
+func init() {
+	os.Setenv("ENV", "test")
+}
+
+func setup() (*jenkinstest.Server, *telegramtest.Server, *watch.MemoryStore, func(*watch.MemoryStore) *watch.Watcher) {
+	j := jenkinstest.NewServer()
+	j.AddJob("deploy")
+	tg := telegramtest.NewServer("123:token")
+	store := watch.NewMemoryStore()
+	return j, tg, store, func(s *watch.MemoryStore) *watch.Watcher {
+		return watch.New(s, jenkins.New(j.URL, "bot", "token"), tg.Bot())
+	}
+}
+
+// trigger queues a build of deploy, returning its queue item
+func trigger(t *testing.T, j *jenkinstest.Server) int64 {
+	id, err := jenkins.New(j.URL, "bot", "token").Trigger(context.Background(), "deploy", map[string]string{"ENV": "staging"})
+	assert.Nil(t, err)
+	return id
+}
+
+func TestCurrent(t *testing.T) {
+	assert.Equal(t, "", watch.Current(nil))
+	stages := []jenkins.Stage{
+		{Name: "Build", Status: jenkins.StageSuccess},
+		{Name: "Test", Status: jenkins.StageInProgress},
+		{Name: "Deploy", Status: jenkins.StageNotExecuted},
+	}
+	assert.Equal(t, "Stage Test (2/3)", watch.Current(stages))
+	stages[1].Status = jenkins.StageSuccess
+	assert.Equal(t, "Stage Test (2/3)", watch.Current(stages))
+	stages[2].Status = jenkins.StagePendingInput
+	assert.Equal(t, "Stage Deploy (3/3) waiting for input", watch.Current(stages))
+}
+
+func TestWatcher(t *testing.T) {
+	j, tg, store, newWatcher := setup()
+	defer j.Close()
+	defer tg.Close()
+	ctx := context.Background()
+	w := newWatcher(store)
+
+	j.Hold()
+	id := trigger(t, j)
+	assert.Nil(t, w.Start(ctx, chatID, messageID, "deploy", "deploy with ENV=staging", id))
+	sent := tg.Sent()
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "editMessageText", sent[0].Method)
+		assert.Equal(t, int64(messageID), sent[0].MessageID)
+		assert.Equal(t, "Queued: deploy with ENV=staging\nWaiting for next available executor", sent[0].Text)
+	}
+
+	// nothing changed, nothing edited
+	w.Check(ctx)
+	assert.Len(t, tg.Sent(), 1)
+
+	// a restarted bot resumes the watch from the store
+	j.Release()
+	j.SetStages("deploy", 1, jenkins.Stage{Name: "Build", Status: jenkins.StageSuccess}, jenkins.Stage{Name: "Deploy", Status: jenkins.StageInProgress})
+	newWatcher(store).Check(ctx)
+	sent = tg.Sent()
+	if assert.Len(t, sent, 2) {
+		assert.True(t, strings.HasPrefix(sent[1].Text, "Building #1: deploy with ENV=staging\nStage Deploy (2/2), 0s elapsed of about 1m0s\n"), sent[1].Text)
+	}
+	watches, _ := store.Active(ctx)
+	if assert.Len(t, watches, 1) {
+		assert.Equal(t, int64(1), watches[0].BuildNumber)
+	}
+
+	j.Finish("deploy", 1, jenkins.ResultSuccess, "Finished: SUCCESS\n")
+	w.Check(ctx)
+	sent = tg.Sent()
+	if assert.Len(t, sent, 4) {
+		assert.True(t, strings.HasPrefix(sent[2].Text, "SUCCESS #1: deploy with ENV=staging\nFinished in 0s\n"), sent[2].Text)
+		assert.Equal(t, "sendMessage", sent[3].Method)
+		assert.True(t, strings.HasPrefix(sent[3].Text, "deploy #1 finished with SUCCESS in 0s\nhttp://"), sent[3].Text)
+	}
+	watches, _ = store.Active(ctx)
+	assert.Empty(t, watches)
+
+	w.Check(ctx)
+	assert.Len(t, tg.Sent(), 4)
+
+	// the result of a failed build tells its probable cause
+	id = trigger(t, j)
+	assert.Nil(t, w.Start(ctx, chatID, messageID, "deploy", "deploy", id))
+	j.Finish("deploy", 2, jenkins.ResultFailure, "fatal error: runtime: out of memory\n")
+	w.Check(ctx)
+	sent = tg.Sent()
+	if assert.Len(t, sent, 7) {
+		assert.True(t, strings.HasSuffix(sent[6].Text, "\nProbable cause: Out of memory\n> fatal error: runtime: out of memory"), sent[6].Text)
+	}
+}
+
+func TestWatcherCancelledAndTimeout(t *testing.T) {
+	j, tg, store, newWatcher := setup()
+	defer j.Close()
+	defer tg.Close()
+	ctx := context.Background()
+	w := newWatcher(store)
+
+	j.Hold()
+	id := trigger(t, j)
+	assert.Nil(t, w.Start(ctx, chatID, messageID, "deploy", "deploy", id))
+	j.Cancel(id)
+	w.Check(ctx)
+	sent := tg.Sent()
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, "Cancelled in queue: deploy", sent[1].Text)
+	}
+
+	w.Timeout = 100 * time.Millisecond
+	j.Release()
+	id = trigger(t, j)
+	assert.Nil(t, w.Start(ctx, chatID, messageID+1, "deploy", "deploy", id))
+	time.Sleep(150 * time.Millisecond)
+	w.Check(ctx)
+	sent = tg.Sent()
+	if assert.Len(t, sent, 4) {
+		assert.Equal(t, "Stopped watching #1: deploy\nStill running after 100ms, see /status deploy 1", sent[3].Text)
+	}
+	watches, _ := store.Active(ctx)
+	assert.Empty(t, watches)
+}
+
+func TestWatcherStartDuringCheck(t *testing.T) {
+	j, tg, store, _ := setup()
+	defer j.Close()
+	defer tg.Close()
+	ctx := context.Background()
+
+	// Jenkins answers slowly about queue item 99
+	entered, release := make(chan struct{}), make(chan struct{})
+	target, _ := url.Parse(j.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	slow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/queue/item/99/") {
+			close(entered)
+			<-release
+		}
+		proxy.ServeHTTP(rw, r)
+	}))
+	defer slow.Close()
+	w := watch.New(store, jenkins.New(slow.URL, "bot", "token"), tg.Bot())
+
+	assert.Nil(t, store.Create(ctx, &watch.Watch{ChatID: chatID, MessageID: messageID, Job: "deploy", Title: "deploy", QueueID: 99, ExpiresAt: time.Now().Add(time.Hour)}))
+	checked := make(chan struct{})
+	go func() {
+		w.Check(ctx)
+		close(checked)
+	}()
+	<-entered
+
+	j.Hold()
+	assert.Nil(t, w.Start(ctx, chatID, messageID+1, "deploy", "deploy", trigger(t, j)), "starting does not wait for the check")
+	sent := tg.Sent()
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, int64(messageID+1), sent[0].MessageID)
+	}
+
+	close(release)
+	<-checked
+	assert.Len(t, tg.Sent(), 2, "the check edits the watch it loaded only")
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/log"
+	"github.com/wiskarindra/jenkins_jr/pkg/resource"
+	"github.com/wiskarindra/jenkins_jr/pkg/response"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+	"github.com/wiskarindra/jenkins_jr/pkg/watch"
+)
+
+// maxBodySize caps webhook payloads, which are small unless they include the console log
+const maxBodySize = 1 << 20
+
+// Handler receives build events from Jenkins, as an alternative to polling
+type Handler struct {
+	// Secret signs payloads, see Sign
+	Secret  string
+	Poller  *subscription.Poller
+	Watcher *watch.Watcher
+
+	// handling counts the events being handled
+	handling sync.WaitGroup
+}
+
+// Jenkins acknowledges a build event, then updates watches of its job and alerts subscribers once the build completed.
+// Events are handled in the background, as Jenkins waits for the response and checks may wait for a running poll.
+func (h *Handler) Jenkins(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		response.Fail(w, http.StatusRequestEntityTooLarge, "Payload is too large")
+		return
+	}
+	if !Verify(h.Secret, body, r.Header.Get(SignatureHeader)) {
+		response.Fail(w, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		response.Fail(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	job := p.Job()
+	if job == "" || p.Build.Number <= 0 {
+		response.Fail(w, http.StatusUnprocessableEntity, "Payload has no job or build number")
+		return
+	}
+
+	h.handling.Add(1)
+	go func() {
+		defer h.handling.Done()
+		h.handle(job, p)
+	}()
+	response.Accepted(w, map[string]interface{}{"job": job, "number": p.Build.Number, "phase": p.Build.Phase})
+}
+
+// Wait waits for the events acknowledged so far to be handled
+func (h *Handler) Wait() {
+	h.handling.Wait()
+}
+
+func (h *Handler) handle(job string, p Payload) {
+	ctx := resource.NewContext(context.Background(), "webhook-"+strconv.FormatInt(time.Now().UnixNano(), 10), "bot/webhook", time.Now())
+	if p.Completed() {
+		if err := h.Poller.Receive(ctx, job, p.Build.Number); err != nil {
+			log.ErrLog(ctx, err, "webhook", "Failed to alert subscribers of "+job)
+		}
+	}
+	h.Watcher.CheckJob(ctx, job)
+}
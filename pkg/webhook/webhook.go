@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the request body, e.g. sha256=5d41...
+const SignatureHeader = "X-Jenkins-Signature"
+
+// Build phases sent by the Jenkins notification plugin
+const (
+	PhaseQueued    = "QUEUED"
+	PhaseStarted   = "STARTED"
+	PhaseCompleted = "COMPLETED"
+	PhaseFinalized = "FINALIZED"
+)
+
+// Payload is a build event in the format of the Jenkins notification plugin
+type Payload struct {
+	// Name is the job name, without its folders
+	Name string `json:"name"`
+	// URL is the job path relative to Jenkins, e.g. job/mobile/job/android/
+	URL   string `json:"url"`
+	Build Build  `json:"build"`
+}
+
+// Build is the build of a Payload
+type Build struct {
+	FullURL string `json:"full_url"`
+	Number  int64  `json:"number"`
+	Phase   string `json:"phase"`
+	// Status is the build result, set once completed
+	Status string `json:"status,omitempty"`
+	URL    string `json:"url"`
+}
+
+// Job returns the full name of the job, folders included
+func (p *Payload) Job() string {
+	parts := strings.Split(strings.Trim(p.URL, "/"), "/")
+	names := []string{}
+	for k := 0; k+1 < len(parts); k += 2 {
+		if parts[k] != "job" {
+			break
+		}
+		name, err := url.PathUnescape(parts[k+1])
+		if err != nil {
+			break
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return p.Name
+	}
+	return strings.Join(names, "/")
+}
+
+// Completed tells whether the build ended
+func (p *Payload) Completed() bool {
+	return p.Build.Phase == PhaseCompleted || p.Build.Phase == PhaseFinalized
+}
+
+// Sign returns the signature of body, as expected in SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of body in constant time
+func Verify(secret string, body []byte, signature string) bool {
+	return secret != "" && hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
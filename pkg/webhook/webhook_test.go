@@ -0,0 +1,96 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins"
+	"github.com/wiskarindra/jenkins_jr/pkg/jenkins/jenkinstest"
+	"github.com/wiskarindra/jenkins_jr/pkg/subscription"
+	"github.com/wiskarindra/jenkins_jr/pkg/telegram/telegramtest"
+	"github.com/wiskarindra/jenkins_jr/pkg/watch"
+	"github.com/wiskarindra/jenkins_jr/pkg/webhook"
+)
+
+const secret = "s3cret"
+
+func init() {
+	os.Setenv("ENV", "test")
+}
+
+func TestPayloadJob(t *testing.T) {
+	assert.Equal(t, "mobile/android release", (&webhook.Payload{Name: "android release", URL: "job/mobile/job/android%20release/"}).Job())
+	assert.Equal(t, "deploy", (&webhook.Payload{Name: "deploy", URL: "job/deploy/"}).Job())
+	assert.Equal(t, "deploy", (&webhook.Payload{Name: "deploy"}).Job())
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"name":"deploy"}`)
+	signature := webhook.Sign(secret, body)
+	assert.True(t, strings.HasPrefix(signature, "sha256="))
+	assert.True(t, webhook.Verify(secret, body, signature))
+	assert.False(t, webhook.Verify(secret, []byte(`{"name":"deploy2"}`), signature))
+	assert.False(t, webhook.Verify("other", body, signature))
+	assert.False(t, webhook.Verify("", body, webhook.Sign("", body)))
+}
+
+func post(h *webhook.Handler, body, signature string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/webhooks/jenkins", bytes.NewBufferString(body))
+	r.Header.Set(webhook.SignatureHeader, signature)
+	w := httptest.NewRecorder()
+	h.Jenkins(w, r, nil)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	j := jenkinstest.NewServer()
+	defer j.Close()
+	tg := telegramtest.NewServer("123:token")
+	defer tg.Close()
+	ctx := context.Background()
+	client := jenkins.New(j.URL, "bot", "token")
+
+	j.AddJob("deploy")
+	store := subscription.NewMemoryStore()
+	store.Subscribe(ctx, &subscription.Subscription{ChatID: 1, Pattern: "deploy", Events: "failure"})
+	poller := subscription.NewPoller(store, client, tg.Bot())
+	poller.Check(ctx)
+	watcher := watch.New(watch.NewMemoryStore(), client, tg.Bot())
+	h := &webhook.Handler{Secret: secret, Poller: poller, Watcher: watcher}
+
+	id, _ := client.Trigger(ctx, "deploy", nil)
+	watcher.Start(ctx, 2, 42, "deploy", "deploy", id)
+	assert.Len(t, tg.Sent(), 1)
+
+	assert.Equal(t, http.StatusUnauthorized, post(h, `{}`, "sha256=00").Code)
+	assert.Equal(t, http.StatusBadRequest, post(h, `{`, webhook.Sign(secret, []byte(`{`))).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, post(h, `{}`, webhook.Sign(secret, []byte(`{}`))).Code)
+
+	// the build fails, the watch and the subscribed chat are updated at once
+	j.Finish("deploy", 1, jenkins.ResultFailure, "")
+	body := `{"name":"deploy","url":"job/deploy/","build":{"number":1,"phase":"COMPLETED","status":"FAILURE","url":"job/deploy/1/"}}`
+	w := post(h, body, webhook.Sign(secret, []byte(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	h.Wait()
+
+	sent := tg.Sent()
+	if assert.Len(t, sent, 4) {
+		assert.Equal(t, int64(1), sent[1].ChatID)
+		assert.True(t, strings.HasPrefix(sent[1].Text, "deploy #1 failed after "), sent[1].Text)
+		assert.True(t, strings.HasPrefix(sent[2].Text, "FAILURE #1: deploy\n"), sent[2].Text)
+		assert.True(t, strings.HasPrefix(sent[3].Text, "deploy #1 finished with FAILURE"), sent[3].Text)
+	}
+
+	// FINALIZED follows COMPLETED, nothing is sent twice
+	body = strings.Replace(body, "COMPLETED", "FINALIZED", 1)
+	assert.Equal(t, http.StatusAccepted, post(h, body, webhook.Sign(secret, []byte(body))).Code)
+	h.Wait()
+	assert.Len(t, tg.Sent(), 4)
+}